@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Alert kinds
+const (
+	AlertClaimChange = "claim_change"
+	AlertCreditsLow  = "credits_low"
+)
+
+// Alert is a notable change detected in the stored history
+type Alert struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	KeywordID  string    `json:"keyword_id"`
+	Keyword    string    `json:"keyword"`
+	SnapshotID int64     `json:"snapshot_id"`
+	At         time.Time `json:"at"`
+	Message    string    `json:"message"`
+}
+
+// raiseAlert stores a, logs it and notifies subscribers.
+func raiseAlert(a Alert) {
+	if a.At.IsZero() {
+		a.At = time.Now()
+	}
+	log.Println("🚨", a.Message)
+	if _, err := store.AddAlert(a); err != nil {
+		log.Println("❌ failed to store alert:", err)
+	}
+	go notifySubscribers(a)
+	go notifyWebhook(a)
+}
+
+// notifyWebhook POSTs the alert as JSON to the configured alert webhook.
+func notifyWebhook(a Alert) {
+	hook := currentSettings().AlertWebhook
+	if hook == "" {
+		return
+	}
+	raw, _ := json.Marshal(a)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(hook, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		log.Println("❌ alert webhook failed:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Println("❌ alert webhook returned", resp.Status)
+	}
+}
+
+var alertsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Alerts</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+	</style>
+</head>
+<body>
+	<h1>🚨 Alerts</h1>
+	<p><a href="/">← Search</a></p>
+	{{range .}}
+		<div class="text-block">
+			<strong>{{.Kind}}</strong> · {{.At.Format "2006-01-02 15:04"}} {{if .KeywordID}}· <a href="/keywords/{{.KeywordID}}/claims">{{.Keyword}}</a>{{end}}
+			<p>{{.Message}}</p>
+		</div>
+	{{else}}
+		<p><em>No alerts yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var alertsTpl = template.Must(template.New("alerts").Parse(alertsTmpl))
+
+func alertsPage(w http.ResponseWriter, r *http.Request) {
+	if err := alertsTpl.Execute(w, store.Alerts()); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiAlerts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, store.Alerts())
+}
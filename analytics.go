@@ -0,0 +1,200 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// AnalyticsFilter narrows analytics to a slice of keywords. Empty fields match all.
+type AnalyticsFilter struct {
+	Intent string `json:"intent,omitempty"`
+	Topic  string `json:"topic,omitempty"`
+}
+
+func (f AnalyticsFilter) match(k Keyword) bool {
+	return (f.Intent == "" || k.Classification.Intent == f.Intent) &&
+		(f.Topic == "" || k.Classification.Topic == f.Topic)
+}
+
+// AnalyticsRow aggregates lookups for one value of a dimension
+type AnalyticsRow struct {
+	Key          string  `json:"key"`
+	Keywords     int     `json:"keywords"`
+	Requests     int     `json:"requests"`
+	Snapshots    int     `json:"snapshots"`
+	WithOverview int     `json:"with_overview"`
+	OverviewRate float64 `json:"overview_rate"` // share of snapshots with an AI Overview
+	Mismatches   int     `json:"language_mismatches"`
+	MismatchRate float64 `json:"language_mismatch_rate"` // share of overviews not in the requested hl
+
+	keywords map[string]bool
+}
+
+type Analytics struct {
+	Filter   AnalyticsFilter `json:"filter"`
+	Totals   AnalyticsRow    `json:"totals"`
+	ByIntent []AnalyticsRow  `json:"by_intent"`
+	ByTopic  []AnalyticsRow  `json:"by_topic"`
+	ByLocale []AnalyticsRow  `json:"by_locale"`
+}
+
+// BuildAnalytics aggregates the stored history of all keywords matching f.
+func BuildAnalytics(f AnalyticsFilter) Analytics {
+	keywords := map[string]Keyword{}
+	for _, k := range store.Keywords() {
+		if f.match(k) {
+			keywords[k.ID] = k
+		}
+	}
+
+	a := Analytics{Filter: f, Totals: AnalyticsRow{Key: "all"}}
+	byIntent := map[string]*AnalyticsRow{}
+	byTopic := map[string]*AnalyticsRow{}
+	byLocale := map[string]*AnalyticsRow{}
+	rowsFor := func(keywordID string, loc Locale) []*AnalyticsRow {
+		k, ok := keywords[keywordID]
+		if !ok {
+			return nil
+		}
+		rows := []*AnalyticsRow{
+			&a.Totals,
+			analyticsRow(byIntent, k.Classification.Intent),
+			analyticsRow(byTopic, k.Classification.Topic),
+			analyticsRow(byLocale, loc.String()),
+		}
+		for _, row := range rows {
+			if row.keywords == nil {
+				row.keywords = map[string]bool{}
+			}
+			row.keywords[keywordID] = true
+		}
+		return rows
+	}
+
+	for _, req := range store.Requests() {
+		for _, row := range rowsFor(req.KeywordID, req.Locale) {
+			row.Requests++
+		}
+	}
+	for _, snap := range store.Snapshots(nil) {
+		hasOverview := snap.Overview != nil && !snap.Overview.IsEmpty()
+		mismatch := hasOverview && DetectOverviewLanguages(snap.Overview, snap.Locale.HL).Mismatch
+		for _, row := range rowsFor(snap.KeywordID, snap.Locale) {
+			row.Snapshots++
+			if hasOverview {
+				row.WithOverview++
+			}
+			if mismatch {
+				row.Mismatches++
+			}
+		}
+	}
+	a.Totals.finish()
+	a.ByIntent = sortedRows(byIntent)
+	a.ByTopic = sortedRows(byTopic)
+	a.ByLocale = sortedRows(byLocale)
+	return a
+}
+
+func analyticsRow(rows map[string]*AnalyticsRow, key string) *AnalyticsRow {
+	row, ok := rows[key]
+	if !ok {
+		row = &AnalyticsRow{Key: key}
+		rows[key] = row
+	}
+	return row
+}
+
+func (r *AnalyticsRow) finish() {
+	r.Keywords = len(r.keywords)
+	if r.Snapshots > 0 {
+		r.OverviewRate = float64(r.WithOverview) / float64(r.Snapshots)
+	}
+	if r.WithOverview > 0 {
+		r.MismatchRate = float64(r.Mismatches) / float64(r.WithOverview)
+	}
+}
+
+func sortedRows(rows map[string]*AnalyticsRow) []AnalyticsRow {
+	out := make([]AnalyticsRow, 0, len(rows))
+	for _, row := range rows {
+		row.finish()
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Keywords != out[j].Keywords {
+			return out[i].Keywords > out[j].Keywords
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+var analyticsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Analytics</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+		th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+	</style>
+</head>
+<body>
+	<h1>📊 Analytics</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="GET">
+		<select name="intent">
+			<option value="">all intents</option>
+			{{range .Intents}}<option {{if eq . $.Analytics.Filter.Intent}}selected{{end}}>{{.}}</option>{{end}}
+		</select>
+		<select name="topic">
+			<option value="">all topics</option>
+			{{range .Topics}}<option {{if eq . $.Analytics.Filter.Topic}}selected{{end}}>{{.}}</option>{{end}}
+		</select>
+		<button type="submit">Filter</button>
+	</form>
+	{{with .Analytics}}
+		<p>{{.Totals.Keywords}} keywords, {{.Totals.Requests}} requests, {{.Totals.Snapshots}} snapshots, {{percent .Totals.OverviewRate}} with AI Overview, {{percent .Totals.MismatchRate}} not in the requested language</p>
+		<h2>By intent</h2>
+		{{template "rows" .ByIntent}}
+		<h2>By topic</h2>
+		{{template "rows" .ByTopic}}
+		<h2>By locale (hl-gl)</h2>
+		{{template "rows" .ByLocale}}
+	{{end}}
+</body>
+</html>
+{{define "rows"}}
+<table>
+	<tr><th></th><th>Keywords</th><th>Requests</th><th>Snapshots</th><th>AI Overview rate</th><th>Language mismatch</th></tr>
+	{{range .}}
+	<tr><td>{{.Key}}</td><td>{{.Keywords}}</td><td>{{.Requests}}</td><td>{{.Snapshots}}</td><td>{{percent .OverviewRate}}</td><td>{{.Mismatches}} ({{percent .MismatchRate}})</td></tr>
+	{{end}}
+</table>
+{{end}}
+`
+
+var analyticsTpl = template.Must(template.New("analytics").Funcs(funcMap).Parse(analyticsTmpl))
+
+func analyticsFilter(r *http.Request) AnalyticsFilter {
+	q := r.URL.Query()
+	return AnalyticsFilter{Intent: q.Get("intent"), Topic: q.Get("topic")}
+}
+
+func analyticsPage(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Analytics Analytics
+		Intents   []string
+		Topics    []string
+	}{BuildAnalytics(analyticsFilter(r)), intents, topics()}
+	if err := analyticsTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiAnalytics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, BuildAnalytics(analyticsFilter(r)))
+}
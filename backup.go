@@ -0,0 +1,341 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupSchemaVersion describes the layout of the data directory. Bump it
+// when a stored format changes incompatibly, and teach restore to upgrade.
+const backupSchemaVersion = 1
+
+const backupManifestName = "manifest.json"
+
+// Limits on what readBackup decompresses, per entry and in total.
+const (
+	maxBackupEntry = 256 << 20
+	maxBackupSize  = 1 << 30
+)
+
+// backupKeyFiles hold signing secrets. They are left out of archives
+// unless asked for: a backup served over HTTP must not hand out the key
+// that signs evidence. An instance restored without them generates new
+// keys, so older evidence and embed links stop verifying.
+var backupKeyFiles = []string{"embed_secret", "evidence_key"}
+
+// BackupManifest is the first entry of every archive
+type BackupManifest struct {
+	SchemaVersion int          `json:"schema_version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	KeysIncluded  bool         `json:"keys_included"`
+	OmittedKeys   []string     `json:"omitted_keys,omitempty"` // key files left out of this archive
+	Files         []BackupFile `json:"files"`
+}
+
+// BackupFile is one data file in the archive
+type BackupFile struct {
+	Name   string `json:"name"` // slash separated, relative to the data directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// collectDataFiles reads every file in the data directory. While the
+// server runs, the store and settings are taken from memory under their
+// locks, so each is consistent even if a write is in progress.
+func collectDataFiles(dir string, live bool) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = raw
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if live {
+		raw, err := store.Export()
+		if err != nil {
+			return nil, err
+		}
+		files["history.json"] = raw
+		if raw, ok := settings.Export(); ok {
+			files["settings.json"] = raw
+		}
+	}
+	return files, nil
+}
+
+// writeBackup writes files as a gzipped tar with the manifest first. Key
+// files are only written with keys.
+func writeBackup(w io.Writer, files map[string][]byte, keys bool) (BackupManifest, error) {
+	m := BackupManifest{SchemaVersion: backupSchemaVersion, CreatedAt: time.Now().UTC(), KeysIncluded: keys}
+	if !keys {
+		files = maps.Clone(files)
+		for _, name := range backupKeyFiles {
+			if _, ok := files[name]; ok {
+				delete(files, name)
+				m.OmittedKeys = append(m.OmittedKeys, name)
+			}
+		}
+	}
+	for name, raw := range files {
+		sum := sha256.Sum256(raw)
+		m.Files = append(m.Files, BackupFile{Name: name, Size: int64(len(raw)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, raw []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(raw)), ModTime: m.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(raw)
+		return err
+	}
+	if err := add(backupManifestName, manifest); err != nil {
+		return m, err
+	}
+	for _, f := range m.Files {
+		if err := add("data/"+f.Name, files[f.Name]); err != nil {
+			return m, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return m, err
+	}
+	return m, gz.Close()
+}
+
+// readBackup reads an archive and checks it against its manifest.
+func readBackup(r io.Reader) (BackupManifest, map[string][]byte, error) {
+	var m BackupManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return m, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	first := true
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, nil, err
+		}
+		if hdr.Size > maxBackupEntry {
+			return m, nil, fmt.Errorf("%s is larger than %d bytes", hdr.Name, maxBackupEntry)
+		}
+		raw, err := io.ReadAll(io.LimitReader(tr, maxBackupEntry+1))
+		if err != nil {
+			return m, nil, err
+		}
+		if len(raw) > maxBackupEntry {
+			return m, nil, fmt.Errorf("%s is larger than %d bytes", hdr.Name, maxBackupEntry)
+		}
+		if total += int64(len(raw)); total > maxBackupSize {
+			return m, nil, fmt.Errorf("archive expands to more than %d bytes", maxBackupSize)
+		}
+		if first {
+			if hdr.Name != backupManifestName {
+				return m, nil, errors.New("archive does not start with a manifest")
+			}
+			if err := json.Unmarshal(raw, &m); err != nil {
+				return m, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			first = false
+			continue
+		}
+		name, ok := strings.CutPrefix(hdr.Name, "data/")
+		if !ok || name != path.Clean(name) || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return m, nil, fmt.Errorf("unexpected entry %q", hdr.Name)
+		}
+		files[name] = raw
+	}
+	if first {
+		return m, nil, errors.New("archive is empty")
+	}
+	if m.SchemaVersion < 1 || m.SchemaVersion > backupSchemaVersion {
+		return m, nil, fmt.Errorf("archive has schema version %d, this build supports up to %d", m.SchemaVersion, backupSchemaVersion)
+	}
+	if len(files) != len(m.Files) {
+		return m, nil, fmt.Errorf("archive has %d files, manifest lists %d", len(files), len(m.Files))
+	}
+	for _, f := range m.Files {
+		raw, ok := files[f.Name]
+		if !ok {
+			return m, nil, fmt.Errorf("%s is missing", f.Name)
+		}
+		sum := sha256.Sum256(raw)
+		if int64(len(raw)) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			return m, nil, fmt.Errorf("%s does not match its checksum", f.Name)
+		}
+	}
+	return m, files, nil
+}
+
+// restoreBackup writes the archive into dir, which must not hold any data.
+func restoreBackup(r io.Reader, dir string) (BackupManifest, error) {
+	m, files, err := readBackup(r)
+	if err != nil {
+		return m, err
+	}
+	if raw, ok := files["history.json"]; ok {
+		var f storeFile
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return m, fmt.Errorf("history.json: %w", err)
+		}
+	}
+	if existing, _ := collectDataFiles(dir, false); len(existing) > 0 {
+		return m, fmt.Errorf("%s is not empty, restore only into a new instance", dir)
+	}
+	for name, raw := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return m, err
+		}
+		if err := os.WriteFile(p, raw, 0o600); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+func backupFileName() string {
+	return "aio-backup-" + time.Now().UTC().Format("20060102-150405") + ".tar.gz"
+}
+
+// runBackupCommand implements
+//
+//	aio backup [-o file] [-keys]
+//	aio backup verify <file>
+//	aio restore <file>
+//
+// backup reads the data files from disk, so it is safe next to a running
+// server; use GET /admin/backup for a snapshot taken from memory. Key
+// files are only included with -keys, or backup_include_keys=1 for the
+// endpoint.
+func runBackupCommand(args []string) error {
+	switch args[0] {
+	case "backup":
+		if len(args) == 3 && args[1] == "verify" {
+			f, err := os.Open(args[2])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			m, _, err := readBackup(f)
+			if err != nil {
+				return err
+			}
+			log.Printf("✅ %s is intact: schema %d, %d files, created %s", args[2], m.SchemaVersion, len(m.Files), m.CreatedAt.Format(time.RFC3339))
+			return nil
+		}
+		fl := flag.NewFlagSet("backup", flag.ContinueOnError)
+		out := fl.String("o", backupFileName(), "archive to write")
+		keys := fl.Bool("keys", false, "include the evidence signing key and embed secret")
+		if err := fl.Parse(args[1:]); err != nil {
+			return err
+		}
+		files, err := collectDataFiles(dataDir(), false)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		m, err := writeBackup(&buf, files, *keys)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, buf.Bytes(), 0o600); err != nil {
+			return err
+		}
+		log.Printf("💾 wrote %s with %d files", *out, len(m.Files))
+		if len(m.OmittedKeys) > 0 {
+			log.Printf("🔑 left out %s, pass -keys to include them", strings.Join(m.OmittedKeys, ", "))
+		}
+		return nil
+	case "restore":
+		if len(args) != 2 {
+			return errors.New("usage: restore <archive>")
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m, err := restoreBackup(f, dataDir())
+		if err != nil {
+			return err
+		}
+		log.Printf("✅ restored %d files from %s into %s", len(m.Files), m.CreatedAt.Format(time.RFC3339), dataDir())
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// adminBackup streams a backup of the running instance, without key files
+// unless backup_include_keys=1.
+func adminBackup(w http.ResponseWriter, r *http.Request) {
+	files, err := collectDataFiles(dataDir(), true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if _, err := writeBackup(&buf, files, os.Getenv("backup_include_keys") == "1"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+backupFileName()+`"`)
+	w.Write(buf.Bytes())
+}
+
+// apiVerifyBackup checks an uploaded archive without restoring it.
+func apiVerifyBackup(w http.ResponseWriter, r *http.Request) {
+	m, _, err := readBackup(http.MaxBytesReader(w, r.Body, 1<<30))
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testArchive writes entries as a gzipped tar in the given order.
+func testArchive(t *testing.T, entries ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e[0], Mode: 0o600, Size: int64(len(e[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e[1]))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	files := map[string][]byte{
+		"history.json":          []byte(`{"next_id":2,"snapshots":[]}`),
+		"settings.json":         []byte(`{"fetch_interval":"1h"}`),
+		"exchanges/1.json":      []byte(`[]`),
+		"evidence/abc.json":     []byte(`{"document":{}}`),
+		"sitemaps/sitemap.xml":  []byte(`<urlset/>`),
+		"empty/placeholder.txt": {},
+	}
+	var buf bytes.Buffer
+	written, err := writeBackup(&buf, files, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, got, err := readBackup(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, files) || !reflect.DeepEqual(m.Files, written.Files) || m.SchemaVersion != backupSchemaVersion {
+		t.Fatalf("read back %+v, %v", m, got)
+	}
+
+	dir := t.TempDir()
+	if _, err := restoreBackup(bytes.NewReader(buf.Bytes()), dir); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := collectDataFiles(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored, files) {
+		t.Errorf("restored %v, want %v", restored, files)
+	}
+	if _, err := restoreBackup(bytes.NewReader(buf.Bytes()), dir); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("restore over existing data: err = %v", err)
+	}
+}
+
+func TestReadBackupRejectsTampering(t *testing.T) {
+	var buf bytes.Buffer
+	m, err := writeBackup(&buf, map[string][]byte{"history.json": []byte(`{"snapshots":[]}`)}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := func(edit func(*BackupManifest)) string {
+		c := m
+		c.Files = append([]BackupFile(nil), m.Files...)
+		edit(&c)
+		raw, _ := json.Marshal(c)
+		return string(raw)
+	}
+	good := manifest(func(*BackupManifest) {})
+
+	// An entry that claims more than the limit; only its header is written.
+	var huge bytes.Buffer
+	gz := gzip.NewWriter(&huge)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: backupManifestName, Mode: 0o600, Size: maxBackupEntry + 1})
+	gz.Close()
+
+	tests := []struct {
+		name    string
+		archive []byte
+		err     string
+	}{
+		{"not gzip", []byte("history.json"), "not a backup archive"},
+		{"empty", testArchive(t), "archive is empty"},
+		{"manifest not first", testArchive(t, [2]string{"data/history.json", `{"snapshots":[]}`}, [2]string{backupManifestName, good}), "does not start with a manifest"},
+		{"invalid manifest", testArchive(t, [2]string{backupManifestName, "{"}), "invalid manifest"},
+		{"future schema", testArchive(t, [2]string{backupManifestName, manifest(func(m *BackupManifest) { m.SchemaVersion = backupSchemaVersion + 1 })},
+			[2]string{"data/history.json", `{"snapshots":[]}`}), "schema version"},
+		{"edited file", testArchive(t, [2]string{backupManifestName, good}, [2]string{"data/history.json", `{"snapshots":[{}]}`}), "does not match its checksum"},
+		{"same size edit", testArchive(t, [2]string{backupManifestName, good}, [2]string{"data/history.json", `{"snapshots":[ ]}`}), "does not match its checksum"},
+		{"missing file", testArchive(t, [2]string{backupManifestName, good}, [2]string{"data/settings.json", `{}`}), "is missing"},
+		{"extra file", testArchive(t, [2]string{backupManifestName, good}, [2]string{"data/history.json", `{"snapshots":[]}`},
+			[2]string{"data/settings.json", `{}`}), "archive has 2 files"},
+		{"path traversal", testArchive(t, [2]string{backupManifestName, good}, [2]string{"data/../history.json", `{"snapshots":[]}`}), "unexpected entry"},
+		{"oversized entry", huge.Bytes(), "is larger than"},
+		{"outside data", testArchive(t, [2]string{backupManifestName, good}, [2]string{"history.json", `{"snapshots":[]}`}), "unexpected entry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readBackup(bytes.NewReader(tt.archive))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	// A bad archive must not leave anything behind.
+	dir := t.TempDir()
+	if _, err := restoreBackup(bytes.NewReader(tests[5].archive), dir); err == nil {
+		t.Fatal("restored an edited archive")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("restore wrote %v", entries)
+	}
+}
+
+func TestBackupKeyFiles(t *testing.T) {
+	files := map[string][]byte{
+		"history.json": []byte(`{"snapshots":[]}`),
+		"evidence_key": []byte("seed"),
+		"embed_secret": []byte("secret"),
+	}
+	tests := []struct {
+		keys        bool
+		wantFiles   []string
+		wantOmitted []string
+	}{
+		{false, []string{"history.json"}, []string{"embed_secret", "evidence_key"}},
+		{true, []string{"embed_secret", "evidence_key", "history.json"}, nil},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if _, err := writeBackup(&buf, files, tt.keys); err != nil {
+			t.Fatal(err)
+		}
+		m, got, err := readBackup(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range m.Files {
+			names = append(names, f.Name)
+		}
+		if !reflect.DeepEqual(names, tt.wantFiles) || len(got) != len(tt.wantFiles) {
+			t.Errorf("keys=%v: files %v, want %v", tt.keys, names, tt.wantFiles)
+		}
+		if m.KeysIncluded != tt.keys || !reflect.DeepEqual(m.OmittedKeys, tt.wantOmitted) {
+			t.Errorf("keys=%v: manifest says included=%v, omitted %v", tt.keys, m.KeysIncluded, m.OmittedKeys)
+		}
+	}
+	if len(files) != 3 {
+		t.Error("writeBackup changed the caller's files")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Project is a named group of keywords that badges report on
+type Project struct {
+	Name       string
+	KeywordIDs map[string]bool // nil means every keyword
+}
+
+// parseProjects reads projects=docs=harga emas,kurs dollar;shop=beli emas.
+// "all" (every stored keyword) and "tracked" (imported keywords) always exist.
+func parseProjects(spec string) map[string]Project {
+	out := map[string]Project{"all": {Name: "all"}}
+	for _, entry := range strings.Split(spec, ";") {
+		name, kws, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			continue
+		}
+		p := Project{Name: name, KeywordIDs: map[string]bool{}}
+		for _, kw := range splitAliases(kws) {
+			_, id := CanonicalKeyword(kw)
+			p.KeywordIDs[id] = true
+		}
+		out[name] = p
+	}
+	return out
+}
+
+var projects = parseProjects(os.Getenv("projects"))
+
+func findProject(name string) (Project, bool) {
+	name = strings.ToLower(name)
+	if name == "tracked" {
+		p := Project{Name: name, KeywordIDs: map[string]bool{}}
+		for _, tk := range store.Tracked() {
+			p.KeywordIDs[tk.KeywordID] = true
+		}
+		return p, true
+	}
+	p, ok := projects[name]
+	return p, ok
+}
+
+// BadgeStatus summarises how a domain or URL is cited in a project's latest overviews
+type BadgeStatus struct {
+	Project   string    `json:"project"`
+	Target    string    `json:"target"`
+	Overviews int       `json:"overviews"` // latest lookups that returned an overview
+	Cited     int       `json:"cited"`     // of those, how many cite the target
+	Updated   time.Time `json:"updated"`   // newest lookup considered
+}
+
+// citesTarget matches a reference link against a bare domain (including its
+// subdomains) or, when target has a path, against that exact page.
+func citesTarget(link, target string) bool {
+	if !strings.Contains(target, "/") {
+		host := referenceHost(link)
+		d := strings.TrimPrefix(target, "www.")
+		return host == d || strings.HasSuffix(host, "."+d)
+	}
+	return pageKey(link) == pageKey(target)
+}
+
+// pageKey reduces a URL to host and path, ignoring scheme, www., query,
+// fragment and trailing slash.
+func pageKey(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return strings.ToLower(link)
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimRight(u.Path, "/")
+}
+
+// BuildBadgeStatus looks at the latest lookup of every keyword and locale in p.
+func BuildBadgeStatus(p Project, target string) BadgeStatus {
+	target = strings.ToLower(strings.TrimSpace(target))
+	st := BadgeStatus{Project: p.Name, Target: target}
+	for _, snaps := range snapshotSeries() {
+		snap := snaps[len(snaps)-1]
+		if p.KeywordIDs != nil && !p.KeywordIDs[snap.KeywordID] {
+			continue
+		}
+		if snap.FetchedAt.After(st.Updated) {
+			st.Updated = snap.FetchedAt
+		}
+		if snap.Overview == nil || snap.Overview.IsEmpty() {
+			continue
+		}
+		st.Overviews++
+		for _, ref := range snap.Overview.References {
+			if citesTarget(ref.Link, target) {
+				st.Cited++
+				break
+			}
+		}
+	}
+	return st
+}
+
+// Message and Color follow shields.io conventions.
+func (st BadgeStatus) Message() string {
+	switch {
+	case st.Overviews == 0:
+		return "no data"
+	case st.Cited == 0:
+		return "not cited"
+	default:
+		return fmt.Sprintf("cited %d/%d", st.Cited, st.Overviews)
+	}
+}
+
+func (st BadgeStatus) Color() string {
+	switch {
+	case st.Overviews == 0:
+		return "#9f9f9f"
+	case st.Cited == 0:
+		return "#e05d44"
+	case float64(st.Cited)/float64(st.Overviews) >= 0.5:
+		return "#4c1"
+	default:
+		return "#dfb317"
+	}
+}
+
+var badgeTmpl = `<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+<title>{{.Label}}: {{.Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+<rect width="{{.Width}}" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text>
+<text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{.Message}}</text>
+<text x="{{.MessageX}}" y="14">{{.Message}}</text>
+</g>
+</svg>
+`
+
+var badgeTpl = template.Must(template.New("badge").Parse(badgeTmpl))
+
+// textWidth approximates Verdana 11px, which is what shields.io measures.
+func textWidth(s string) int {
+	return len([]rune(s))*7 + 10
+}
+
+// badge serves GET /badge/{project}/{target...}, where target is a domain
+// or URL followed by .svg.
+func badge(w http.ResponseWriter, r *http.Request) {
+	target, ok := strings.CutSuffix(r.PathValue("target"), ".svg")
+	if !ok || target == "" {
+		http.NotFound(w, r)
+		return
+	}
+	p, ok := findProject(r.PathValue("project"))
+	if !ok {
+		http.Error(w, "unknown project", http.StatusNotFound)
+		return
+	}
+	st := BuildBadgeStatus(p, target)
+	label := "AI Overview"
+	if l := r.URL.Query().Get("label"); l != "" {
+		label = l
+	}
+	data := struct {
+		Label, Message, Color           string
+		Width, LabelWidth, MessageWidth int
+		LabelX, MessageX                float64
+	}{Label: label, Message: st.Message(), Color: st.Color()}
+	data.LabelWidth, data.MessageWidth = textWidth(data.Label), textWidth(data.Message)
+	data.Width = data.LabelWidth + data.MessageWidth
+	data.LabelX = float64(data.LabelWidth) / 2
+	data.MessageX = float64(data.LabelWidth) + float64(data.MessageWidth)/2
+
+	sum := sha1.Sum([]byte(label + "|" + data.Message + "|" + data.Color))
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=3600, s-maxage=3600")
+	if !st.Updated.IsZero() {
+		w.Header().Set("Last-Modified", st.Updated.UTC().Format(http.TimeFormat))
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	if err := badgeTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering badge", http.StatusInternalServerError)
+	}
+}
+
+// apiBadge returns the numbers behind a badge.
+func apiBadge(w http.ResponseWriter, r *http.Request) {
+	p, ok := findProject(r.PathValue("project"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown project"})
+		return
+	}
+	writeJSON(w, http.StatusOK, BuildBadgeStatus(p, r.PathValue("target")))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Exchange is one recorded SerpAPI call. The API key is removed from the
+// URL and from the body before it is kept.
+type Exchange struct {
+	Engine         string      `json:"engine"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header,omitempty"`
+	Status         string      `json:"status"` // e.g. "200 OK"
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	Body           []byte      `json:"body"`
+	RequestedAt    time.Time   `json:"requested_at"`
+	RespondedAt    time.Time   `json:"responded_at"`
+}
+
+// upstreamRecorder is an http.RoundTripper that keeps a copy of every
+// exchange made through it.
+type upstreamRecorder struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+func (rec *upstreamRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	u := *req.URL
+	q := u.Query()
+	q.Del("api_key")
+	u.RawQuery = q.Encode()
+	reqHeader := req.Header.Clone()
+	if reqHeader == nil {
+		reqHeader = http.Header{}
+	}
+	reqHeader.Set("Host", req.URL.Host)
+	rec.mu.Lock()
+	rec.exchanges = append(rec.exchanges, Exchange{
+		Engine:         q.Get("engine"),
+		Method:         req.Method,
+		URL:            u.String(),
+		RequestHeader:  reqHeader,
+		Status:         resp.Status,
+		ResponseHeader: resp.Header.Clone(),
+		Body:           []byte(redactKeys(string(body))),
+		RequestedAt:    started,
+		RespondedAt:    time.Now(),
+	})
+	rec.mu.Unlock()
+	return resp, nil
+}
+
+func (rec *upstreamRecorder) Exchanges() []Exchange {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]Exchange(nil), rec.exchanges...)
+}
+
+// Exchanges are kept next to the store, one file per snapshot, so the
+// history file stays small.
+func capturePath(snapshotID int64) string {
+	return filepath.Join(dataDir(), "captures", fmt.Sprintf("%d.json", snapshotID))
+}
+
+func saveExchanges(snapshotID int64, ex []Exchange) error {
+	p := capturePath(snapshotID)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(ex)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// loadExchanges returns the recorded calls behind a snapshot, or none for
+// snapshots taken before recording existed.
+func loadExchanges(snapshotID int64) ([]Exchange, error) {
+	raw, err := os.ReadFile(capturePath(snapshotID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ex []Exchange
+	return ex, json.Unmarshal(raw, &ex)
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Claim types
+const (
+	ClaimCurrency    = "currency"
+	ClaimPercentage  = "percentage"
+	ClaimMeasurement = "measurement"
+	ClaimDate        = "date"
+)
+
+// Claim is a typed value stated in an overview passage
+type Claim struct {
+	Type             string  `json:"type"`
+	Text             string  `json:"text"`            // as written, e.g. "Rp1,2 juta"
+	Value            float64 `json:"value,omitempty"` // numeric value, unset for dates
+	Unit             string  `json:"unit,omitempty"`  // IDR, USD, %, mg, ...
+	Normalized       string  `json:"normalized"`      // "1200000 IDR", "2025-08-17"
+	Subject          string  `json:"subject"`         // canonical words leading up to the value
+	Sentence         string  `json:"sentence"`
+	ReferenceIndexes []int   `json:"reference_indexes,omitempty"`
+}
+
+// Key identifies the "same" claim across snapshots of one query.
+func (c Claim) Key() string {
+	return c.Type + "|" + c.Unit + "|" + c.Subject
+}
+
+const numberPattern = `(\d[\d.,]*\d|\d)`
+
+var (
+	currencyRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}\d])((rp\.?|idr|us\$|usd|\$|€|eur)\s?` + numberPattern + `(?:\s?(ribu|rb|juta|jt|miliar|milyar|triliun|thousand|million|billion|k)\b)?)`)
+	percentRe  = regexp.MustCompile(`(?i)(?:^|[^\p{L}\d])(` + numberPattern + `\s?(%|persen\b|percent\b))`)
+	measureRe  = regexp.MustCompile(`(?i)(?:^|[^\p{L}\d])(` + numberPattern + `\s?(mg|mcg|gram|gr|g|kg|ton|ml|liter|litre|l|mm|cm|meter|m|km|inci|inch|gb|tb|mah|watt|w|kcal|kalori|calories)\b)`)
+	isoDateRe  = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	slashRe    = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`) // day/month/year, as written in Indonesia
+	dmyRe      = regexp.MustCompile(`(?i)\b(\d{1,2})\s+([\p{L}]+)\s+(\d{4})\b`)
+	mdyRe      = regexp.MustCompile(`(?i)\b([\p{L}]+)\s+(\d{1,2}),?\s+(\d{4})\b`)
+)
+
+var monthNames = map[string]time.Month{
+	"januari": 1, "january": 1, "jan": 1,
+	"februari": 2, "february": 2, "feb": 2,
+	"maret": 3, "march": 3, "mar": 3,
+	"april": 4, "apr": 4,
+	"mei": 5, "may": 5,
+	"juni": 6, "june": 6, "jun": 6,
+	"juli": 7, "july": 7, "jul": 7,
+	"agustus": 8, "august": 8, "agu": 8, "aug": 8,
+	"september": 9, "sep": 9, "sept": 9,
+	"oktober": 10, "october": 10, "okt": 10, "oct": 10,
+	"november": 11, "nov": 11,
+	"desember": 12, "december": 12, "des": 12, "dec": 12,
+}
+
+var multipliers = map[string]float64{
+	"ribu": 1e3, "rb": 1e3, "k": 1e3, "thousand": 1e3,
+	"juta": 1e6, "jt": 1e6, "million": 1e6,
+	"miliar": 1e9, "milyar": 1e9, "billion": 1e9,
+	"triliun": 1e12,
+}
+
+var currencyUnits = map[string]string{
+	"rp": "IDR", "rp.": "IDR", "idr": "IDR",
+	"$": "USD", "us$": "USD", "usd": "USD",
+	"€": "EUR", "eur": "EUR",
+}
+
+var unitAliases = map[string]string{"gr": "g", "gram": "g", "litre": "l", "liter": "l", "meter": "m", "inci": "inch", "kalori": "kcal", "calories": "kcal"}
+
+// parseNumber reads numbers written in Indonesian ("1.200.000,50") or
+// English ("1,200,000.50") style. A single separator followed by exactly
+// three digits is a thousands separator.
+func parseNumber(s string) (float64, bool) {
+	dots, commas := strings.Count(s, "."), strings.Count(s, ",")
+	switch {
+	case dots > 0 && commas > 0:
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case dots > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	case commas > 1:
+		s = strings.ReplaceAll(s, ",", "")
+	case dots == 1 || commas == 1:
+		sep := "."
+		if commas == 1 {
+			sep = ","
+		}
+		if len(s)-strings.Index(s, sep)-1 == 3 {
+			s = strings.Replace(s, sep, "", 1)
+		} else {
+			s = strings.Replace(s, sep, ".", 1)
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// ExtractClaims pulls typed claims out of every passage of ai.
+func ExtractClaims(ai *AIOverview) []Claim {
+	if ai == nil {
+		return nil
+	}
+	var out []Claim
+	for _, p := range ai.Passages() {
+		for _, sentence := range splitSentences(p.Text) {
+			for _, c := range extractSentenceClaims(sentence) {
+				c.Sentence = sentence
+				c.ReferenceIndexes = p.ReferenceIndexes
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+func extractSentenceClaims(sentence string) []Claim {
+	var out []Claim
+	var taken [][2]int
+	overlaps := func(start, end int) bool {
+		for _, t := range taken {
+			if start < t[1] && end > t[0] {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(start, end int, c Claim) {
+		if overlaps(start, end) {
+			return
+		}
+		taken = append(taken, [2]int{start, end})
+		c.Text = sentence[start:end]
+		c.Subject = claimSubject(sentence[:start])
+		out = append(out, c)
+	}
+
+	for _, m := range currencyRe.FindAllStringSubmatchIndex(sentence, -1) {
+		v, ok := parseNumber(sentence[m[6]:m[7]])
+		if !ok {
+			continue
+		}
+		if m[8] >= 0 {
+			v *= multipliers[strings.ToLower(sentence[m[8]:m[9]])]
+		}
+		unit := currencyUnits[strings.ToLower(sentence[m[4]:m[5]])]
+		add(m[2], m[3], Claim{Type: ClaimCurrency, Value: v, Unit: unit, Normalized: formatValue(v) + " " + unit})
+	}
+	for _, m := range percentRe.FindAllStringSubmatchIndex(sentence, -1) {
+		if v, ok := parseNumber(sentence[m[4]:m[5]]); ok {
+			add(m[2], m[3], Claim{Type: ClaimPercentage, Value: v, Unit: "%", Normalized: formatValue(v) + "%"})
+		}
+	}
+	for _, m := range measureRe.FindAllStringSubmatchIndex(sentence, -1) {
+		v, ok := parseNumber(sentence[m[4]:m[5]])
+		if !ok {
+			continue
+		}
+		unit := strings.ToLower(sentence[m[6]:m[7]])
+		// Single-letter units need a space: "5G" and "4K" are names, not grams.
+		if len(unit) == 1 && m[6] == m[5] {
+			continue
+		}
+		if alias, ok := unitAliases[unit]; ok {
+			unit = alias
+		}
+		add(m[2], m[3], Claim{Type: ClaimMeasurement, Value: v, Unit: unit, Normalized: formatValue(v) + " " + unit})
+	}
+
+	date := func(start, end int, y, mo, d int) {
+		if mo < 1 || mo > 12 || d < 1 || d > 31 {
+			return
+		}
+		t := time.Date(y, time.Month(mo), d, 0, 0, 0, 0, time.UTC)
+		if t.Day() != d {
+			return // e.g. 30 February
+		}
+		add(start, end, Claim{Type: ClaimDate, Normalized: t.Format("2006-01-02")})
+	}
+	atoi := func(s string) int { n, _ := strconv.Atoi(s); return n }
+	for _, m := range isoDateRe.FindAllStringSubmatchIndex(sentence, -1) {
+		date(m[0], m[1], atoi(sentence[m[2]:m[3]]), atoi(sentence[m[4]:m[5]]), atoi(sentence[m[6]:m[7]]))
+	}
+	for _, m := range slashRe.FindAllStringSubmatchIndex(sentence, -1) {
+		date(m[0], m[1], atoi(sentence[m[6]:m[7]]), atoi(sentence[m[4]:m[5]]), atoi(sentence[m[2]:m[3]]))
+	}
+	for _, m := range dmyRe.FindAllStringSubmatchIndex(sentence, -1) {
+		if mo, ok := monthNames[strings.ToLower(sentence[m[4]:m[5]])]; ok {
+			date(m[0], m[1], atoi(sentence[m[6]:m[7]]), int(mo), atoi(sentence[m[2]:m[3]]))
+		}
+	}
+	for _, m := range mdyRe.FindAllStringSubmatchIndex(sentence, -1) {
+		if mo, ok := monthNames[strings.ToLower(sentence[m[2]:m[3]])]; ok {
+			date(m[0], m[1], atoi(sentence[m[6]:m[7]]), int(mo), atoi(sentence[m[4]:m[5]]))
+		}
+	}
+	return out
+}
+
+var subjectStopwords = wordSet(
+	"hari", "ini", "saat", "sekarang", "dari", "pada", "di", "ke", "yang", "adalah", "yaitu", "sebesar", "sekitar", "kurang", "lebih", "menjadi", "dan", "atau", "per", "mulai",
+	"the", "a", "an", "is", "are", "of", "on", "at", "to", "from", "or", "and", "about", "around", "now", "today", "per", "was", "be", "by",
+)
+
+// claimSubject keeps the last three meaningful canonical words of the
+// clause before a value, which is usually enough to tell "harga emas" from
+// "harga perak".
+func claimSubject(prefix string) string {
+	for _, sep := range []string{", ", "; ", ": ", "("} {
+		if i := strings.LastIndex(prefix, sep); i >= 0 {
+			prefix = prefix[i+len(sep):]
+		}
+	}
+	var words []string
+	for _, w := range strings.Fields(NormalizeQuery(prefix, normalizeOptions)) {
+		if subjectStopwords[w] || strings.ContainsAny(w, "0123456789") {
+			continue
+		}
+		words = append(words, w)
+	}
+	if len(words) > 3 {
+		words = words[len(words)-3:]
+	}
+	return strings.Join(words, " ")
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// claimChangeThreshold is the relative change that raises an alert, 0.1 = 10%.
+func claimChangeThreshold() float64 {
+	return currentSettings().ClaimChangeThreshold
+}
+
+// ClaimChange is a claim whose value differs between two snapshots
+type ClaimChange struct {
+	Key      string  `json:"key"`
+	Before   Claim   `json:"before"`
+	After    Claim   `json:"after"`
+	Relative float64 `json:"relative"` // (after-before)/before, 0 for dates
+}
+
+// Significant reports whether the change is worth an alert.
+func (c ClaimChange) Significant(threshold float64) bool {
+	if c.After.Type == ClaimDate {
+		return c.Before.Normalized != c.After.Normalized
+	}
+	return math.Abs(c.Relative) >= threshold
+}
+
+// CompareClaims matches the claims of two snapshots by key and returns the
+// ones whose value changed.
+func CompareClaims(before, after []Claim) []ClaimChange {
+	prev := map[string]Claim{}
+	for _, c := range before {
+		if _, ok := prev[c.Key()]; !ok {
+			prev[c.Key()] = c
+		}
+	}
+	var out []ClaimChange
+	seen := map[string]bool{}
+	for _, c := range after {
+		p, ok := prev[c.Key()]
+		if !ok || seen[c.Key()] || p.Normalized == c.Normalized {
+			continue
+		}
+		seen[c.Key()] = true
+		change := ClaimChange{Key: c.Key(), Before: p, After: c}
+		if p.Value != 0 {
+			change.Relative = (c.Value - p.Value) / p.Value
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+// ClaimHistory tracks one claim key of a keyword across snapshots
+type ClaimHistory struct {
+	Key    string       `json:"key"`
+	Type   string       `json:"type"`
+	Points []ClaimPoint `json:"points"`
+}
+
+type ClaimPoint struct {
+	SnapshotID int64     `json:"snapshot_id"`
+	At         time.Time `json:"at"`
+	Claim      Claim     `json:"claim"`
+}
+
+// KeywordClaimHistory lists how every claim of a keyword evolved, in the
+// given locale.
+func KeywordClaimHistory(keywordID string, loc Locale) []ClaimHistory {
+	byKey := map[string]*ClaimHistory{}
+	for _, snap := range store.KeywordSnapshots(keywordID) {
+		if snap.Locale != loc {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, c := range ExtractClaims(snap.Overview) {
+			if seen[c.Key()] {
+				continue
+			}
+			seen[c.Key()] = true
+			h, ok := byKey[c.Key()]
+			if !ok {
+				h = &ClaimHistory{Key: c.Key(), Type: c.Type}
+				byKey[c.Key()] = h
+			}
+			h.Points = append(h.Points, ClaimPoint{SnapshotID: snap.ID, At: snap.FetchedAt, Claim: c})
+		}
+	}
+	out := make([]ClaimHistory, 0, len(byKey))
+	for _, h := range byKey {
+		out = append(out, *h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// checkClaimChanges compares snap with the previous successful snapshot of
+// the same keyword and locale, raising an alert for each significant change.
+func checkClaimChanges(snap Snapshot) {
+	if snap.Overview == nil {
+		return
+	}
+	var prev *Snapshot
+	for _, s := range store.KeywordSnapshots(snap.KeywordID) {
+		if s.ID < snap.ID && s.Locale == snap.Locale && s.Overview != nil {
+			prev = &s
+		}
+	}
+	if prev == nil {
+		return
+	}
+	threshold := claimChangeThreshold()
+	for _, change := range CompareClaims(ExtractClaims(prev.Overview), ExtractClaims(snap.Overview)) {
+		if !change.Significant(threshold) {
+			continue
+		}
+		msg := fmt.Sprintf("%s: %q changed from %s to %s", snap.Keyword, change.After.Subject, change.Before.Normalized, change.After.Normalized)
+		if change.Relative != 0 {
+			msg += fmt.Sprintf(" (%+.1f%%)", change.Relative*100)
+		}
+		raiseAlert(Alert{
+			Kind:       AlertClaimChange,
+			KeywordID:  snap.KeywordID,
+			Keyword:    snap.Keyword,
+			SnapshotID: snap.ID,
+			Message:    msg,
+		})
+	}
+}
+
+var claimsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Claims · {{.Keyword.Keyword}}</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.subject { color: #888; }
+	</style>
+</head>
+<body>
+	<h1>🔢 Claims for "{{.Keyword.Keyword}}"</h1>
+	<p><a href="/keywords">← Keywords</a> · locale {{.Locale}}</p>
+	{{range .History}}
+		<div class="text-block">
+			<strong>{{.Type}}</strong> <span class="subject">{{.Key}}</span>
+			<ul>
+			{{range .Points}}
+				<li>{{.At.Format "2006-01-02 15:04"}}: <strong>{{.Claim.Normalized}}</strong> — "{{.Claim.Sentence}}"{{if .Claim.ReferenceIndexes}} [refs {{range $i, $r := .Claim.ReferenceIndexes}}{{if $i}}, {{end}}{{$r}}{{end}}]{{else}} (uncited){{end}}</li>
+			{{end}}
+			</ul>
+		</div>
+	{{else}}
+		<p><em>No claims extracted yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var claimsTpl = template.Must(template.New("claims").Parse(claimsTmpl))
+
+func claimsPage(w http.ResponseWriter, r *http.Request) {
+	k, ok := store.FindKeyword(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	loc := localeFromRequest(r)
+	data := struct {
+		Keyword Keyword
+		Locale  Locale
+		History []ClaimHistory
+	}{k, loc, KeywordClaimHistory(k.ID, loc)}
+	if err := claimsTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiClaims(w http.ResponseWriter, r *http.Request) {
+	k, ok := store.FindKeyword(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "keyword not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, KeywordClaimHistory(k.ID, localeFromRequest(r)))
+}
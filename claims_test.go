@@ -0,0 +1,99 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"7", 7, true},
+		{"1.200.000", 1200000, true},
+		{"1,200,000", 1200000, true},
+		{"1.200.000,50", 1200000.5, true},
+		{"1,200,000.50", 1200000.5, true},
+		{"1.234", 1234, true}, // one separator before three digits groups thousands
+		{"1,234", 1234, true},
+		{"1,2", 1.2, true},
+		{"12,5", 12.5, true},
+		{"4.1", 4.1, true},
+		{"0.12", 0.12, true},
+		{"", 0, false},
+		{"1.2.3,4,5", 0, false},
+		{"abc", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseNumber(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseNumber(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtractSentenceClaims(t *testing.T) {
+	type claim struct{ Type, Text, Normalized, Subject string }
+	tests := []struct {
+		sentence string
+		want     []claim
+	}{
+		{"Harga emas Antam hari ini Rp1,2 juta per gram", []claim{
+			{ClaimCurrency, "Rp1,2 juta", "1200000 IDR", "harga emas antam"},
+		}},
+		{"Harga emas naik 2,5% menjadi Rp 1.350.000", []claim{
+			{ClaimCurrency, "Rp 1.350.000", "1350000 IDR", "harga emas naik"},
+			{ClaimPercentage, "2,5%", "2.5%", "harga emas naik"},
+		}},
+		{"Inflation was 3.1 percent, down from $2.5 billion", []claim{
+			{ClaimCurrency, "$2.5 billion", "2500000000 USD", "down"},
+			{ClaimPercentage, "3.1 percent", "3.1%", "inflation"},
+		}},
+		{"Dosis paracetamol dewasa 500 mg", []claim{
+			{ClaimMeasurement, "500 mg", "500 mg", "dosis paracetamol dewasa"},
+		}},
+		{"Berat emas batangan 100 gram", []claim{
+			{ClaimMeasurement, "100 gram", "100 g", "berat emas batangan"},
+		}},
+		{"Jaringan 5G tersedia", nil},
+		{"Tambahkan garam 5 g", []claim{{ClaimMeasurement, "5 g", "5 g", "tambahkan garam"}}},
+		{"Berlaku mulai 17 Agustus 2025", []claim{{ClaimDate, "17 Agustus 2025", "2025-08-17", "berlaku"}}},
+		{"Effective March 5, 2025", []claim{{ClaimDate, "March 5, 2025", "2025-03-05", "effective"}}},
+		{"Diperbarui 05/03/2025", []claim{{ClaimDate, "05/03/2025", "2025-03-05", "diperbarui"}}},
+		{"Released 2025-02-30", nil},
+		{"Tanggal 13/13/2025 tidak valid", nil},
+		{"Tersedia 24 jam di 3 cabang", nil},
+	}
+	for _, tt := range tests {
+		var got []claim
+		for _, c := range extractSentenceClaims(tt.sentence) {
+			got = append(got, claim{c.Type, c.Text, c.Normalized, c.Subject})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractSentenceClaims(%q) =\n%+v\nwant\n%+v", tt.sentence, got, tt.want)
+		}
+	}
+}
+
+func TestExtractClaimsKeepsReferences(t *testing.T) {
+	ai := &AIOverview{TextBlocks: []TextBlock{
+		{Type: "paragraph", Snippet: "Harga emas Rp1,2 juta. Harga perak Rp15 ribu.", ReferenceIndexes: []int{0, 2}},
+	}}
+	claims := ExtractClaims(ai)
+	if len(claims) != 2 {
+		t.Fatalf("got %d claims, want 2: %+v", len(claims), claims)
+	}
+	if claims[0].Key() == claims[1].Key() {
+		t.Errorf("gold and silver share key %q", claims[0].Key())
+	}
+	for _, c := range claims {
+		if !reflect.DeepEqual(c.ReferenceIndexes, []int{0, 2}) {
+			t.Errorf("claim %q has references %v", c.Text, c.ReferenceIndexes)
+		}
+	}
+	if claims[1].Value != 15000 {
+		t.Errorf("silver = %v, want 15000", claims[1].Value)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Search intents
+const (
+	IntentInformational = "informational"
+	IntentNavigational  = "navigational"
+	IntentCommercial    = "commercial"
+	IntentTransactional = "transactional"
+	IntentLocal         = "local"
+)
+
+var intents = []string{IntentInformational, IntentNavigational, IntentCommercial, IntentTransactional, IntentLocal}
+
+// SERPFeatures are the result blocks present on the regular Google SERP
+type SERPFeatures struct {
+	Shopping       bool `json:"shopping"`
+	LocalPack      bool `json:"local_pack"`
+	Ads            bool `json:"ads"`
+	KnowledgeGraph bool `json:"knowledge_graph"`
+}
+
+func serpFeatures(results map[string]interface{}) SERPFeatures {
+	has := func(keys ...string) bool {
+		for _, k := range keys {
+			if _, ok := results[k]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	return SERPFeatures{
+		Shopping:       has("shopping_results", "inline_shopping_results", "immersive_products"),
+		LocalPack:      has("local_results", "local_map"),
+		Ads:            has("ads"),
+		KnowledgeGraph: has("knowledge_graph"),
+	}
+}
+
+// Classification of a query by intent and topic
+type Classification struct {
+	Intent string `json:"intent"`
+	Topic  string `json:"topic"`
+}
+
+// Indonesian and English cue phrases per intent. Multi-word cues are
+// matched as phrases against the canonical query.
+var intentCues = map[string][]string{
+	IntentInformational: {
+		"apa", "apa itu", "bagaimana", "cara", "kenapa", "mengapa", "siapa", "kapan", "pengertian", "arti", "fungsi", "manfaat", "contoh", "sejarah", "tips",
+		"what", "how", "why", "who", "when", "meaning", "definition", "guide", "tutorial", "example",
+	},
+	IntentNavigational: {
+		"login", "masuk", "website", "situs", "situs resmi", "resmi", "official", "official site", "homepage", "customer service", "cs", "kontak", "contact",
+		"facebook", "instagram", "youtube", "tokopedia", "shopee", "gmail",
+	},
+	IntentCommercial: {
+		"harga", "terbaik", "review", "ulasan", "rekomendasi", "bandingkan", "perbandingan", "vs", "murah", "spesifikasi", "kelebihan", "kekurangan",
+		"price", "best", "top", "compare", "comparison", "cheap", "cheapest", "specs", "alternative", "alternatif",
+	},
+	IntentTransactional: {
+		"beli", "jual", "pesan", "order", "daftar", "unduh", "download", "promo", "diskon", "kupon", "voucher", "sewa", "booking", "tiket", "langganan",
+		"buy", "purchase", "sign up", "subscribe", "coupon", "discount", "deal", "rent", "ticket",
+	},
+	IntentLocal: {
+		"terdekat", "dekat", "di sekitar", "sekitar saya", "near me", "nearby", "alamat", "lokasi", "jam buka", "open now", "rute",
+		"jakarta", "bandung", "surabaya", "medan", "semarang", "yogyakarta", "jogja", "bali", "denpasar", "makassar", "bekasi", "tangerang", "depok", "bogor", "malang", "solo",
+	},
+}
+
+// Topic categories and their cue words
+var topicCues = map[string][]string{
+	"finance":       {"emas", "gold", "saham", "stock", "kripto", "crypto", "bitcoin", "bank", "kredit", "credit", "pinjaman", "loan", "kpr", "asuransi", "insurance", "pajak", "tax", "investasi", "investment", "reksadana", "bunga", "kurs", "dolar", "rupiah"},
+	"health":        {"sakit", "penyakit", "obat", "dokter", "gejala", "diet", "vitamin", "kesehatan", "hamil", "health", "disease", "symptom", "medicine", "doctor", "covid", "diabetes", "kolesterol", "hipertensi", "dosis", "dose"},
+	"technology":    {"hp", "laptop", "android", "iphone", "aplikasi", "app", "software", "komputer", "computer", "internet", "wifi", "ai", "programming", "coding", "golang", "python", "samsung", "xiaomi"},
+	"travel":        {"hotel", "wisata", "liburan", "pesawat", "flight", "travel", "tiket pesawat", "pantai", "beach", "visa", "paspor", "passport", "resort"},
+	"food":          {"resep", "recipe", "makanan", "food", "restoran", "restaurant", "kuliner", "masakan", "kopi", "coffee", "cafe", "minuman"},
+	"automotive":    {"mobil", "motor", "car", "motorcycle", "bensin", "sparepart", "honda", "toyota", "yamaha", "suzuki", "bengkel"},
+	"education":     {"sekolah", "kuliah", "universitas", "university", "beasiswa", "scholarship", "kursus", "course", "ujian", "exam", "skripsi", "belajar", "learn"},
+	"shopping":      {"baju", "sepatu", "tas", "fashion", "pakaian", "clothes", "shoes", "kosmetik", "skincare", "makeup", "parfum"},
+	"property":      {"rumah", "apartemen", "apartment", "tanah", "properti", "property", "kos", "kontrakan", "house"},
+	"legal":         {"hukum", "law", "undang undang", "pasal", "pengacara", "lawyer", "sertifikat", "akta", "izin", "ktp", "sim", "paspor online"},
+	"entertainment": {"film", "movie", "musik", "music", "lagu", "song", "game", "drama", "anime", "konser", "concert", "netflix"},
+	"sports":        {"bola", "sepak bola", "football", "badminton", "motogp", "liga", "league", "olahraga", "sport", "fitness", "gym"},
+}
+
+// TopicOther is assigned when no topic cue matches
+const TopicOther = "other"
+
+// Classify assigns an intent and topic to a canonical query, using the SERP
+// features of the lookup as extra signals.
+func Classify(canonical string, f SERPFeatures) Classification {
+	scores := map[string]int{}
+	for intent, cues := range intentCues {
+		for _, cue := range cues {
+			if containsPhrase(canonical, cue) {
+				scores[intent] += 2
+			}
+		}
+	}
+	if f.Shopping {
+		scores[IntentCommercial]++
+		scores[IntentTransactional]++
+	}
+	if f.Ads {
+		scores[IntentCommercial]++
+	}
+	if f.LocalPack {
+		scores[IntentLocal] += 2
+	}
+	if f.KnowledgeGraph {
+		scores[IntentInformational]++
+		scores[IntentNavigational]++
+	}
+
+	c := Classification{Intent: IntentInformational, Topic: TopicOther}
+	best := 0
+	for _, intent := range intents { // fixed order keeps ties deterministic
+		if scores[intent] > best {
+			best = scores[intent]
+			c.Intent = intent
+		}
+	}
+
+	best = 0
+	for topic, cues := range topicCues {
+		n := 0
+		for _, cue := range cues {
+			if containsPhrase(canonical, cue) {
+				n++
+			}
+		}
+		if n > best || (n == best && n > 0 && topic < c.Topic) {
+			best = n
+			c.Topic = topic
+		}
+	}
+	return c
+}
+
+// containsPhrase reports whether phrase appears in s on word boundaries.
+func containsPhrase(s, phrase string) bool {
+	return strings.Contains(" "+s+" ", " "+phrase+" ")
+}
+
+func validIntent(intent string) bool {
+	for _, i := range intents {
+		if i == intent {
+			return true
+		}
+	}
+	return false
+}
+
+func validTopic(topic string) bool {
+	_, ok := topicCues[topic]
+	return ok || topic == TopicOther
+}
+
+// topics lists all topic categories in display order.
+func topics() []string {
+	out := make([]string, 0, len(topicCues)+1)
+	for topic := range topicCues {
+		out = append(out, topic)
+	}
+	sort.Strings(out)
+	return append(out, TopicOther)
+}
@@ -0,0 +1,847 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Co-citation network: domains are nodes, and two domains share an edge
+// weighted by the number of overviews citing both. Only the latest overview
+// of each keyword and locale counts unless all snapshots are asked for, so
+// frequently refreshed keywords do not dominate the graph.
+
+const (
+	maxCoCitationReferences = 100  // per overview; pairs grow with the square
+	maxCoCitationDomains    = 1000 // per graph; betweenness runs a shortest path search from every domain
+)
+
+// CoCitationFilter selects the stored overviews to build the graph from
+type CoCitationFilter struct {
+	Topic     string `json:"topic,omitempty"`
+	Locale    string `json:"locale,omitempty"` // hl-gl
+	All       bool   `json:"all,omitempty"`    // every snapshot, not only the latest per keyword
+	MinWeight int    `json:"min_weight"`       // edges below are dropped
+}
+
+// CoCitationNode is a cited domain
+type CoCitationNode struct {
+	Domain      string  `json:"domain"`
+	Overviews   int     `json:"overviews"` // overviews citing the domain
+	Degree      int     `json:"degree"`
+	Strength    int     `json:"strength"` // sum of edge weights
+	Betweenness float64 `json:"betweenness"`
+	Eigenvector float64 `json:"eigenvector"`
+	Community   int     `json:"community"`
+}
+
+// CoCitationEdge joins two domains cited in the same overview
+type CoCitationEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// CoCitationCommunity is a cluster of domains cited together
+type CoCitationCommunity struct {
+	ID      int      `json:"id"`
+	Domains []string `json:"domains"` // most cited first
+	Weight  int      `json:"weight"`  // edge weight inside the community
+}
+
+// CoCitationGraph is the network and its analysis
+type CoCitationGraph struct {
+	Filter      CoCitationFilter      `json:"filter"`
+	Overviews   int                   `json:"overviews"`
+	Nodes       []CoCitationNode      `json:"nodes"` // most central first
+	Edges       []CoCitationEdge      `json:"edges"` // heaviest first
+	Communities []CoCitationCommunity `json:"communities"`
+	Modularity  float64               `json:"modularity"`
+	Dropped     int                   `json:"dropped_domains,omitempty"` // least cited domains beyond maxCoCitationDomains
+}
+
+// coCitationOverviews picks the stored overviews matching f.
+func coCitationOverviews(f CoCitationFilter) []*AIOverview {
+	topics := map[string]string{}
+	for _, k := range store.Keywords() {
+		topics[k.ID] = k.Classification.Topic
+	}
+	match := func(s Snapshot) bool {
+		return s.Overview != nil && len(s.Overview.References) > 0 &&
+			(f.Topic == "" || topics[s.KeywordID] == f.Topic) &&
+			(f.Locale == "" || s.Locale.String() == f.Locale)
+	}
+	var out []*AIOverview
+	for _, series := range snapshotSeries() {
+		for i := len(series) - 1; i >= 0; i-- {
+			if match(series[i]) {
+				out = append(out, series[i].Overview)
+				if !f.All {
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// BuildCoCitationGraph builds and analyses the network of overviews.
+func BuildCoCitationGraph(overviews []*AIOverview, f CoCitationFilter) CoCitationGraph {
+	g := CoCitationGraph{Filter: f, Overviews: len(overviews)}
+	if f.MinWeight < 1 {
+		g.Filter.MinWeight = 1
+	}
+	cited := map[string]int{}
+	lists := make([][]string, 0, len(overviews))
+	for _, ai := range overviews {
+		lists = append(lists, overviewDomains(ai))
+		for _, d := range lists[len(lists)-1] {
+			cited[d]++
+		}
+	}
+	if len(cited) > maxCoCitationDomains {
+		// Keep the most cited domains so the analysis stays tractable.
+		var all []string
+		for d := range cited {
+			all = append(all, d)
+		}
+		sort.Slice(all, func(i, j int) bool {
+			if cited[all[i]] != cited[all[j]] {
+				return cited[all[i]] > cited[all[j]]
+			}
+			return all[i] < all[j]
+		})
+		for _, d := range all[maxCoCitationDomains:] {
+			delete(cited, d)
+		}
+		g.Dropped = len(all) - maxCoCitationDomains
+	}
+	weights := map[[2]string]int{}
+	for _, domains := range lists {
+		for i, a := range domains {
+			if cited[a] == 0 {
+				continue
+			}
+			for _, b := range domains[i+1:] {
+				if cited[b] > 0 {
+					weights[[2]string{a, b}]++
+				}
+			}
+		}
+	}
+
+	var names []string
+	for d := range cited {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+	index := map[string]int{}
+	for i, d := range names {
+		index[d] = i
+	}
+	adj := make([]map[int]float64, len(names))
+	for i := range adj {
+		adj[i] = map[int]float64{}
+	}
+	for pair, w := range weights {
+		if w < g.Filter.MinWeight {
+			continue
+		}
+		g.Edges = append(g.Edges, CoCitationEdge{Source: pair[0], Target: pair[1], Weight: w})
+		a, b := index[pair[0]], index[pair[1]]
+		adj[a][b], adj[b][a] = float64(w), float64(w)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Weight != g.Edges[j].Weight {
+			return g.Edges[i].Weight > g.Edges[j].Weight
+		}
+		return g.Edges[i].Source+" "+g.Edges[i].Target < g.Edges[j].Source+" "+g.Edges[j].Target
+	})
+
+	betweenness := weightedBetweenness(adj)
+	eigenvector := eigenvectorCentrality(adj)
+	community := louvain(adj)
+	g.Modularity = modularity(adj, community)
+
+	byCommunity := map[int][]int{}
+	for i, d := range names {
+		n := CoCitationNode{Domain: d, Overviews: cited[d], Degree: len(adj[i]), Betweenness: betweenness[i], Eigenvector: eigenvector[i]}
+		for _, w := range adj[i] {
+			n.Strength += int(w)
+		}
+		g.Nodes = append(g.Nodes, n)
+		byCommunity[community[i]] = append(byCommunity[community[i]], i)
+	}
+
+	// Number communities by size, largest first, so IDs are stable.
+	for _, members := range byCommunity {
+		sort.Slice(members, func(i, j int) bool {
+			a, b := g.Nodes[members[i]], g.Nodes[members[j]]
+			if a.Overviews != b.Overviews {
+				return a.Overviews > b.Overviews
+			}
+			return a.Domain < b.Domain
+		})
+		c := CoCitationCommunity{}
+		for _, m := range members {
+			c.Domains = append(c.Domains, names[m])
+			for n, w := range adj[m] {
+				if community[n] == community[m] && n > m {
+					c.Weight += int(w)
+				}
+			}
+		}
+		g.Communities = append(g.Communities, c)
+	}
+	sort.Slice(g.Communities, func(i, j int) bool {
+		a, b := g.Communities[i], g.Communities[j]
+		if len(a.Domains) != len(b.Domains) {
+			return len(a.Domains) > len(b.Domains)
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Domains[0] < b.Domains[0]
+	})
+	for i := range g.Communities {
+		g.Communities[i].ID = i + 1
+		for _, d := range g.Communities[i].Domains {
+			g.Nodes[index[d]].Community = i + 1
+		}
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		a, b := g.Nodes[i], g.Nodes[j]
+		if a.Eigenvector != b.Eigenvector {
+			return a.Eigenvector > b.Eigenvector
+		}
+		if a.Overviews != b.Overviews {
+			return a.Overviews > b.Overviews
+		}
+		return a.Domain < b.Domain
+	})
+	return g
+}
+
+// overviewDomains lists the distinct cited domains of an overview, sorted,
+// from at most maxCoCitationReferences references.
+func overviewDomains(ai *AIOverview) []string {
+	refs := ai.References
+	if len(refs) > maxCoCitationReferences {
+		refs = refs[:maxCoCitationReferences]
+	}
+	seen := map[string]bool{}
+	var domains []string
+	for _, ref := range refs {
+		if d := referenceHost(ref.Link); d != "" && !seen[d] {
+			seen[d] = true
+			domains = append(domains, d)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// weightedBetweenness is Brandes' algorithm with 1/weight as distance, so
+// paths through domains often cited together are short. Scores are
+// normalized to 0..1.
+func weightedBetweenness(adj []map[int]float64) []float64 {
+	n := len(adj)
+	cb := make([]float64, n)
+	for s := 0; s < n; s++ {
+		dist := make([]float64, n)
+		sigma := make([]float64, n)
+		delta := make([]float64, n)
+		preds := make([][]int, n)
+		done := make([]bool, n)
+		for i := range dist {
+			dist[i] = math.Inf(1)
+		}
+		dist[s], sigma[s] = 0, 1
+		var order []int
+		queue := &distQueue{{s, 0}}
+		for queue.Len() > 0 {
+			v := heap.Pop(queue).(distItem).node
+			if done[v] {
+				continue // a stale entry, v was reached by a shorter path
+			}
+			done[v] = true
+			order = append(order, v)
+			for w, weight := range adj[v] {
+				d := dist[v] + 1/weight
+				switch {
+				case d < dist[w]-1e-12:
+					dist[w], sigma[w], preds[w] = d, sigma[v], []int{v}
+					heap.Push(queue, distItem{w, d})
+				case math.Abs(d-dist[w]) <= 1e-12:
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+		for i := len(order) - 1; i >= 0; i-- {
+			w := order[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				cb[w] += delta[w]
+			}
+		}
+	}
+	if n > 2 {
+		// Each pair was counted from both ends.
+		norm := float64((n - 1) * (n - 2))
+		for i := range cb {
+			cb[i] /= norm
+		}
+	}
+	return cb
+}
+
+type distItem struct {
+	node int
+	dist float64
+}
+
+// distQueue is a min-heap of tentative distances for Dijkstra. A node may
+// be queued more than once; only its first pop counts.
+type distQueue []distItem
+
+func (q distQueue) Len() int           { return len(q) }
+func (q distQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q distQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *distQueue) Push(x any)        { *q = append(*q, x.(distItem)) }
+func (q *distQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// eigenvectorCentrality uses power iteration on the weighted adjacency
+// matrix, shifted by the identity so bipartite parts converge too. The
+// largest score is 1.
+func eigenvectorCentrality(adj []map[int]float64) []float64 {
+	n := len(adj)
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 1
+	}
+	for iter := 0; iter < 200 && n > 0; iter++ {
+		next := make([]float64, n)
+		var top float64
+		for i := range adj {
+			next[i] = x[i]
+			for j, w := range adj[i] {
+				next[i] += w * x[j]
+			}
+			top = max(top, next[i])
+		}
+		var change float64
+		for i := range next {
+			next[i] /= top
+			change += math.Abs(next[i] - x[i])
+		}
+		x = next
+		if change < 1e-9 {
+			break
+		}
+	}
+	for i := range adj {
+		if len(adj[i]) == 0 {
+			x[i] = 0
+		}
+	}
+	return x
+}
+
+// louvain assigns each node a community by greedy modularity optimization,
+// moving nodes in index order and then merging communities into single
+// nodes until nothing improves. Isolated domains stay on their own.
+func louvain(adj []map[int]float64) []int {
+	community := make([]int, len(adj))
+	for i := range community {
+		community[i] = i
+	}
+	graph := adj
+	for {
+		level, moved := louvainLevel(graph)
+		if !moved {
+			return renumber(community)
+		}
+		level = renumber(level)
+		for i := range community {
+			community[i] = level[community[i]]
+		}
+		size := 0
+		for _, c := range level {
+			size = max(size, c+1)
+		}
+		merged := make([]map[int]float64, size)
+		for i := range merged {
+			merged[i] = map[int]float64{}
+		}
+		for i := range graph {
+			for j, w := range graph[i] {
+				merged[level[i]][level[j]] += w
+			}
+		}
+		graph = merged
+	}
+}
+
+// louvainLevel runs the local moving phase. A merged node's self loop
+// already holds its internal weight in both directions, so row sums are
+// degrees at every level.
+func louvainLevel(adj []map[int]float64) ([]int, bool) {
+	n := len(adj)
+	k := make([]float64, n)
+	var m2 float64
+	for i := range adj {
+		for _, w := range adj[i] {
+			k[i] += w
+		}
+		m2 += k[i]
+	}
+	community := make([]int, n)
+	total := make([]float64, n)
+	for i := range community {
+		community[i] = i
+		total[i] = k[i]
+	}
+	if m2 == 0 {
+		return community, false
+	}
+	moved := false
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n; i++ {
+			links := map[int]float64{}
+			for j, w := range adj[i] {
+				if j != i {
+					links[community[j]] += w
+				}
+			}
+			own := community[i]
+			total[own] -= k[i]
+			best, bestGain := own, links[own]-total[own]*k[i]/m2
+			var candidates []int
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			for _, c := range candidates {
+				if gain := links[c] - total[c]*k[i]/m2; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			total[best] += k[i]
+			if best != own {
+				community[i] = best
+				improved, moved = true, true
+			}
+		}
+	}
+	return community, moved
+}
+
+func renumber(community []int) []int {
+	ids := map[int]int{}
+	out := make([]int, len(community))
+	for i, c := range community {
+		if _, ok := ids[c]; !ok {
+			ids[c] = len(ids)
+		}
+		out[i] = ids[c]
+	}
+	return out
+}
+
+// modularity is Newman's Q of a partition, from -0.5 to 1.
+func modularity(adj []map[int]float64, community []int) float64 {
+	var m2 float64
+	k := make([]float64, len(adj))
+	for i := range adj {
+		for _, w := range adj[i] {
+			k[i] += w
+		}
+		m2 += k[i]
+	}
+	if m2 == 0 {
+		return 0
+	}
+	inside := map[int]float64{}
+	total := map[int]float64{}
+	for i := range adj {
+		total[community[i]] += k[i]
+		for j, w := range adj[i] {
+			if community[j] == community[i] {
+				inside[community[i]] += w
+			}
+		}
+	}
+	var q float64
+	for c, t := range total {
+		q += inside[c]/m2 - (t/m2)*(t/m2)
+	}
+	return q
+}
+
+// WriteGraphML writes the network for Gephi, Cytoscape or yEd.
+func (g CoCitationGraph) WriteGraphML(w io.Writer) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   []data `xml:"data"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	type graphML struct {
+		XMLName xml.Name `xml:"graphml"`
+		NS      string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			ID          string `xml:"id,attr"`
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	doc := graphML{NS: "http://graphml.graphdrawing.org/xmlns", Keys: []key{
+		{"overviews", "node", "overviews", "int"},
+		{"strength", "node", "strength", "int"},
+		{"betweenness", "node", "betweenness", "double"},
+		{"eigenvector", "node", "eigenvector", "double"},
+		{"community", "node", "community", "int"},
+		{"weight", "edge", "weight", "int"},
+	}}
+	doc.Graph.ID, doc.Graph.EdgeDefault = "cocitation", "undirected"
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node{ID: n.Domain, Data: []data{
+			{"overviews", strconv.Itoa(n.Overviews)},
+			{"strength", strconv.Itoa(n.Strength)},
+			{"betweenness", f(n.Betweenness)},
+			{"eigenvector", f(n.Eigenvector)},
+			{"community", strconv.Itoa(n.Community)},
+		}})
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, edge{Source: e.Source, Target: e.Target, Data: []data{{"weight", strconv.Itoa(e.Weight)}}})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteDOT writes the network for Graphviz.
+func (g CoCitationGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("graph cocitation {\n\tnode [shape=ellipse];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "\t%s [overviews=%d, community=%d, eigenvector=%.4f, betweenness=%.4f];\n",
+			strconv.Quote(n.Domain), n.Overviews, n.Community, n.Eigenvector, n.Betweenness)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%s -- %s [weight=%d, penwidth=%.1f];\n", strconv.Quote(e.Source), strconv.Quote(e.Target), e.Weight, math.Min(1+math.Log2(float64(e.Weight)), 8))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func coCitationFilter(r *http.Request) CoCitationFilter {
+	q := r.URL.Query()
+	f := CoCitationFilter{Topic: q.Get("topic"), Locale: q.Get("locale"), All: q.Get("all") == "1"}
+	f.MinWeight, _ = strconv.Atoi(q.Get("min"))
+	return f
+}
+
+// Query encodes the filter as URL parameters for the export links.
+func (f CoCitationFilter) Query() template.URL {
+	q := url.Values{}
+	if f.Topic != "" {
+		q.Set("topic", f.Topic)
+	}
+	if f.Locale != "" {
+		q.Set("locale", f.Locale)
+	}
+	if f.All {
+		q.Set("all", "1")
+	}
+	if f.MinWeight > 1 {
+		q.Set("min", strconv.Itoa(f.MinWeight))
+	}
+	return template.URL(q.Encode())
+}
+
+func storedCoCitationGraph(r *http.Request) CoCitationGraph {
+	f := coCitationFilter(r)
+	return BuildCoCitationGraph(coCitationOverviews(f), f)
+}
+
+var coCitationTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Co-citation network</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 900px; }
+		table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+		th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+		#graph { width: 100%; height: 600px; border: 1px solid #ddd; border-radius: 8px; cursor: grab; }
+		#graph line { stroke: #999; stroke-opacity: 0.5; }
+		#graph text { font-size: 11px; pointer-events: none; }
+		#graph .dim { opacity: 0.15; }
+	</style>
+</head>
+<body>
+	<h1>🕸️ Co-citation network</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="GET">
+		<select name="topic">
+			<option value="">all topics</option>
+			{{range .Topics}}<option {{if eq . $.Graph.Filter.Topic}}selected{{end}}>{{.}}</option>{{end}}
+		</select>
+		<select name="locale">
+			<option value="">all locales</option>
+			{{range .Locales}}<option {{if eq . $.Graph.Filter.Locale}}selected{{end}}>{{.}}</option>{{end}}
+		</select>
+		<label>min. weight <input type="number" name="min" min="1" value="{{.Graph.Filter.MinWeight}}" style="width: 4em" /></label>
+		<label><input type="checkbox" name="all" value="1" {{if .Graph.Filter.All}}checked{{end}} /> every snapshot</label>
+		<button type="submit">Filter</button>
+		· <a href="/export/cocitation.graphml?{{.Graph.Filter.Query}}">GraphML</a> · <a href="/export/cocitation.dot?{{.Graph.Filter.Query}}">DOT</a>
+	</form>
+	{{with .Graph}}
+		<p>{{.Overviews}} overviews, {{len .Nodes}} domains, {{len .Edges}} edges, {{len .Communities}} communities (modularity {{printf "%.2f" .Modularity}}).{{if .Dropped}} {{.Dropped}} rarely cited domains left out.{{end}}</p>
+		<svg id="graph"></svg>
+		<p><small>Drag to move domains, click one to highlight its neighbours.</small></p>
+		<h2>Most central domains</h2>
+		<table>
+			<tr><th>Domain</th><th>Overviews</th><th>Degree</th><th>Strength</th><th>Eigenvector</th><th>Betweenness</th><th>Community</th></tr>
+			{{range $i, $n := .Nodes}}{{if lt $i 25}}
+			<tr><td>{{$n.Domain}}</td><td>{{$n.Overviews}}</td><td>{{$n.Degree}}</td><td>{{$n.Strength}}</td><td>{{printf "%.3f" $n.Eigenvector}}</td><td>{{printf "%.3f" $n.Betweenness}}</td><td>{{$n.Community}}</td></tr>
+			{{end}}{{end}}
+		</table>
+		<h2>Communities</h2>
+		<table>
+			<tr><th>#</th><th>Domains</th><th>Internal weight</th></tr>
+			{{range .Communities}}{{if gt (len .Domains) 1}}
+			<tr><td>{{.ID}}</td><td>{{range $i, $d := .Domains}}{{if $i}}, {{end}}{{$d}}{{end}}</td><td>{{.Weight}}</td></tr>
+			{{end}}{{end}}
+		</table>
+	{{end}}
+	<script>
+		const data = {{.Graph}};
+		const svg = document.getElementById("graph");
+		const ns = "http://www.w3.org/2000/svg";
+		const width = svg.clientWidth, height = svg.clientHeight;
+		const colors = ["#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"];
+		const nodes = (data.nodes || []).map((n, i) => ({...n,
+			x: width / 2 + Math.cos(i) * (50 + i * 3), y: height / 2 + Math.sin(i) * (50 + i * 3), vx: 0, vy: 0}));
+		const byDomain = Object.fromEntries(nodes.map(n => [n.domain, n]));
+		const edges = (data.edges || []).map(e => ({...e, s: byDomain[e.source], t: byDomain[e.target]}));
+		const maxWeight = Math.max(1, ...edges.map(e => e.weight));
+
+		for (const e of edges) {
+			e.el = document.createElementNS(ns, "line");
+			e.el.setAttribute("stroke-width", 1 + 4 * e.weight / maxWeight);
+			svg.appendChild(e.el);
+		}
+		for (const n of nodes) {
+			n.el = document.createElementNS(ns, "g");
+			const c = document.createElementNS(ns, "circle");
+			c.setAttribute("r", 4 + 3 * Math.sqrt(n.overviews));
+			c.setAttribute("fill", n.community <= colors.length ? colors[n.community - 1] : "#ccc");
+			const title = document.createElementNS(ns, "title");
+			title.textContent = n.domain + " · " + n.overviews + " overviews · community " + n.community;
+			c.appendChild(title);
+			const label = document.createElementNS(ns, "text");
+			label.setAttribute("dx", 8);
+			label.setAttribute("dy", 4);
+			label.textContent = n.domain;
+			n.el.append(c, label);
+			svg.appendChild(n.el);
+		}
+
+		// A small force layout: edges pull, every pair repels, all drift to the centre.
+		let heat = 1, dragged = null, selected = null;
+		function tick() {
+			for (const a of nodes) for (const b of nodes) {
+				if (a === b) continue;
+				const dx = a.x - b.x, dy = a.y - b.y, d2 = Math.max(dx * dx + dy * dy, 25);
+				a.vx += 800 * dx / d2 * heat; a.vy += 800 * dy / d2 * heat;
+			}
+			for (const e of edges) {
+				const dx = e.t.x - e.s.x, dy = e.t.y - e.s.y, d = Math.max(Math.hypot(dx, dy), 1);
+				const f = (d - 80) * 0.01 * Math.min(e.weight, 5) * heat;
+				e.s.vx += f * dx / d; e.s.vy += f * dy / d; e.t.vx -= f * dx / d; e.t.vy -= f * dy / d;
+			}
+			for (const n of nodes) {
+				n.vx += (width / 2 - n.x) * 0.005 * heat; n.vy += (height / 2 - n.y) * 0.005 * heat;
+				if (n !== dragged) { n.x += n.vx; n.y += n.vy; }
+				n.vx *= 0.5; n.vy *= 0.5;
+				n.x = Math.min(width - 10, Math.max(10, n.x)); n.y = Math.min(height - 10, Math.max(10, n.y));
+				n.el.setAttribute("transform", "translate(" + n.x + "," + n.y + ")");
+			}
+			for (const e of edges) {
+				e.el.setAttribute("x1", e.s.x); e.el.setAttribute("y1", e.s.y);
+				e.el.setAttribute("x2", e.t.x); e.el.setAttribute("y2", e.t.y);
+			}
+			heat = Math.max(heat * 0.99, dragged ? 0.3 : 0);
+			if (heat > 0.01) requestAnimationFrame(tick);
+		}
+
+		function highlight(n) {
+			selected = selected === n ? null : n;
+			const near = new Set(selected ? [selected] : nodes);
+			for (const e of edges) {
+				if (e.s === selected) near.add(e.t);
+				if (e.t === selected) near.add(e.s);
+				e.el.classList.toggle("dim", !!selected && e.s !== selected && e.t !== selected);
+			}
+			for (const m of nodes) m.el.classList.toggle("dim", !near.has(m));
+		}
+
+		function point(ev) {
+			const r = svg.getBoundingClientRect();
+			return [ev.clientX - r.left, ev.clientY - r.top];
+		}
+		let moved = false;
+		for (const n of nodes) {
+			n.el.addEventListener("pointerdown", ev => { dragged = n; moved = false; svg.setPointerCapture(ev.pointerId); });
+		}
+		svg.addEventListener("pointermove", ev => {
+			if (!dragged) return;
+			[dragged.x, dragged.y] = point(ev);
+			moved = true;
+			if (heat <= 0.01) { heat = 0.3; requestAnimationFrame(tick); }
+		});
+		svg.addEventListener("pointerup", () => {
+			if (dragged && !moved) highlight(dragged);
+			dragged = null;
+		});
+		tick();
+	</script>
+</body>
+</html>
+`
+
+var coCitationTpl = template.Must(template.New("cocitation").Funcs(funcMap).Parse(coCitationTmpl))
+
+func coCitationPage(w http.ResponseWriter, r *http.Request) {
+	locales := map[string]bool{}
+	for _, series := range snapshotSeries() {
+		locales[series[0].Locale.String()] = true
+	}
+	data := struct {
+		Graph   CoCitationGraph
+		Topics  []string
+		Locales []string
+	}{storedCoCitationGraph(r), topics(), nil}
+	for l := range locales {
+		data.Locales = append(data.Locales, l)
+	}
+	sort.Strings(data.Locales)
+	if err := coCitationTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiCoCitation(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, storedCoCitationGraph(r))
+}
+
+// apiBatchCoCitation builds the network from posted overviews instead of
+// the stored ones: a JSON array of overviews, or of snapshots as returned
+// by /api/v1/snapshots/{id}. Query parameters other than min are ignored.
+// Overviews with more than maxCoCitationReferences references, or more than
+// maxCoCitationDomains domains in all, are refused.
+func apiBatchCoCitation(w http.ResponseWriter, r *http.Request) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 32<<20)).Decode(&items); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "request body is larger than 32 MB"})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expected a JSON array of overviews or snapshots"})
+		return
+	}
+	var overviews []*AIOverview
+	domains := map[string]bool{}
+	for i, raw := range items {
+		var item struct {
+			Overview *AIOverview `json:"overview"`
+			AIOverview
+		}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("item %d: %v", i, err)})
+			return
+		}
+		ai := item.Overview
+		if ai == nil && len(item.References) > 0 {
+			ai = &item.AIOverview
+		}
+		if ai == nil {
+			continue
+		}
+		if len(ai.References) > maxCoCitationReferences {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": fmt.Sprintf("item %d has more than %d references", i, maxCoCitationReferences)})
+			return
+		}
+		for _, d := range overviewDomains(ai) {
+			domains[d] = true
+		}
+		if len(domains) > maxCoCitationDomains {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": fmt.Sprintf("more than %d distinct domains", maxCoCitationDomains)})
+			return
+		}
+		overviews = append(overviews, ai)
+	}
+	f := CoCitationFilter{}
+	f.MinWeight, _ = strconv.Atoi(r.URL.Query().Get("min"))
+	writeJSON(w, http.StatusOK, BuildCoCitationGraph(overviews, f))
+}
+
+func exportCoCitationGraphML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/graphml+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="cocitation.graphml"`)
+	storedCoCitationGraph(r).WriteGraphML(w)
+}
+
+func exportCoCitationDOT(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/vnd.graphviz")
+	w.Header().Set("Content-Disposition", `attachment; filename="cocitation.dot"`)
+	storedCoCitationGraph(r).WriteDOT(w)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGraph builds an undirected adjacency list from weighted edges.
+func testGraph(n int, edges ...[3]int) []map[int]float64 {
+	adj := make([]map[int]float64, n)
+	for i := range adj {
+		adj[i] = map[int]float64{}
+	}
+	for _, e := range edges {
+		adj[e[0]][e[1]], adj[e[1]][e[0]] = float64(e[2]), float64(e[2])
+	}
+	return adj
+}
+
+// Two triangles joined by the bridge 2-3.
+var twoTriangles = [][3]int{{0, 1, 1}, {1, 2, 1}, {0, 2, 1}, {2, 3, 1}, {3, 4, 1}, {4, 5, 1}, {3, 5, 1}}
+
+func TestWeightedBetweenness(t *testing.T) {
+	tests := []struct {
+		name string
+		adj  []map[int]float64
+		want []float64
+	}{
+		{"empty", testGraph(0), []float64{}},
+		{"path", testGraph(3, [3]int{0, 1, 1}, [3]int{1, 2, 1}), []float64{0, 1, 0}},
+		{"star", testGraph(4, [3]int{0, 1, 1}, [3]int{0, 2, 1}, [3]int{0, 3, 1}), []float64{1, 0, 0, 0}},
+		{"two triangles", testGraph(6, twoTriangles...), []float64{0, 0, 0.6, 0.6, 0, 0}},
+		// 0-2-1 has distance 1/4 + 1/4, shorter than the direct 0-1 edge.
+		{"heavy detour", testGraph(3, [3]int{0, 1, 1}, [3]int{0, 2, 4}, [3]int{1, 2, 4}), []float64{0, 0, 1}},
+		{"isolated node", testGraph(4, [3]int{0, 1, 1}, [3]int{1, 2, 1}), []float64{0, 1.0 / 3, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weightedBetweenness(tt.adj)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLouvain(t *testing.T) {
+	tests := []struct {
+		name       string
+		adj        []map[int]float64
+		want       []int
+		modularity float64
+	}{
+		{"empty", testGraph(0), []int{}, 0},
+		{"single edge", testGraph(2, [3]int{0, 1, 1}), []int{0, 0}, 0},
+		{"two triangles", testGraph(6, twoTriangles...), []int{0, 0, 0, 1, 1, 1}, 6.0/7 - 0.5},
+		{"isolated node stays alone", testGraph(7, twoTriangles...), []int{0, 0, 0, 1, 1, 1, 2}, 6.0/7 - 0.5},
+		// A heavy bridge pulls 2 and 3 together.
+		{"heavy bridge", testGraph(4, [3]int{0, 1, 1}, [3]int{1, 2, 1}, [3]int{2, 3, 10}), []int{0, 0, 1, 1}, 13.0 / 96},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := louvain(tt.adj)
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("communities = %v, want %v", got, tt.want)
+			}
+			if q := modularity(tt.adj, got); math.Abs(q-tt.modularity) > 1e-9 {
+				t.Errorf("modularity = %v, want %v", q, tt.modularity)
+			}
+		})
+	}
+
+	adj := testGraph(6, twoTriangles...)
+	if one := modularity(adj, make([]int, 6)); one != 0 {
+		t.Errorf("modularity of a single community = %v, want 0", one)
+	}
+	if q := modularity(adj, louvain(adj)); q <= modularity(adj, []int{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("louvain did not improve on singletons: %v", q)
+	}
+}
+
+func TestBuildCoCitationGraph(t *testing.T) {
+	overview := func(links ...string) *AIOverview {
+		ai := &AIOverview{}
+		for _, l := range links {
+			ai.References = append(ai.References, Reference{Link: l})
+		}
+		return ai
+	}
+	overviews := []*AIOverview{
+		overview("https://www.a.com/1", "https://a.com/2", "https://b.com/", "https://c.com/"),
+		overview("https://a.com/", "https://b.com/x", "https://c.com/"),
+		overview("https://c.com/", "https://x.org/"),
+		overview("https://x.org/", "https://y.org/", "https://z.org/"),
+		overview("https://x.org/a", "https://y.org/", "https://z.org/"),
+		overview("https://lone.net/"),
+	}
+
+	g := BuildCoCitationGraph(overviews, CoCitationFilter{})
+	if g.Overviews != 6 || g.Filter.MinWeight != 1 || len(g.Nodes) != 7 || len(g.Edges) != 7 {
+		t.Fatalf("graph = %d overviews, min weight %d, %d nodes, %d edges", g.Overviews, g.Filter.MinWeight, len(g.Nodes), len(g.Edges))
+	}
+	nodes := map[string]CoCitationNode{}
+	for _, n := range g.Nodes {
+		nodes[n.Domain] = n
+	}
+	if a := nodes["a.com"]; a.Overviews != 2 || a.Degree != 2 || a.Strength != 4 {
+		t.Errorf("a.com = %+v, want cited twice with strength 4 (www. and duplicates folded)", a)
+	}
+	if c, x := nodes["c.com"], nodes["x.org"]; math.Abs(c.Betweenness-0.4) > 1e-9 || math.Abs(x.Betweenness-0.4) > 1e-9 {
+		t.Errorf("bridge betweenness = %v, %v, want 0.4", c.Betweenness, x.Betweenness)
+	}
+	if g.Edges[0].Weight != 2 || g.Edges[len(g.Edges)-1] != (CoCitationEdge{Source: "c.com", Target: "x.org", Weight: 1}) {
+		t.Errorf("edges not heaviest first: %+v", g.Edges)
+	}
+	wantCommunities := [][]string{{"c.com", "a.com", "b.com"}, {"x.org", "y.org", "z.org"}, {"lone.net"}}
+	if len(g.Communities) != len(wantCommunities) {
+		t.Fatalf("communities = %+v", g.Communities)
+	}
+	for i, c := range g.Communities {
+		if c.ID != i+1 || !reflect.DeepEqual(c.Domains, wantCommunities[i]) {
+			t.Errorf("community %d = %+v, want %v", i+1, c, wantCommunities[i])
+		}
+		for _, d := range c.Domains {
+			if nodes[d].Community != c.ID {
+				t.Errorf("%s has community %d, want %d", d, nodes[d].Community, c.ID)
+			}
+		}
+	}
+	if math.Abs(g.Modularity-(12.0/13-0.5)) > 1e-9 {
+		t.Errorf("modularity = %v", g.Modularity)
+	}
+
+	g = BuildCoCitationGraph(overviews, CoCitationFilter{MinWeight: 2})
+	for _, e := range g.Edges {
+		if e.Weight < 2 {
+			t.Errorf("edge %+v below the minimum weight", e)
+		}
+	}
+	if len(g.Edges) != 6 || len(g.Nodes) != 7 {
+		t.Errorf("min weight 2 kept %d edges, want 6", len(g.Edges))
+	}
+}
+
+func TestBatchCoCitationLimits(t *testing.T) {
+	var refs []string
+	for i := 0; i <= maxCoCitationReferences; i++ {
+		refs = append(refs, fmt.Sprintf(`{"link":"https://d%d.example/"}`, i))
+	}
+	body := `[{"references":[` + strings.Join(refs, ",") + `]}]`
+	rec := httptest.NewRecorder()
+	apiBatchCoCitation(rec, httptest.NewRequest(http.MethodPost, "/api/v1/cocitation/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+}
+
+func TestCoCitationFilterQuery(t *testing.T) {
+	f := CoCitationFilter{Topic: `x"><script>`, Locale: "id-id", MinWeight: 2}
+	if got := string(f.Query()); got != "locale=id-id&min=2&topic=x%22%3E%3Cscript%3E" {
+		t.Errorf("query = %s", got)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AccountStatus is what SerpAPI's Account API reports for one key, next to
+// our own count of the calls made with it
+type AccountStatus struct {
+	KeyID            string    `json:"key_id"` // fingerprint, never the key itself
+	Plan             string    `json:"plan"`
+	SearchesPerMonth int       `json:"searches_per_month"`
+	SearchesLeft     int       `json:"searches_left"` // plan and extra credits
+	ThisMonthUsage   int       `json:"this_month_usage"`
+	LocalUsage       int       `json:"local_usage"` // GetJSON calls counted here this month
+	CheckedAt        time.Time `json:"checked_at"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// Drift is how many more searches SerpAPI billed than we counted.
+func (a AccountStatus) Drift() int {
+	return a.ThisMonthUsage - a.LocalUsage
+}
+
+// serpAPIKeys lists the configured keys, api_key=key1,key2.
+func serpAPIKeys() []string {
+	return splitAliases(os.Getenv("api_key"))
+}
+
+// redactKeys hides configured keys in messages, e.g. errors quoting the
+// request URL.
+func redactKeys(msg string) string {
+	for _, k := range serpAPIKeys() {
+		msg = strings.ReplaceAll(msg, k, "***")
+	}
+	return msg
+}
+
+func keyID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// pickAPIKey returns the key with the most searches left according to the
+// last sync, or the first key before any sync.
+func pickAPIKey() string {
+	keys := serpAPIKeys()
+	if len(keys) == 0 {
+		return ""
+	}
+	status := map[string]AccountStatus{}
+	for _, a := range store.Accounts() {
+		status[a.KeyID] = a
+	}
+	best, bestLeft := keys[0], -1
+	for _, k := range keys {
+		a, ok := status[keyID(k)]
+		if !ok || a.Error != "" {
+			continue
+		}
+		if a.SearchesLeft > bestLeft {
+			best, bestLeft = k, a.SearchesLeft
+		}
+	}
+	return best
+}
+
+// serpCallCount counts SerpAPI calls since startup, across all keys.
+var serpCallCount atomic.Int64
+
+// countSerpCall records one billed call made with key.
+func countSerpCall(key string) {
+	serpCallCount.Add(1)
+	if err := store.CountSerpCall(keyID(key)); err != nil {
+		log.Println("❌ failed to count SerpAPI call:", err)
+	}
+}
+
+func accountURL() string {
+	if u := os.Getenv("serpapi_account_url"); u != "" {
+		return u
+	}
+	return "https://serpapi.com/account.json"
+}
+
+// fetchAccount queries the Account API, which is free and not billed.
+func fetchAccount(key string) (AccountStatus, error) {
+	a := AccountStatus{KeyID: keyID(key), CheckedAt: time.Now()}
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Get(accountURL() + "?api_key=" + url.QueryEscape(key))
+	if err != nil {
+		// A *url.Error quotes the URL, and with it the key.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = fmt.Errorf("account API request failed: %w", uerr.Err)
+		}
+		return a, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		PlanName          string `json:"plan_name"`
+		SearchesPerMonth  int    `json:"searches_per_month"`
+		TotalSearchesLeft int    `json:"total_searches_left"`
+		ThisMonthUsage    int    `json:"this_month_usage"`
+		Error             string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return a, err
+	}
+	if body.Error != "" {
+		return a, errors.New(body.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return a, fmt.Errorf("account API returned %s", resp.Status)
+	}
+	a.Plan = body.PlanName
+	a.SearchesPerMonth = body.SearchesPerMonth
+	a.SearchesLeft = body.TotalSearchesLeft
+	a.ThisMonthUsage = body.ThisMonthUsage
+	return a, nil
+}
+
+// minCredits is the threshold below which scheduled work pauses
+func minCredits() int {
+	return currentSettings().CreditsMin
+}
+
+var creditsLow struct {
+	sync.Mutex
+	low bool
+}
+
+// syncCredits polls every key, reconciles against the local counters and
+// raises an alert when credits first drop below the threshold.
+func syncCredits() []AccountStatus {
+	month := time.Now().UTC().Format("2006-01")
+	var out []AccountStatus
+	total := 0
+	for _, key := range serpAPIKeys() {
+		a, err := fetchAccount(key)
+		if err != nil {
+			a.Error = redactKeys(err.Error())
+			log.Println("❌ failed to sync SerpAPI account:", a.Error)
+		}
+		a.LocalUsage = store.SerpCalls(a.KeyID, month)
+		if a.Error == "" {
+			total += a.SearchesLeft
+			if d := a.Drift(); d != 0 {
+				log.Printf("💳 key %s: SerpAPI billed %d searches this month, counted %d locally (drift %+d)", a.KeyID, a.ThisMonthUsage, a.LocalUsage, d)
+			}
+		}
+		if err := store.SetAccount(a); err != nil {
+			log.Println("❌ failed to store account status:", err)
+		}
+		out = append(out, a)
+	}
+
+	synced := false
+	for _, a := range out {
+		synced = synced || a.Error == ""
+	}
+	if !synced {
+		return out
+	}
+	low := total < minCredits()
+	creditsLow.Lock()
+	crossed := low && !creditsLow.low
+	creditsLow.low = low
+	creditsLow.Unlock()
+	if crossed {
+		raiseAlert(Alert{Kind: AlertCreditsLow, Message: fmt.Sprintf("Only %d SerpAPI searches left (threshold %d), scheduled work is paused", total, minCredits())})
+	}
+	return out
+}
+
+var errCreditsLow = errors.New("SerpAPI credits below threshold")
+
+// creditGate is checked by scheduled work before it spends credits. It
+// returns errCreditsLow while the last sync was below the threshold.
+func creditGate() error {
+	creditsLow.Lock()
+	defer creditsLow.Unlock()
+	if creditsLow.low {
+		return errCreditsLow
+	}
+	return nil
+}
+
+func creditSyncInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("credits_sync_interval")); err == nil && d > 0 {
+		return d
+	}
+	return 15 * time.Minute
+}
+
+// syncCreditsLoop polls the Account API until the process exits.
+func syncCreditsLoop() {
+	for {
+		syncCredits()
+		time.Sleep(creditSyncInterval())
+	}
+}
+
+var creditsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>SerpAPI Credits</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.25rem 0.5rem; border-bottom: 1px solid #ddd; }
+		.flag { color: #cf222e; }
+	</style>
+</head>
+<body>
+	<h1>💳 SerpAPI Credits</h1>
+	<p><a href="/">← Search</a></p>
+	<div class="text-block">
+		<p>Threshold: {{.Min}} searches left. {{if .Paused}}<strong class="flag">Scheduled work is paused.</strong>{{else}}Scheduled work is running.{{end}}</p>
+		<form method="POST"><button type="submit">Sync now</button></form>
+	</div>
+	<table>
+		<tr><th>Key</th><th>Plan</th><th>Left</th><th>Used this month</th><th>Counted here</th><th>Drift</th><th>Checked</th></tr>
+		{{range .Accounts}}
+			<tr>
+				<td><code>{{.KeyID}}</code></td>
+				{{if .Error}}
+					<td colspan="5" class="flag">{{.Error}}</td>
+				{{else}}
+					<td>{{.Plan}}</td>
+					<td>{{.SearchesLeft}} / {{.SearchesPerMonth}}</td>
+					<td>{{.ThisMonthUsage}}</td>
+					<td>{{.LocalUsage}}</td>
+					<td {{if .Drift}}class="flag"{{end}}>{{.Drift}}</td>
+				{{end}}
+				<td>{{.CheckedAt.Format "2006-01-02 15:04"}}</td>
+			</tr>
+		{{else}}
+			<tr><td colspan="7"><em>Not synced yet.</em></td></tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var creditsTpl = template.Must(template.New("credits").Parse(creditsTmpl))
+
+func creditsPage(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Accounts []AccountStatus
+		Min      int
+		Paused   bool
+	}{store.Accounts(), minCredits(), creditGate() != nil}
+	if err := creditsTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func syncCreditsNow(w http.ResponseWriter, r *http.Request) {
+	syncCredits()
+	http.Redirect(w, r, "/admin/credits", http.StatusSeeOther)
+}
+
+func apiCredits(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"accounts":    store.Accounts(),
+		"min_credits": minCredits(),
+		"paused":      creditGate() != nil,
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// OverviewDiff lists what changed between two overviews
+type OverviewDiff struct {
+	AddedReferences   []Reference `json:"added_references"`
+	RemovedReferences []Reference `json:"removed_references"`
+	AddedPassages     []string    `json:"added_passages"`
+	RemovedPassages   []string    `json:"removed_passages"`
+	TextChange        float64     `json:"text_change"` // word edit distance, 0..1
+}
+
+// Empty reports whether nothing changed.
+func (d OverviewDiff) Empty() bool {
+	return len(d.AddedReferences) == 0 && len(d.RemovedReferences) == 0 &&
+		len(d.AddedPassages) == 0 && len(d.RemovedPassages) == 0
+}
+
+// DiffOverviews compares two overviews; either may be nil.
+func DiffOverviews(before, after *AIOverview) OverviewDiff {
+	if before == nil {
+		before = &AIOverview{}
+	}
+	if after == nil {
+		after = &AIOverview{}
+	}
+	var d OverviewDiff
+
+	beforeRefs := map[string]bool{}
+	for _, ref := range before.References {
+		beforeRefs[ref.Link] = true
+	}
+	afterRefs := map[string]bool{}
+	for _, ref := range after.References {
+		afterRefs[ref.Link] = true
+		if !beforeRefs[ref.Link] {
+			d.AddedReferences = append(d.AddedReferences, ref)
+		}
+	}
+	for _, ref := range before.References {
+		if !afterRefs[ref.Link] {
+			d.RemovedReferences = append(d.RemovedReferences, ref)
+		}
+	}
+
+	beforeText := passageSet(before)
+	afterText := passageSet(after)
+	for _, p := range after.Passages() {
+		if !beforeText[p.Text] {
+			d.AddedPassages = append(d.AddedPassages, p.Text)
+		}
+	}
+	for _, p := range before.Passages() {
+		if !afterText[p.Text] {
+			d.RemovedPassages = append(d.RemovedPassages, p.Text)
+		}
+	}
+	d.TextChange = wordEditDistance(NormalizeQuery(before.Text(), normalizeOptions), NormalizeQuery(after.Text(), normalizeOptions))
+	return d
+}
+
+func passageSet(ai *AIOverview) map[string]bool {
+	set := map[string]bool{}
+	for _, p := range ai.Passages() {
+		set[p.Text] = true
+	}
+	return set
+}
+
+// overviewFingerprint identifies the content of an overview: its normalized
+// text and the sorted set of cited links. Nil and empty overviews share one
+// fingerprint.
+func overviewFingerprint(ai *AIOverview) string {
+	if ai == nil || ai.IsEmpty() {
+		return ""
+	}
+	links := make([]string, 0, len(ai.References))
+	for _, ref := range ai.References {
+		links = append(links, ref.Link)
+	}
+	sort.Strings(links)
+	sum := sha1.Sum([]byte(NormalizeQuery(ai.Text(), normalizeOptions) + "\x00" + strings.Join(links, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
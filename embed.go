@@ -0,0 +1,418 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmbedToken grants an embedding site access to the overviews of some
+// keywords. It is signed with the server's embed secret.
+type EmbedToken struct {
+	Keywords []string `json:"kw"`  // canonical keyword IDs, "*" for any
+	Origins  []string `json:"org"` // allowed embedding origins, e.g. https://intranet.example.com
+	Expires  int64    `json:"exp"` // unix seconds, 0 for no expiry
+}
+
+func (t EmbedToken) allowsKeyword(id string) bool {
+	for _, k := range t.Keywords {
+		if k == "*" || k == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (t EmbedToken) allowsOrigin(origin string) bool {
+	for _, o := range t.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+var embedSecret []byte
+
+// loadEmbedSecret reads embed_secret from the environment, or from
+// data/embed_secret, generating the file on first use.
+func loadEmbedSecret() ([]byte, error) {
+	if s := os.Getenv("embed_secret"); s != "" {
+		return []byte(s), nil
+	}
+	path := filepath.Join(dataDir(), "embed_secret")
+	if raw, err := os.ReadFile(path); err == nil {
+		return hex.DecodeString(strings.TrimSpace(string(raw)))
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return nil, err
+	}
+	return secret, os.WriteFile(path, []byte(hex.EncodeToString(secret)), 0o600)
+}
+
+// SignEmbedToken encodes t as base64url(JSON) "." base64url(HMAC-SHA256).
+func SignEmbedToken(t EmbedToken) (string, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, embedSecret)
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+var errInvalidEmbedToken = errors.New("invalid embed token")
+
+// VerifyEmbedToken checks the signature and expiry of token.
+func VerifyEmbedToken(token string) (EmbedToken, error) {
+	enc := base64.RawURLEncoding
+	payloadPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return EmbedToken{}, errInvalidEmbedToken
+	}
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return EmbedToken{}, errInvalidEmbedToken
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil {
+		return EmbedToken{}, errInvalidEmbedToken
+	}
+	mac := hmac.New(sha256.New, embedSecret)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return EmbedToken{}, errInvalidEmbedToken
+	}
+	var t EmbedToken
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return EmbedToken{}, errInvalidEmbedToken
+	}
+	if t.Expires != 0 && time.Now().Unix() > t.Expires {
+		return EmbedToken{}, errors.New("embed token expired")
+	}
+	return t, nil
+}
+
+// storedOverview returns the cached or latest stored overview of a keyword
+// without ever calling SerpAPI.
+func storedOverview(keywordID string, loc Locale) (Snapshot, bool) {
+	if snap, ok := cache.Get(keywordID + "|" + loc.String()); ok {
+		return snap, true
+	}
+	series := store.Snapshots(func(s Snapshot) bool {
+		return s.KeywordID == keywordID && s.Locale == loc && s.Overview != nil && !s.Overview.IsEmpty()
+	})
+	if len(series) == 0 {
+		return Snapshot{}, false
+	}
+	return series[len(series)-1], true
+}
+
+// EmbedStyle is the configurable look of the widget
+type EmbedStyle struct {
+	Theme    string // light or dark
+	Accent   string // CSS hex color
+	FontSize int    // px
+	MaxRefs  int
+}
+
+var hexColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{3}([0-9a-fA-F]{3})?$`)
+
+func embedStyleFromRequest(r *http.Request) EmbedStyle {
+	q := r.URL.Query()
+	s := EmbedStyle{Theme: "light", Accent: "#1a73e8", FontSize: 14, MaxRefs: 3}
+	if q.Get("theme") == "dark" {
+		s.Theme = "dark"
+	}
+	if a := q.Get("accent"); hexColorRe.MatchString(a) {
+		s.Accent = a
+	}
+	if n, err := strconv.Atoi(q.Get("font_size")); err == nil && n >= 10 && n <= 24 {
+		s.FontSize = n
+	}
+	if n, err := strconv.Atoi(q.Get("max_refs")); err == nil && n >= 0 && n <= 20 {
+		s.MaxRefs = n
+	}
+	return s
+}
+
+var embedTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8" />
+	<style>
+		body { font-family: sans-serif; margin: 0; padding: 0.75rem; font-size: {{.Style.FontSize}}px;
+			{{if eq .Style.Theme "dark"}}background: #1f2328; color: #e6edf3;{{else}}background: #fff; color: #1f2328;{{end}} }
+		h3 { margin: 0 0 0.5rem; font-size: 1em; color: {{.Style.Accent | css}}; }
+		p, li { margin: 0.25rem 0; line-height: 1.4; }
+		a { color: {{.Style.Accent | css}}; }
+		.refs { margin-top: 0.5rem; font-size: 0.85em; }
+		.meta { opacity: 0.6; font-size: 0.8em; }
+	</style>
+</head>
+<body>
+	{{if .Snapshot.Overview}}
+		<h3>AI Overview · {{.Snapshot.Keyword}}</h3>
+		{{range .Snapshot.Overview.TextBlocks}}
+			{{if .Snippet}}<p>{{.Snippet}}</p>{{end}}
+			{{if .List}}<ul>{{range .List}}<li><strong>{{.Title}}</strong> {{.Snippet}}</li>{{end}}</ul>{{end}}
+		{{end}}
+		{{if .Refs}}
+			<ol class="refs">{{range .Refs}}<li><a href="{{.Link}}" target="_blank" rel="noopener">{{.Title}}</a></li>{{end}}</ol>
+		{{end}}
+		<p class="meta">As of {{.Snapshot.FetchedAt.Format "2006-01-02 15:04"}}</p>
+	{{else}}
+		<p class="meta">No AI Overview stored for this keyword yet.</p>
+	{{end}}
+</body>
+</html>
+`
+
+var embedTpl = template.Must(template.New("embed").Funcs(template.FuncMap{
+	"css": func(s string) template.CSS { return template.CSS(s) }, // only validated hex colors reach this
+}).Parse(embedTmpl))
+
+// embedOrigin is the origin of the page embedding the widget, as reported
+// by the browser in the Origin or Referer header. It is an early rejection
+// only: headers can be left out, and frame-ancestors is what browsers
+// enforce.
+func embedOrigin(r *http.Request) string {
+	if o := r.Header.Get("Origin"); o != "" && o != "null" {
+		return o
+	}
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host != "" {
+		return ref.Scheme + "://" + ref.Host
+	}
+	return ""
+}
+
+// embedPage serves the iframe. It only ever reads the cache and the store.
+func embedPage(w http.ResponseWriter, r *http.Request) {
+	if !currentSettings().Embeds {
+		http.Error(w, "embeds are disabled", http.StatusNotFound)
+		return
+	}
+	token, err := VerifyEmbedToken(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	origin := embedOrigin(r)
+	if origin != "" && !token.allowsOrigin(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	_, id := CanonicalKeyword(r.URL.Query().Get("q"))
+	if k := r.URL.Query().Get("keyword_id"); k != "" {
+		id = k
+	}
+	if !token.allowsKeyword(id) {
+		http.Error(w, "keyword not allowed", http.StatusForbidden)
+		return
+	}
+
+	// browsers enforce the origin list through frame-ancestors
+	w.Header().Set("Content-Security-Policy", "frame-ancestors "+strings.Join(token.Origins, " "))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+
+	style := embedStyleFromRequest(r)
+	snap, _ := storedOverview(id, localeFromRequest(r))
+	data := struct {
+		Snapshot Snapshot
+		Refs     []Reference
+		Style    EmbedStyle
+	}{Snapshot: snap, Style: style}
+	if snap.Overview != nil {
+		data.Refs = snap.Overview.References
+		if len(data.Refs) > style.MaxRefs {
+			data.Refs = data.Refs[:style.MaxRefs]
+		}
+	}
+	if err := embedTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+// embedLoader is the script sites include to place widgets:
+//
+//	<div data-aio-keyword="harga emas" data-aio-token="..."></div>
+//	<script src="https://host/embed.js" async></script>
+const embedLoader = `(function () {
+	var script = document.currentScript;
+	var base = new URL(script.src).origin;
+	var opts = ["hl", "gl", "theme", "accent", "font_size", "max_refs"];
+	document.querySelectorAll("[data-aio-token]").forEach(function (el) {
+		var params = new URLSearchParams({ token: el.dataset.aioToken, q: el.dataset.aioKeyword || "" });
+		opts.forEach(function (o) {
+			var v = el.getAttribute("data-aio-" + o.replace("_", "-"));
+			if (v) params.set(o, v);
+		});
+		var frame = document.createElement("iframe");
+		frame.src = base + "/embed?" + params.toString();
+		frame.referrerPolicy = "origin";
+		frame.style.border = "0";
+		frame.style.width = "100%";
+		frame.style.height = el.dataset.aioHeight || "320px";
+		frame.loading = "lazy";
+		frame.title = "AI Overview";
+		el.appendChild(frame);
+	});
+})();
+`
+
+func embedScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write([]byte(embedLoader))
+}
+
+// embedTokenRequest is the body of POST /api/v1/embed/tokens
+type embedTokenRequest struct {
+	Keywords []string      `json:"keywords"` // raw queries, canonicalized here; "*" for any
+	Origins  []string      `json:"origins"`
+	TTL      time.Duration `json:"ttl"` // nanoseconds, 0 for no expiry
+}
+
+func issueEmbedToken(req embedTokenRequest) (string, EmbedToken, error) {
+	if len(req.Keywords) == 0 || len(req.Origins) == 0 {
+		return "", EmbedToken{}, errors.New("keywords and origins are required")
+	}
+	var t EmbedToken
+	for _, k := range req.Keywords {
+		if k == "*" {
+			t.Keywords = append(t.Keywords, k)
+			continue
+		}
+		_, id := CanonicalKeyword(k)
+		t.Keywords = append(t.Keywords, id)
+	}
+	for _, o := range req.Origins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		if o != "*" {
+			u, err := url.Parse(o)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return "", EmbedToken{}, errors.New("invalid origin " + o)
+			}
+		}
+		t.Origins = append(t.Origins, o)
+	}
+	if req.TTL > 0 {
+		t.Expires = time.Now().Add(req.TTL).Unix()
+	}
+	token, err := SignEmbedToken(t)
+	return token, t, err
+}
+
+func apiIssueEmbedToken(w http.ResponseWriter, r *http.Request) {
+	var req embedTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	token, t, err := issueEmbedToken(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"token": token, "claims": t})
+}
+
+var embedAdminTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Embed Widget</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		textarea { width: 100%; }
+	</style>
+</head>
+<body>
+	<h1>🧩 Embed Widget</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="POST" class="text-block">
+		<p>Keywords (one per line, * for any)<br/><textarea name="keywords" rows="3">{{.Keywords}}</textarea></p>
+		<p>Allowed origins (one per line)<br/><textarea name="origins" rows="2">{{.Origins}}</textarea></p>
+		<p>Valid for <input type="text" name="ttl" value="{{.TTL}}" size="8" /> (e.g. 720h, empty for no expiry)</p>
+		<button type="submit">Create token</button>
+	</form>
+	{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+	{{if .Token}}
+		<div class="text-block">
+			<p>Embed snippet:</p>
+			<textarea rows="4" readonly>&lt;div data-aio-token="{{.Token}}" data-aio-keyword="{{.FirstKeyword}}"&gt;&lt;/div&gt;
+&lt;script src="{{.Base}}/embed.js" async&gt;&lt;/script&gt;</textarea>
+			<p>Optional attributes: data-aio-hl, data-aio-gl, data-aio-theme (light|dark), data-aio-accent (#hex), data-aio-font-size, data-aio-max-refs, data-aio-height.</p>
+		</div>
+	{{end}}
+</body>
+</html>
+`
+
+var embedAdminTpl = template.Must(template.New("embedadmin").Parse(embedAdminTmpl))
+
+func embedAdminPage(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Keywords, Origins, TTL string
+		Token, FirstKeyword    string
+		Base, Error            string
+	}{Keywords: r.FormValue("keywords"), Origins: r.FormValue("origins"), TTL: r.FormValue("ttl")}
+	if r.Method == http.MethodPost {
+		lines := func(s string) []string {
+			var out []string
+			for _, l := range strings.Split(s, "\n") {
+				if l = strings.TrimSpace(l); l != "" {
+					out = append(out, l)
+				}
+			}
+			return out
+		}
+		req := embedTokenRequest{Keywords: lines(data.Keywords), Origins: lines(data.Origins)}
+		if data.TTL != "" {
+			ttl, err := time.ParseDuration(data.TTL)
+			if err != nil {
+				data.Error = "invalid duration " + data.TTL
+			}
+			req.TTL = ttl
+		}
+		if data.Error == "" {
+			token, _, err := issueEmbedToken(req)
+			if err != nil {
+				data.Error = err.Error()
+			} else {
+				data.Token = token
+				if req.Keywords[0] != "*" {
+					data.FirstKeyword = req.Keywords[0]
+				}
+				scheme := "http"
+				if r.TLS != nil {
+					scheme = "https"
+				}
+				data.Base = scheme + "://" + r.Host
+			}
+		}
+	}
+	if err := embedAdminTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errEntityNotFound = errors.New("entity not found")
+
+// Entity types
+var entityTypes = []string{"product", "company", "person", "place", "other"}
+
+// Entity is a tracked product, company, person or other named thing
+type Entity struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Aliases []string `json:"aliases"`
+}
+
+// terms returns the canonical forms of the entity name and its aliases.
+func (e Entity) terms() []string {
+	var out []string
+	for _, t := range append([]string{e.Name}, e.Aliases...) {
+		if c := NormalizeQuery(t, normalizeOptions); c != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (e Entity) validate() error {
+	if strings.TrimSpace(e.Name) == "" {
+		return errors.New("entity name is required")
+	}
+	for _, t := range entityTypes {
+		if e.Type == t {
+			return nil
+		}
+	}
+	return errors.New("entity type must be one of " + strings.Join(entityTypes, ", "))
+}
+
+// EntityPassage is an overview passage mentioning an entity, deduplicated
+// across the snapshots of one keyword.
+type EntityPassage struct {
+	KeywordID  string      `json:"keyword_id"`
+	Keyword    string      `json:"keyword"`
+	Text       string      `json:"text"`
+	References []Reference `json:"references"`
+	FirstSeen  time.Time   `json:"first_seen"`
+	LastSeen   time.Time   `json:"last_seen"`
+	Snapshots  int         `json:"snapshots"`
+}
+
+// EntityCitation counts how often a link is cited by passages mentioning the entity
+type EntityCitation struct {
+	Reference Reference `json:"reference"`
+	Passages  int       `json:"passages"`
+}
+
+// EntityCoverage is the entity's presence in overviews on one day
+type EntityCoverage struct {
+	Day       string `json:"day"`
+	Keywords  int    `json:"keywords"`  // keywords whose overview mentioned the entity
+	Snapshots int    `json:"snapshots"` // snapshots taken that day, mentioning or not
+	Passages  int    `json:"passages"`
+}
+
+type EntityReport struct {
+	Entity    Entity           `json:"entity"`
+	Passages  []EntityPassage  `json:"passages"`
+	Citations []EntityCitation `json:"citations"`
+	Coverage  []EntityCoverage `json:"coverage"`
+}
+
+// BuildEntityReport collects everything stored overviews say about e.
+func BuildEntityReport(e Entity) EntityReport {
+	terms := e.terms()
+	report := EntityReport{Entity: e}
+	passages := map[string]*EntityPassage{}
+	citations := map[string]*EntityCitation{}
+	days := map[string]*EntityCoverage{}
+	dayKeywords := map[string]map[string]bool{}
+
+	for _, snap := range store.Snapshots(func(s Snapshot) bool { return s.Overview != nil }) {
+		day := snap.FetchedAt.Format("2006-01-02")
+		cov, ok := days[day]
+		if !ok {
+			cov = &EntityCoverage{Day: day}
+			days[day] = cov
+			dayKeywords[day] = map[string]bool{}
+		}
+		cov.Snapshots++
+		for _, p := range snap.Overview.Passages() {
+			if !mentionsAny(NormalizeQuery(p.Text, normalizeOptions), terms) {
+				continue
+			}
+			cov.Passages++
+			dayKeywords[day][snap.KeywordID] = true
+
+			key := snap.KeywordID + "|" + p.Text
+			ep, ok := passages[key]
+			if !ok {
+				ep = &EntityPassage{
+					KeywordID:  snap.KeywordID,
+					Keyword:    snap.Keyword,
+					Text:       p.Text,
+					References: snap.Overview.ReferencesFor(p.ReferenceIndexes),
+					FirstSeen:  snap.FetchedAt,
+				}
+				passages[key] = ep
+				for _, ref := range ep.References {
+					c, ok := citations[ref.Link]
+					if !ok {
+						c = &EntityCitation{Reference: ref}
+						citations[ref.Link] = c
+					}
+					c.Passages++
+				}
+			}
+			ep.LastSeen = snap.FetchedAt
+			ep.Snapshots++
+		}
+	}
+
+	for _, ep := range passages {
+		report.Passages = append(report.Passages, *ep)
+	}
+	sort.Slice(report.Passages, func(i, j int) bool { return report.Passages[i].LastSeen.After(report.Passages[j].LastSeen) })
+	for _, c := range citations {
+		report.Citations = append(report.Citations, *c)
+	}
+	sort.Slice(report.Citations, func(i, j int) bool {
+		if report.Citations[i].Passages != report.Citations[j].Passages {
+			return report.Citations[i].Passages > report.Citations[j].Passages
+		}
+		return report.Citations[i].Reference.Link < report.Citations[j].Reference.Link
+	})
+	for day, cov := range days {
+		cov.Keywords = len(dayKeywords[day])
+		report.Coverage = append(report.Coverage, *cov)
+	}
+	sort.Slice(report.Coverage, func(i, j int) bool { return report.Coverage[i].Day < report.Coverage[j].Day })
+	return report
+}
+
+func findEntity(r *http.Request) (Entity, bool) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	for _, e := range store.Entities() {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Entity{}, false
+}
+
+// splitAliases reads a comma separated alias list from a form field.
+func splitAliases(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+var entitiesTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Entities</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.type { color: #888; }
+	</style>
+</head>
+<body>
+	<h1>🏷️ Entities</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="POST" action="/entities" class="text-block">
+		<input type="text" name="name" placeholder="Name" required />
+		<select name="type">{{range .Types}}<option>{{.}}</option>{{end}}</select>
+		<input type="text" name="aliases" placeholder="Aliases, comma separated" style="width:40%;" />
+		<button type="submit">Add</button>
+	</form>
+	{{range .Entities}}
+		<div class="text-block">
+			<a href="/entities/{{.ID}}"><strong>{{.Name}}</strong></a> <span class="type">{{.Type}}</span>
+			{{if .Aliases}}<p>Aliases: {{join .Aliases ", "}}</p>{{end}}
+			<form method="POST" action="/entities/{{.ID}}/delete"><button type="submit">Delete</button></form>
+		</div>
+	{{else}}
+		<p><em>No entities yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var entityTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>{{.Entity.Name}}</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; margin-bottom: 1rem; }
+		th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+		.meta { color: #888; }
+	</style>
+</head>
+<body>
+	<h1>🏷️ {{.Entity.Name}}</h1>
+	<p><a href="/entities">← Entities</a> · {{.Entity.Type}}{{if .Entity.Aliases}} · aka {{join .Entity.Aliases ", "}}{{end}}</p>
+	<h2>📈 Coverage over time</h2>
+	<table>
+		<tr><th>Day</th><th>Keywords mentioning</th><th>Passages</th><th>Snapshots taken</th></tr>
+		{{range .Coverage}}<tr><td>{{.Day}}</td><td>{{.Keywords}}</td><td>{{.Passages}}</td><td>{{.Snapshots}}</td></tr>{{end}}
+	</table>
+	<h2>🔗 Cited references</h2>
+	<ul>
+	{{range .Citations}}<li><a href="{{.Reference.Link}}">{{.Reference.Title}}</a> <span class="meta">{{.Reference.Source}} · {{.Passages}} passages</span></li>{{else}}<li><em>None</em></li>{{end}}
+	</ul>
+	<h2>💬 Passages</h2>
+	{{range .Passages}}
+		<div class="text-block">
+			<span class="meta"><a href="/keywords/{{.KeywordID}}/timeline">{{.Keyword}}</a> · {{.FirstSeen.Format "2006-01-02"}} → {{.LastSeen.Format "2006-01-02"}} · {{.Snapshots}} snapshots</span>
+			<p>{{.Text}}</p>
+			{{range .References}}<p class="meta">↳ <a href="{{.Link}}">{{.Title}}</a></p>{{end}}
+		</div>
+	{{else}}
+		<p><em>No overview mentions this entity yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var (
+	entitiesTpl = template.Must(template.New("entities").Funcs(template.FuncMap{"join": strings.Join}).Parse(entitiesTmpl))
+	entityTpl   = template.Must(template.New("entity").Funcs(template.FuncMap{"join": strings.Join}).Parse(entityTmpl))
+)
+
+func entitiesPage(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Entities []Entity
+		Types    []string
+	}{store.Entities(), entityTypes}
+	if err := entitiesTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func entityPage(w http.ResponseWriter, r *http.Request) {
+	e, ok := findEntity(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := entityTpl.Execute(w, BuildEntityReport(e)); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+// createEntity handles the add form on the entities page.
+func createEntity(w http.ResponseWriter, r *http.Request) {
+	e := Entity{Name: strings.TrimSpace(r.FormValue("name")), Type: r.FormValue("type"), Aliases: splitAliases(r.FormValue("aliases"))}
+	if err := e.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := store.SaveEntity(e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/entities", http.StatusSeeOther)
+}
+
+func deleteEntity(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := store.DeleteEntity(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, "/entities", http.StatusSeeOther)
+}
+
+func apiEntities(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, store.Entities())
+}
+
+// apiSaveEntity creates (POST) or replaces (PUT /{id}) an entity.
+func apiSaveEntity(w http.ResponseWriter, r *http.Request) {
+	var e Entity
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	e.ID = 0
+	if r.Method == http.MethodPut {
+		// A PUT never creates: an id that does not parse is not found.
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id <= 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": errEntityNotFound.Error()})
+			return
+		}
+		e.ID = id
+	}
+	if err := e.validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	saved, err := store.SaveEntity(e)
+	if errors.Is(err, errEntityNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+func apiDeleteEntity(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := store.DeleteEntity(id); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiEntityReport(w http.ResponseWriter, r *http.Request) {
+	e, ok := findEntity(r)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": errEntityNotFound.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, BuildEntityReport(e))
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Evidence captures. A capture makes a fresh SerpAPI lookup and bundles
+// the raw responses, the parsed overview and the request into a canonical
+// JSON document, which is hashed and signed with the server's Ed25519 key.
+// The key is read from evidence_key (hex seed) or data_dir/evidence_key,
+// created on first use. Keep a copy of the public key with the bundles:
+// anyone can verify a bundle against it with "aio evidence verify".
+
+const evidenceVersion = 1
+
+// EvidenceDocument is the signed content of a capture
+type EvidenceDocument struct {
+	Version     int             `json:"version"`
+	CapturedAt  time.Time       `json:"captured_at"`
+	CompletedAt time.Time       `json:"completed_at"`
+	Request     EvidenceRequest `json:"request"`
+	SnapshotID  int64           `json:"snapshot_id"`
+	Overview    *AIOverview     `json:"overview"`
+	Error       string          `json:"error,omitempty"`
+	Exchanges   []Exchange      `json:"exchanges"` // raw SerpAPI calls, bodies base64 encoded
+}
+
+// EvidenceRequest is what was asked upstream
+type EvidenceRequest struct {
+	Query    string `json:"query"`
+	HL       string `json:"hl"`
+	GL       string `json:"gl"`
+	Location string `json:"location,omitempty"`
+}
+
+// EvidenceBundle is a document with its hash and signature
+type EvidenceBundle struct {
+	Document  json.RawMessage `json:"document"`   // canonical JSON
+	SHA256    string          `json:"sha256"`     // hex digest of document
+	Signature string          `json:"signature"`  // base64 Ed25519 signature of document
+	PublicKey string          `json:"public_key"` // base64 Ed25519 public key
+}
+
+// ID names a bundle by the start of its hash.
+func (b EvidenceBundle) ID() string {
+	if len(b.SHA256) < 16 {
+		return b.SHA256
+	}
+	return b.SHA256[:16]
+}
+
+// EvidenceVerification is the outcome of checking a bundle
+type EvidenceVerification struct {
+	ID          string            `json:"id"`
+	HashValid   bool              `json:"hash_valid"`
+	SignatureOK bool              `json:"signature_valid"`
+	TrustedKey  bool              `json:"trusted_key"` // signed by this server's key or the one given
+	Valid       bool              `json:"valid"`
+	Errors      []string          `json:"errors,omitempty"`
+	Document    *EvidenceDocument `json:"document,omitempty"`
+	KeyID       string            `json:"key_id"`
+}
+
+var evidenceKey ed25519.PrivateKey
+
+func loadEvidenceKey() (ed25519.PrivateKey, error) {
+	if s := os.Getenv("evidence_key"); s != "" {
+		seed, err := hex.DecodeString(strings.TrimSpace(s))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("evidence_key must be a 32 byte hex seed")
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	path := filepath.Join(dataDir(), "evidence_key")
+	if raw, err := os.ReadFile(path); err == nil {
+		seed, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%s is not a 32 byte hex seed", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed), os.WriteFile(path, []byte(hex.EncodeToString(seed)), 0o600)
+}
+
+func publicKeyString(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// evidenceKeyID is a short fingerprint of a public key.
+func evidenceKeyID(pub []byte) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// canonicalJSON encodes v with sorted keys and no insignificant whitespace,
+// so equal documents always give equal bytes. Strings are escaped the way
+// encoding/json does by default, so embedding the result in another JSON
+// value leaves it byte for byte the same.
+func canonicalJSON(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// SignEvidence canonicalizes, hashes and signs a document.
+func SignEvidence(doc EvidenceDocument, key ed25519.PrivateKey) (EvidenceBundle, error) {
+	canonical, err := canonicalJSON(doc)
+	if err != nil {
+		return EvidenceBundle{}, err
+	}
+	sum := sha256.Sum256(canonical)
+	return EvidenceBundle{
+		Document:  canonical,
+		SHA256:    hex.EncodeToString(sum[:]),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)),
+		PublicKey: publicKeyString(key.Public().(ed25519.PublicKey)),
+	}, nil
+}
+
+// VerifyEvidence checks a bundle's hash and signature. The document is
+// canonicalized again first, so a bundle that was pretty-printed still
+// verifies. trusted is the key the bundle should be signed with; the key in
+// the bundle alone only proves the bundle is consistent.
+func VerifyEvidence(b EvidenceBundle, trusted ed25519.PublicKey) EvidenceVerification {
+	v := EvidenceVerification{ID: b.ID()}
+	fail := func(msg string) { v.Errors = append(v.Errors, msg) }
+
+	var doc EvidenceDocument
+	canonical, err := canonicalJSON(b.Document)
+	if err == nil {
+		err = json.Unmarshal(canonical, &doc)
+	}
+	if err != nil {
+		fail("document is not valid JSON: " + err.Error())
+		return v
+	}
+	v.Document = &doc
+	sum := sha256.Sum256(canonical)
+	v.HashValid = hex.EncodeToString(sum[:]) == strings.ToLower(b.SHA256)
+	if !v.HashValid {
+		fail("document does not match its SHA-256 hash")
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(b.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		fail("public key is not a base64 Ed25519 key")
+		return v
+	}
+	v.KeyID = evidenceKeyID(pub)
+	sig, err := base64.StdEncoding.DecodeString(b.Signature)
+	v.SignatureOK = err == nil && ed25519.Verify(pub, canonical, sig)
+	if !v.SignatureOK {
+		fail("signature does not match the document")
+	}
+	v.TrustedKey = trusted != nil && bytes.Equal(pub, trusted)
+	if !v.TrustedKey {
+		fail("signed by key " + v.KeyID + ", which is not the trusted key")
+	}
+	if doc.Version < 1 || doc.Version > evidenceVersion {
+		fail(fmt.Sprintf("document version %d is not supported", doc.Version))
+	}
+	v.Valid = len(v.Errors) == 0
+	return v
+}
+
+func evidencePath(id string) string {
+	return filepath.Join(dataDir(), "evidence", id+".json")
+}
+
+func saveEvidence(b EvidenceBundle) error {
+	p := evidencePath(b.ID())
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// compact, so the stored document keeps its canonical bytes
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, raw, 0o600)
+}
+
+func loadEvidence(id string) (EvidenceBundle, error) {
+	var b EvidenceBundle
+	if strings.ContainsAny(id, `/\.`) {
+		return b, fs.ErrNotExist
+	}
+	raw, err := os.ReadFile(evidencePath(id))
+	if err != nil {
+		return b, err
+	}
+	return b, json.Unmarshal(raw, &b)
+}
+
+// EvidenceSummary lists a stored capture
+type EvidenceSummary struct {
+	ID         string    `json:"id"`
+	Query      string    `json:"query"`
+	Locale     string    `json:"locale"`
+	CapturedAt time.Time `json:"captured_at"`
+	Overview   bool      `json:"overview"`
+}
+
+// listEvidence returns the stored captures, newest first.
+func listEvidence() ([]EvidenceSummary, error) {
+	entries, err := os.ReadDir(filepath.Join(dataDir(), "evidence"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []EvidenceSummary
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		b, err := loadEvidence(id)
+		if err != nil {
+			return nil, err
+		}
+		var doc EvidenceDocument
+		if err := json.Unmarshal(b.Document, &doc); err != nil {
+			continue
+		}
+		out = append(out, EvidenceSummary{ID: id, Query: doc.Request.Query,
+			Locale: Locale{HL: doc.Request.HL, GL: doc.Request.GL}.String(), CapturedAt: doc.CapturedAt, Overview: doc.Overview != nil})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CapturedAt.After(out[j].CapturedAt) })
+	return out, nil
+}
+
+// CaptureEvidence looks the query up afresh, bypassing the cache, and signs
+// what came back.
+func CaptureEvidence(query string, loc Locale) (EvidenceBundle, error) {
+	started := time.Now()
+	snap, err := refresh(query, loc, cache.TTL())
+	if err != nil && !errors.Is(err, errNoOverview) {
+		return EvidenceBundle{}, err
+	}
+	ex, lerr := loadExchanges(snap.ID)
+	if lerr != nil {
+		return EvidenceBundle{}, lerr
+	}
+	if len(ex) == 0 {
+		return EvidenceBundle{}, errors.New("no upstream response was recorded")
+	}
+	doc := EvidenceDocument{
+		Version:     evidenceVersion,
+		CapturedAt:  started.UTC(),
+		CompletedAt: time.Now().UTC(),
+		Request:     EvidenceRequest{Query: query, HL: loc.HL, GL: loc.GL, Location: loc.Location},
+		SnapshotID:  snap.ID,
+		Overview:    snap.Overview,
+		Error:       snap.Error,
+		Exchanges:   ex,
+	}
+	b, err := SignEvidence(doc, evidenceKey)
+	if err != nil {
+		return b, err
+	}
+	if err := saveEvidence(b); err != nil {
+		return b, err
+	}
+	log.Printf("🔏 evidence %s captured for %q (%s)", b.ID(), query, loc)
+	return b, nil
+}
+
+// runEvidenceCommand implements
+//
+//	aio evidence verify [-pubkey base64] <bundle.json>
+//
+// Without -pubkey the bundle must be signed with the key in the data
+// directory.
+func runEvidenceCommand(args []string) error {
+	fl := flag.NewFlagSet("evidence verify", flag.ContinueOnError)
+	pubkey := fl.String("pubkey", "", "trusted base64 Ed25519 public key")
+	if len(args) < 2 || args[1] != "verify" {
+		return errors.New("usage: evidence verify [-pubkey key] <bundle.json>")
+	}
+	if err := fl.Parse(args[2:]); err != nil {
+		return err
+	}
+	if fl.NArg() != 1 {
+		return errors.New("usage: evidence verify [-pubkey key] <bundle.json>")
+	}
+	var trusted ed25519.PublicKey
+	if *pubkey != "" {
+		raw, err := base64.StdEncoding.DecodeString(*pubkey)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return errors.New("-pubkey must be a base64 Ed25519 public key")
+		}
+		trusted = raw
+	} else {
+		key, err := loadEvidenceKey()
+		if err != nil {
+			return err
+		}
+		trusted = key.Public().(ed25519.PublicKey)
+	}
+	raw, err := os.ReadFile(fl.Arg(0))
+	if err != nil {
+		return err
+	}
+	var b EvidenceBundle
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return fmt.Errorf("not an evidence bundle: %w", err)
+	}
+	v := VerifyEvidence(b, trusted)
+	if !v.Valid {
+		return errors.New(strings.Join(v.Errors, "; "))
+	}
+	log.Printf("✅ %s is intact and signed by key %s: %q (%s-%s) captured %s", fl.Arg(0), v.KeyID,
+		v.Document.Request.Query, v.Document.Request.HL, v.Document.Request.GL, v.Document.CapturedAt.Format(time.RFC3339))
+	return nil
+}
+
+func trustedEvidenceKey() ed25519.PublicKey {
+	return evidenceKey.Public().(ed25519.PublicKey)
+}
+
+var evidenceTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Evidence</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.25rem 0.5rem; border-bottom: 1px solid #ddd; }
+		.ok { color: #1a7f37; } .flag { color: #cf222e; }
+		code { word-break: break-all; }
+	</style>
+</head>
+<body>
+	<h1>🔏 Evidence</h1>
+	<p><a href="/">← Search</a></p>
+	{{with .Verification}}
+		<div class="text-block">
+			<h2>{{if .Valid}}<span class="ok">✅ Verified</span>{{else}}<span class="flag">❌ Not verified</span>{{end}}</h2>
+			<p>Hash {{if .HashValid}}<span class="ok">matches</span>{{else}}<span class="flag">does not match</span>{{end}} ·
+				signature {{if .SignatureOK}}<span class="ok">valid</span>{{else}}<span class="flag">invalid</span>{{end}} ·
+				key {{.KeyID}} {{if .TrustedKey}}<span class="ok">is this server's</span>{{else}}<span class="flag">is not this server's</span>{{end}}</p>
+			{{range .Errors}}<p class="flag">{{.}}</p>{{end}}
+			{{with .Document}}
+				<p><b>{{.Request.Query}}</b> ({{.Request.HL}}-{{.Request.GL}}{{if .Request.Location}}, {{.Request.Location}}{{end}}) captured {{.CapturedAt.Format "2006-01-02 15:04:05 MST"}} from {{len .Exchanges}} SerpAPI responses.</p>
+				{{if .Overview}}{{range .Overview.TextBlocks}}<p>{{.Snippet}}</p>{{end}}{{else}}<p><em>No AI Overview was shown{{if .Error}} ({{.Error}}){{end}}.</em></p>{{end}}
+			{{end}}
+			{{if $.ID}}<p><a href="/api/v1/evidence/{{$.ID}}">Download bundle</a></p>{{end}}
+		</div>
+	{{end}}
+	<form method="POST" action="/evidence" class="text-block">
+		<input type="text" name="q" placeholder="Query" required />
+		<input type="text" name="hl" placeholder="hl" size="4" />
+		<input type="text" name="gl" placeholder="gl" size="4" />
+		<button type="submit">Capture</button>
+		<p><small>Makes a fresh SerpAPI lookup and signs the raw response.</small></p>
+	</form>
+	<form method="POST" action="/evidence/verify" enctype="multipart/form-data" class="text-block">
+		<input type="file" name="bundle" accept="application/json" required />
+		<button type="submit">Verify a bundle</button>
+	</form>
+	<p>Public key <code>{{.PublicKey}}</code> (key {{.KeyID}})</p>
+	<table>
+		<tr><th>Captured</th><th>Query</th><th>Locale</th><th>Overview</th><th>ID</th></tr>
+		{{range .Captures}}
+			<tr>
+				<td>{{.CapturedAt.Format "2006-01-02 15:04"}}</td>
+				<td>{{.Query}}</td>
+				<td>{{.Locale}}</td>
+				<td>{{if .Overview}}yes{{else}}no{{end}}</td>
+				<td><a href="/evidence/{{.ID}}">{{.ID}}</a></td>
+			</tr>
+		{{else}}
+			<tr><td colspan="5"><em>No captures yet.</em></td></tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var evidenceTpl = template.Must(template.New("evidence").Parse(evidenceTmpl))
+
+func renderEvidence(w http.ResponseWriter, id string, v *EvidenceVerification) {
+	captures, err := listEvidence()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pub := trustedEvidenceKey()
+	data := map[string]any{"ID": id, "Verification": v, "Captures": captures,
+		"PublicKey": publicKeyString(pub), "KeyID": evidenceKeyID(pub)}
+	if err := evidenceTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func evidencePage(w http.ResponseWriter, r *http.Request) {
+	renderEvidence(w, "", nil)
+}
+
+// evidenceDetail verifies a stored capture and shows it.
+func evidenceDetail(w http.ResponseWriter, r *http.Request) {
+	b, err := loadEvidence(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	v := VerifyEvidence(b, trustedEvidenceKey())
+	renderEvidence(w, b.ID(), &v)
+}
+
+func captureEvidenceForm(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	if query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+	b, err := CaptureEvidence(query, localeFrom(r.FormValue("hl"), r.FormValue("gl")))
+	if err != nil {
+		http.Error(w, redactKeys(err.Error()), http.StatusBadGateway)
+		return
+	}
+	http.Redirect(w, r, "/evidence/"+b.ID(), http.StatusSeeOther)
+}
+
+// verifyEvidenceForm checks an uploaded bundle.
+func verifyEvidenceForm(w http.ResponseWriter, r *http.Request) {
+	f, _, err := r.FormFile("bundle")
+	if err != nil {
+		http.Error(w, "choose a bundle to verify", http.StatusBadRequest)
+		return
+	}
+	defer f.Close()
+	var b EvidenceBundle
+	if err := json.NewDecoder(io.LimitReader(f, 64<<20)).Decode(&b); err != nil {
+		http.Error(w, "not an evidence bundle", http.StatusBadRequest)
+		return
+	}
+	v := VerifyEvidence(b, trustedEvidenceKey())
+	renderEvidence(w, "", &v)
+}
+
+// apiCaptureEvidence serves POST /api/v1/evidence with {"q", "hl", "gl"}.
+func apiCaptureEvidence(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Q  string `json:"q"`
+		HL string `json:"hl"`
+		GL string `json:"gl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Q) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+	b, err := CaptureEvidence(strings.TrimSpace(req.Q), localeFrom(req.HL, req.GL))
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": redactKeys(err.Error())})
+		return
+	}
+	writeJSON(w, http.StatusCreated, b)
+}
+
+func apiEvidence(w http.ResponseWriter, r *http.Request) {
+	b, err := loadEvidence(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "evidence not found"})
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="evidence-`+b.ID()+`.json"`)
+	writeJSON(w, http.StatusOK, b)
+}
+
+func apiVerifyEvidence(w http.ResponseWriter, r *http.Request) {
+	var b EvidenceBundle
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<20)).Decode(&b); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "not an evidence bundle"})
+		return
+	}
+	writeJSON(w, http.StatusOK, VerifyEvidence(b, trustedEvidenceKey()))
+}
+
+func apiEvidenceKey(w http.ResponseWriter, r *http.Request) {
+	pub := trustedEvidenceKey()
+	writeJSON(w, http.StatusOK, map[string]string{"algorithm": "Ed25519", "public_key": publicKeyString(pub), "key_id": evidenceKeyID(pub)})
+}
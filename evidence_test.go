@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestVerifyEvidence(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{1}, 64)))
+	otherPub, otherKey, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{2}, 64)))
+	at := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	doc := EvidenceDocument{
+		Version: evidenceVersion, CapturedAt: at, CompletedAt: at.Add(2 * time.Second),
+		SnapshotID: 42, Overview: testSnapshot(at, "https://www.logammulia.com/id").Overview,
+		Exchanges: []Exchange{{Engine: "google", URL: "https://serpapi.com/search.json?q=harga+emas", Body: []byte(`{"ok":true}`)}},
+	}
+	signed, err := SignEvidence(doc, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(b *EvidenceBundle)
+		trusted ed25519.PublicKey
+		valid   bool
+		hash    bool
+		sig     bool
+	}{
+		{"intact", func(b *EvidenceBundle) {}, pub, true, true, true},
+		{"reformatted document", func(b *EvidenceBundle) {
+			var v any
+			json.Unmarshal(b.Document, &v)
+			b.Document, _ = json.MarshalIndent(v, "", "  ")
+		}, pub, true, true, true},
+		{"edited document", func(b *EvidenceBundle) {
+			b.Document = bytes.Replace(b.Document, []byte(`"snapshot_id":42`), []byte(`"snapshot_id":43`), 1)
+		}, pub, false, false, false},
+		{"edited document with new hash", func(b *EvidenceBundle) {
+			re, _ := SignEvidence(EvidenceDocument{Version: evidenceVersion, SnapshotID: 43}, key)
+			b.Document, b.SHA256 = re.Document, re.SHA256
+		}, pub, false, true, false},
+		{"edited exchange body", func(b *EvidenceBundle) {
+			body := base64.StdEncoding.EncodeToString([]byte(`{"ok":true}`))
+			b.Document = bytes.Replace(b.Document, []byte(body), []byte(base64.StdEncoding.EncodeToString([]byte(`{"ok":false}`))), 1)
+		}, pub, false, false, false},
+		{"re-signed by another key", func(b *EvidenceBundle) {
+			*b, _ = SignEvidence(doc, otherKey)
+		}, pub, false, true, true},
+		{"another key trusted", func(b *EvidenceBundle) {}, otherPub, false, true, true},
+		{"no trusted key", func(b *EvidenceBundle) {}, nil, false, true, true},
+		{"corrupt signature", func(b *EvidenceBundle) { b.Signature = "not base64!" }, pub, false, true, false},
+		{"swapped public key", func(b *EvidenceBundle) { b.PublicKey = publicKeyString(otherPub) }, pub, false, true, false},
+		{"truncated public key", func(b *EvidenceBundle) { b.PublicKey = b.PublicKey[:20] }, pub, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := signed
+			b.Document = append(json.RawMessage(nil), signed.Document...)
+			tt.edit(&b)
+			v := VerifyEvidence(b, tt.trusted)
+			if v.Valid != tt.valid || v.HashValid != tt.hash || v.SignatureOK != tt.sig {
+				t.Errorf("valid=%v hash=%v signature=%v, want %v %v %v (errors %q)",
+					v.Valid, v.HashValid, v.SignatureOK, tt.valid, tt.hash, tt.sig, v.Errors)
+			}
+			if v.Valid != (len(v.Errors) == 0) {
+				t.Errorf("valid=%v with errors %q", v.Valid, v.Errors)
+			}
+		})
+	}
+
+	if v := VerifyEvidence(EvidenceBundle{Document: json.RawMessage(`{`)}, pub); v.Valid || v.Document != nil {
+		t.Errorf("broken JSON verified: %+v", v)
+	}
+	if v := VerifyEvidence(signed, pub); v.Document == nil || v.Document.SnapshotID != 42 || !v.Document.CapturedAt.Equal(at) {
+		t.Errorf("document = %+v", v.Document)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PageFetcher downloads reference and result pages politely: one request
+// per host every page_fetch_delay (default 2s), at most
+// page_fetch_concurrency (default 2) at a time, and never a path robots.txt
+// disallows. page_fetch_base sends every request to a local server instead,
+// as base/host/path, for mirrors and offline fixtures.
+type PageFetcher struct {
+	client *http.Client
+	base   string
+	agent  string
+	delay  time.Duration
+	sem    chan struct{}
+
+	mu     sync.Mutex
+	next   map[string]time.Time // host -> earliest next request
+	robots map[string][]string  // host -> disallowed path prefixes
+}
+
+const maxPageSize = 5 << 20
+
+var errDisallowed = errors.New("disallowed by robots.txt")
+
+func newPageFetcher() *PageFetcher {
+	f := &PageFetcher{
+		client: &http.Client{Timeout: 20 * time.Second},
+		base:   strings.TrimRight(os.Getenv("page_fetch_base"), "/"),
+		agent:  os.Getenv("page_fetch_agent"),
+		delay:  2 * time.Second,
+		next:   map[string]time.Time{},
+		robots: map[string][]string{},
+	}
+	if f.agent == "" {
+		f.agent = "aio-overview-research/1.0"
+	}
+	if d, err := time.ParseDuration(os.Getenv("page_fetch_delay")); err == nil && d >= 0 {
+		f.delay = d
+	}
+	n, err := strconv.Atoi(os.Getenv("page_fetch_concurrency"))
+	if err != nil || n < 1 {
+		n = 2
+	}
+	f.sem = make(chan struct{}, n)
+	return f
+}
+
+var pageFetcher = newPageFetcher()
+
+// target is where a request for u actually goes.
+func (f *PageFetcher) target(u *url.URL) string {
+	if f.base == "" {
+		return u.String()
+	}
+	t := f.base + "/" + u.Host + u.EscapedPath()
+	if u.RawQuery != "" {
+		t += "?" + u.RawQuery
+	}
+	return t
+}
+
+// wait blocks until host may be requested again and books the next slot.
+func (f *PageFetcher) wait(host string) {
+	f.mu.Lock()
+	at := time.Now()
+	if next := f.next[host]; next.After(at) {
+		at = next
+	}
+	f.next[host] = at.Add(f.delay)
+	f.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+func (f *PageFetcher) get(u *url.URL) (*http.Response, error) {
+	f.wait(u.Host)
+	req, err := http.NewRequest(http.MethodGet, f.target(u), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.agent)
+	return f.client.Do(req)
+}
+
+// allowed checks u against the host's robots.txt, reading it once.
+func (f *PageFetcher) allowed(u *url.URL) bool {
+	f.mu.Lock()
+	rules, ok := f.robots[u.Host]
+	f.mu.Unlock()
+	if !ok {
+		rules = nil
+		if resp, err := f.get(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+				rules = parseRobots(raw, f.agent)
+			}
+			resp.Body.Close()
+		}
+		f.mu.Lock()
+		f.robots[u.Host] = rules
+		f.mu.Unlock()
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	for _, prefix := range rules {
+		if strings.HasPrefix(p, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRobots returns the Disallow prefixes of the groups for agent or *.
+// Allow lines and wildcards are not interpreted, which errs on the side of
+// fetching less.
+func parseRobots(raw []byte, agent string) []string {
+	token := strings.ToLower(strings.SplitN(agent, "/", 2)[0])
+	var rules []string
+	applies, inAgents := false, false
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				applies = false
+			}
+			inAgents = true
+			if v := strings.ToLower(value); v == "*" || v == token {
+				applies = true
+			}
+		case "disallow":
+			inAgents = false
+			if applies && value != "" {
+				if i := strings.IndexAny(value, "*$"); i >= 0 {
+					value = value[:i]
+				}
+				rules = append(rules, value)
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return rules
+}
+
+// Fetch downloads an HTML page.
+func (f *PageFetcher) Fetch(link string) (int, []byte, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, nil, fmt.Errorf("not a web page: %q", link)
+	}
+	f.sem <- struct{}{}
+	defer func() { <-f.sem }()
+	if !f.allowed(u) {
+		return 0, nil, errDisallowed
+	}
+	resp, err := f.get(u)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil, fmt.Errorf("%s", resp.Status)
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "" && mt != "text/html" && mt != "application/xhtml+xml" {
+		return resp.StatusCode, nil, fmt.Errorf("not HTML: %s", mt)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	return resp.StatusCode, body, err
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Git history sink. Set git_history to a directory and every lookup writes
+// the keyword's latest overview there as JSON and Markdown, committed to a
+// git repository whenever it changed:
+//
+//	<hl-gl>/<location>/<keyword ID>-<slug>.json
+//	<hl-gl>/<location>/<keyword ID>-<slug>.md
+//
+// Locales without a location leave out that directory. Other files in the
+// directory, such as a README, are left alone.
+//
+// A new repository is filled from the stored history first, so git log
+// goes back as far as the store does.
+
+// gitHistory is nil unless the sink is configured.
+var gitHistory *GitSink
+
+// GitSink commits overviews to a local repository
+type GitSink struct {
+	repo   *git.Repository
+	dir    string
+	author object.Signature
+	queue  chan Snapshot
+}
+
+// OpenGitSink opens or creates the repository at dir.
+func OpenGitSink(dir string) (*GitSink, error) {
+	s := &GitSink{dir: dir, author: object.Signature{Name: "aio", Email: "aio@localhost"}, queue: make(chan Snapshot, 256)}
+	if name := os.Getenv("git_history_author"); name != "" {
+		s.author.Name = name
+	}
+	if email := os.Getenv("git_history_email"); email != "" {
+		s.author.Email = email
+	}
+	repo, err := git.PlainOpen(dir)
+	fresh := errors.Is(err, git.ErrRepositoryNotExists)
+	if fresh {
+		repo, err = git.PlainInit(dir, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.repo = repo
+	if fresh {
+		n := 0
+		for _, snap := range store.Snapshots(nil) {
+			committed, err := s.Record(snap)
+			if err != nil {
+				return nil, err
+			}
+			if committed {
+				n++
+			}
+		}
+		log.Printf("🗂️ created git history in %s with %d commits", dir, n)
+	}
+	return s, nil
+}
+
+// Enqueue hands a snapshot to the background writer without blocking the
+// lookup; Run commits in the order snapshots arrive.
+func (s *GitSink) Enqueue(snap Snapshot) {
+	select {
+	case s.queue <- snap:
+	default:
+		log.Println("❌ git history queue full, skipped snapshot", snap.ID)
+	}
+}
+
+func (s *GitSink) Run() {
+	for snap := range s.queue {
+		if _, err := s.Record(snap); err != nil {
+			log.Println("❌ failed to commit git history:", err)
+		}
+	}
+}
+
+// gitSlug keeps letters and digits of the keyword for readable file names.
+func gitSlug(keyword string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(keyword) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// gitOverviewPath is the file name, without extension, of a series.
+func gitOverviewPath(snap Snapshot) string {
+	name := snap.KeywordID
+	if slug := gitSlug(snap.Keyword); slug != "" {
+		name += "-" + slug
+	}
+	dir := snap.Locale.String()
+	if loc := gitSlug(snap.Locale.Location); loc != "" {
+		dir = path.Join(dir, loc)
+	}
+	return path.Join(dir, name)
+}
+
+// normalizeOverview trims text and orders references by index, so equal
+// overviews give equal files whatever order SerpAPI used.
+func normalizeOverview(ai AIOverview) AIOverview {
+	out := AIOverview{}
+	for _, tb := range ai.TextBlocks {
+		tb.Snippet = strings.TrimSpace(tb.Snippet)
+		tb.List = append([]ListItem(nil), tb.List...)
+		for i := range tb.List {
+			tb.List[i].Title = strings.TrimSpace(tb.List[i].Title)
+			tb.List[i].Snippet = strings.TrimSpace(tb.List[i].Snippet)
+		}
+		out.TextBlocks = append(out.TextBlocks, tb)
+	}
+	for _, ref := range ai.References {
+		ref.Title = strings.TrimSpace(ref.Title)
+		ref.Snippet = strings.TrimSpace(ref.Snippet)
+		out.References = append(out.References, ref)
+	}
+	sort.SliceStable(out.References, func(i, j int) bool { return out.References[i].Index < out.References[j].Index })
+	return out
+}
+
+// gitOverviewFile is the JSON written for a series
+type gitOverviewFile struct {
+	KeywordID string     `json:"keyword_id"`
+	Keyword   string     `json:"keyword"`
+	Locale    Locale     `json:"locale"`
+	Overview  AIOverview `json:"overview"`
+}
+
+func overviewJSON(snap Snapshot) ([]byte, error) {
+	raw, err := canonicalJSON(gitOverviewFile{KeywordID: snap.KeywordID, Keyword: snap.Keyword, Locale: snap.Locale, Overview: normalizeOverview(*snap.Overview)})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func overviewMarkdown(snap Snapshot) []byte {
+	ai := normalizeOverview(*snap.Overview)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\nLocale %s", snap.Keyword, snap.Locale)
+	if snap.Locale.Location != "" {
+		fmt.Fprintf(&b, ", %s", snap.Locale.Location)
+	}
+	b.WriteString("\n")
+	refs := func(idx []int) string {
+		var s []string
+		for _, i := range idx {
+			s = append(s, fmt.Sprintf("[%d]", i+1))
+		}
+		return strings.Join(s, "")
+	}
+	for _, tb := range ai.TextBlocks {
+		b.WriteString("\n")
+		if tb.Snippet != "" {
+			if tb.Type == "heading" {
+				b.WriteString("## ")
+			}
+			b.WriteString(tb.Snippet + refs(tb.ReferenceIndexes) + "\n")
+		}
+		for _, item := range tb.List {
+			b.WriteString("- ")
+			if item.Title != "" {
+				b.WriteString("**" + item.Title + "** ")
+			}
+			b.WriteString(item.Snippet + refs(item.ReferenceIndexes) + "\n")
+		}
+	}
+	if len(ai.References) > 0 {
+		b.WriteString("\n## References\n\n")
+		for _, ref := range ai.References {
+			fmt.Fprintf(&b, "%d. [%s](%s)", ref.Index+1, ref.Title, ref.Link)
+			if ref.Source != "" {
+				b.WriteString(" – " + ref.Source)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+// gitCommitMessage summarizes what changed between the committed overview
+// and the new one.
+func gitCommitMessage(snap Snapshot, before *AIOverview) string {
+	subject := fmt.Sprintf("%s (%s)", snap.Keyword, snap.Locale)
+	if snap.Overview == nil {
+		return subject + ": overview no longer shown\n"
+	}
+	if before == nil {
+		if n := len(snap.Overview.References); n != 1 {
+			return fmt.Sprintf("%s: overview with %d references\n", subject, n)
+		}
+		return subject + ": overview with 1 reference\n"
+	}
+	d := DiffOverviews(before, snap.Overview)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: +%d/−%d references, %.0f%% text change\n", subject, len(d.AddedReferences), len(d.RemovedReferences), d.TextChange*100)
+	if len(d.AddedReferences)+len(d.RemovedReferences) > 0 {
+		b.WriteString("\n")
+	}
+	for _, ref := range d.AddedReferences {
+		b.WriteString("+ " + ref.Link + "\n")
+	}
+	for _, ref := range d.RemovedReferences {
+		b.WriteString("- " + ref.Link + "\n")
+	}
+	return b.String()
+}
+
+// Record writes the snapshot's series and commits if anything changed.
+// Failed lookups leave the files alone; a lookup without an overview
+// removes them.
+func (s *GitSink) Record(snap Snapshot) (bool, error) {
+	if snap.Error != "" && snap.Error != errNoOverview.Error() {
+		return false, nil
+	}
+	wt, err := s.repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	base := gitOverviewPath(snap)
+	jsonPath, mdPath := base+".json", base+".md"
+
+	var before *AIOverview
+	if raw, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(jsonPath))); err == nil {
+		var f gitOverviewFile
+		if json.Unmarshal(raw, &f) == nil {
+			before = &f.Overview
+		}
+	}
+	if snap.Overview == nil {
+		if before == nil {
+			return false, nil
+		}
+		for _, p := range []string{jsonPath, mdPath} {
+			if _, err := wt.Remove(p); err != nil {
+				return false, err
+			}
+		}
+	} else {
+		raw, err := overviewJSON(snap)
+		if err != nil {
+			return false, err
+		}
+		files := map[string][]byte{jsonPath: raw, mdPath: overviewMarkdown(snap)}
+		for p, content := range files {
+			full := filepath.Join(s.dir, filepath.FromSlash(p))
+			if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+				return false, err
+			}
+			if err := os.WriteFile(full, content, 0o644); err != nil {
+				return false, err
+			}
+			if _, err := wt.Add(p); err != nil {
+				return false, err
+			}
+		}
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, err
+	}
+	// Only the series' own files count; untracked files elsewhere in the
+	// directory must not turn an unchanged overview into an empty commit.
+	changed := false
+	for _, p := range []string{jsonPath, mdPath} {
+		if st, ok := status[p]; ok && st.Staging != git.Unmodified && st.Staging != git.Untracked {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	author := s.author
+	author.When = snap.FetchedAt
+	_, err = wt.Commit(gitCommitMessage(snap, before), &git.CommitOptions{Author: &author})
+	return err == nil, err
+}
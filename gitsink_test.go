@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGitSinkIgnoresUntrackedFiles(t *testing.T) {
+	useTestStore(t)
+	dir := t.TempDir()
+	s, err := OpenGitSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("history\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	snap := testSnapshot(day, "https://a.example/")
+	if committed, err := s.Record(snap); err != nil || !committed {
+		t.Fatalf("first record = %v, %v, want a commit", committed, err)
+	}
+	snap.FetchedAt = day.Add(time.Hour)
+	if committed, err := s.Record(snap); err != nil || committed {
+		t.Fatalf("unchanged record = %v, %v, want no commit and no error", committed, err)
+	}
+
+	// A second location of the same keyword gets its own files.
+	other := testSnapshot(day, "https://b.example/")
+	snap.Locale.Location, other.Locale.Location = "Jakarta", "Surabaya"
+	if gitOverviewPath(snap) == gitOverviewPath(other) {
+		t.Fatalf("locations share the path %s", gitOverviewPath(snap))
+	}
+	if committed, err := s.Record(other); err != nil || !committed {
+		t.Fatalf("other location = %v, %v, want a commit", committed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(gitOverviewPath(other))+".json")); err != nil {
+		t.Error(err)
+	}
+}
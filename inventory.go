@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Site inventory. Our pages come from a sitemap.xml, a sitemap index or a
+// plain list of URLs, uploaded or fetched from a URL. A source URL is kept,
+// so the inventory can be refreshed from it later; sitemap sets the source
+// used before anything was imported, and is the only one that may be a
+// local path. Local sitemap index children must be in its directory.
+
+// InventoryPage is one page of our site
+type InventoryPage struct {
+	URL     string `json:"url"`
+	LastMod string `json:"lastmod,omitempty"`
+}
+
+// Inventory is the imported list of our pages
+type Inventory struct {
+	Source     string          `json:"source,omitempty"` // URL, or the sitemap path, to refresh from
+	ImportedAt time.Time       `json:"imported_at"`
+	Sitemaps   int             `json:"sitemaps"` // sitemap files read, including index children
+	Pages      []InventoryPage `json:"pages"`
+}
+
+const (
+	maxSitemapSize  = 50 << 20 // the sitemaps.org limit for one file, uncompressed
+	maxSitemapFiles = 1000
+)
+
+var sitemapClient = &http.Client{Timeout: 30 * time.Second}
+
+type sitemapXML struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemapLoader reads sitemaps and follows sitemap indexes. Children are
+// looked up among uploaded files by name first, then next to a local index,
+// then fetched. Files outside baseDir are never read.
+type sitemapLoader struct {
+	uploads map[string][]byte // by base name
+	baseDir string            // directory of the sitemap setting, when loading it
+	seen    map[string]bool
+	pages   []InventoryPage
+	files   int
+}
+
+func (l *sitemapLoader) open(loc string) ([]byte, error) {
+	name := path.Base(loc)
+	if raw, ok := l.uploads[name]; ok {
+		return raw, nil
+	}
+	if !strings.Contains(loc, "://") {
+		if l.baseDir == "" {
+			return nil, fmt.Errorf("%s: not uploaded and not a URL", name)
+		}
+		if !filepath.IsAbs(loc) {
+			loc = filepath.Join(l.baseDir, loc)
+		}
+		if !inDir(l.baseDir, loc) {
+			return nil, fmt.Errorf("%s: outside the sitemap directory", name)
+		}
+		return readLimited(os.Open(loc))
+	}
+	if !isWebURL(loc) {
+		return nil, fmt.Errorf("%s: only http and https sitemaps can be fetched", name)
+	}
+	if l.baseDir != "" {
+		if raw, err := readLimited(os.Open(filepath.Join(l.baseDir, name))); err == nil {
+			return raw, nil
+		}
+	}
+	resp, err := sitemapClient.Get(loc)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", loc, resp.Status)
+	}
+	return readLimited(resp.Body, nil)
+}
+
+// inDir reports whether p is dir or inside it.
+func inDir(dir, p string) bool {
+	rel, err := filepath.Rel(dir, filepath.Clean(p))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func isWebURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func readLimited(r io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	raw, err := io.ReadAll(io.LimitReader(r, maxSitemapSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxSitemapSize {
+		return nil, errors.New("sitemap is larger than 50 MB")
+	}
+	return raw, nil
+}
+
+// decodeSitemap unpacks a gzipped sitemap and trims a byte order mark.
+func decodeSitemap(raw []byte) ([]byte, error) {
+	if len(raw) > 2 && raw[0] == 0x1f && raw[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = readLimited(io.NopCloser(gz), nil); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.TrimSpace(bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))), nil
+}
+
+// load reads one sitemap or URL list given as raw bytes.
+func (l *sitemapLoader) load(name string, raw []byte, depth int) error {
+	if l.seen[name] {
+		return nil
+	}
+	l.seen[name] = true
+	l.files++
+	if l.files > maxSitemapFiles {
+		return fmt.Errorf("more than %d sitemaps", maxSitemapFiles)
+	}
+	text, err := decodeSitemap(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if !bytes.HasPrefix(text, []byte("<")) {
+		return l.loadList(text)
+	}
+
+	var sm sitemapXML
+	if err := xml.Unmarshal(text, &sm); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	switch sm.XMLName.Local {
+	case "urlset":
+		for _, u := range sm.URLs {
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				l.pages = append(l.pages, InventoryPage{URL: loc, LastMod: strings.TrimSpace(u.LastMod)})
+			}
+		}
+	case "sitemapindex":
+		if depth >= 3 {
+			return fmt.Errorf("%s: sitemap indexes nested too deep", name)
+		}
+		for _, child := range sm.Sitemaps {
+			loc := strings.TrimSpace(child.Loc)
+			if loc == "" || l.seen[loc] {
+				continue
+			}
+			raw, err := l.open(loc)
+			if err != nil {
+				return err
+			}
+			if err := l.load(loc, raw, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: expected urlset or sitemapindex, got <%s>", name, sm.XMLName.Local)
+	}
+	return nil
+}
+
+// loadList reads one URL per line; blank lines and # comments are skipped.
+func (l *sitemapLoader) loadList(raw []byte) error {
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !isWebURL(line) {
+			return fmt.Errorf("line %d is not a URL", n)
+		}
+		l.pages = append(l.pages, InventoryPage{URL: line})
+	}
+	return sc.Err()
+}
+
+// inventory returns the pages read, one per canonical URL.
+func (l *sitemapLoader) inventory(source string) Inventory {
+	inv := Inventory{Source: source, ImportedAt: time.Now(), Sitemaps: l.files}
+	seen := map[string]bool{}
+	for _, p := range l.pages {
+		if key := pageKey(p.URL); !seen[key] {
+			seen[key] = true
+			inv.Pages = append(inv.Pages, p)
+		}
+	}
+	return inv
+}
+
+// LoadInventory reads the sitemap or URL list at source, a URL or the path
+// of the sitemap setting.
+func LoadInventory(source string) (Inventory, error) {
+	l := &sitemapLoader{seen: map[string]bool{}}
+	loc := source
+	if !strings.Contains(source, "://") {
+		configured := os.Getenv("sitemap")
+		if configured == "" || filepath.Clean(source) != filepath.Clean(configured) {
+			return Inventory{}, errors.New("a local sitemap must be the one set in sitemap")
+		}
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return Inventory{}, err
+		}
+		loc, l.baseDir = abs, filepath.Dir(abs)
+	}
+	raw, err := l.open(loc)
+	if err != nil {
+		return Inventory{}, err
+	}
+	if err := l.load(loc, raw, 0); err != nil {
+		return Inventory{}, err
+	}
+	return l.inventory(source), nil
+}
+
+// LoadUploadedInventory reads uploaded files. With several files, the ones
+// no sitemap index refers to are the starting points.
+func LoadUploadedInventory(files map[string][]byte) (Inventory, error) {
+	l := &sitemapLoader{uploads: files, seen: map[string]bool{}}
+	referenced := map[string]bool{}
+	for _, raw := range files {
+		var sm sitemapXML
+		if text, err := decodeSitemap(raw); err == nil && xml.Unmarshal(text, &sm) == nil {
+			for _, child := range sm.Sitemaps {
+				referenced[path.Base(strings.TrimSpace(child.Loc))] = true
+			}
+		}
+	}
+	var names []string
+	for name := range files {
+		if !referenced[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return Inventory{}, errors.New("every uploaded file is referenced by a sitemap index")
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := l.load(name, files[name], 0); err != nil {
+			return Inventory{}, err
+		}
+	}
+	return l.inventory(""), nil
+}
+
+// CitedKeyword is a keyword a page is cited for
+type CitedKeyword struct {
+	KeywordID string    `json:"keyword_id"`
+	Keyword   string    `json:"keyword"`
+	Locale    string    `json:"locale"`
+	Citations int       `json:"citations"`
+	LastCited time.Time `json:"last_cited"`
+}
+
+// InventoryPageReport is the citation record of one page
+type InventoryPageReport struct {
+	InventoryPage
+	Citations int            `json:"citations"`
+	LastCited time.Time      `json:"last_cited,omitempty"`
+	Keywords  []CitedKeyword `json:"keywords,omitempty"`
+}
+
+// InventoryReport says which of our pages AI Overviews cite
+type InventoryReport struct {
+	Source     string                `json:"source,omitempty"`
+	ImportedAt time.Time             `json:"imported_at"`
+	Latest     bool                  `json:"latest"` // only the latest overview of each keyword counts
+	Pages      int                   `json:"pages"`
+	CitedPages int                   `json:"cited_pages"`
+	Cited      []InventoryPageReport `json:"cited"`
+	NeverCited []InventoryPage       `json:"never_cited"`
+	// Own-domain links that were cited but are not in the inventory,
+	// often redirects or pages missing from the sitemap.
+	Unlisted []string `json:"unlisted,omitempty"`
+}
+
+// BuildInventoryReport matches stored references to the inventory by
+// canonical URL. latest limits it to the current overview of each keyword
+// and locale; otherwise every stored overview counts.
+func BuildInventoryReport(inv Inventory, latest bool) InventoryReport {
+	rep := InventoryReport{Source: inv.Source, ImportedAt: inv.ImportedAt, Latest: latest, Pages: len(inv.Pages)}
+	var snaps []Snapshot
+	if latest {
+		for _, series := range snapshotSeries() {
+			snaps = append(snaps, series[len(series)-1])
+		}
+	} else {
+		snaps = store.Snapshots(nil)
+	}
+
+	byKey := map[string]*InventoryPageReport{}
+	rows := make([]InventoryPageReport, len(inv.Pages))
+	for i, p := range inv.Pages {
+		rows[i].InventoryPage = p
+		byKey[pageKey(p.URL)] = &rows[i]
+	}
+	type kwKey struct{ page, series string }
+	keywords := map[kwKey]*CitedKeyword{}
+	unlisted := map[string]bool{}
+	for _, snap := range snaps {
+		if snap.Overview == nil {
+			continue
+		}
+		cited := map[string]bool{} // a page counts once per overview
+		for _, ref := range snap.Overview.References {
+			key := pageKey(ref.Link)
+			row, ok := byKey[key]
+			if !ok {
+				if isOwnLink(ref.Link) {
+					unlisted[ref.Link] = true
+				}
+				continue
+			}
+			if cited[key] {
+				continue
+			}
+			cited[key] = true
+			row.Citations++
+			if snap.FetchedAt.After(row.LastCited) {
+				row.LastCited = snap.FetchedAt
+			}
+			k := kwKey{key, snap.KeywordID + "|" + snap.Locale.String()}
+			kw, ok := keywords[k]
+			if !ok {
+				kw = &CitedKeyword{KeywordID: snap.KeywordID, Keyword: snap.Keyword, Locale: snap.Locale.String()}
+				keywords[k] = kw
+			}
+			kw.Citations++
+			if snap.FetchedAt.After(kw.LastCited) {
+				kw.LastCited = snap.FetchedAt
+			}
+		}
+	}
+	for k, kw := range keywords {
+		row := byKey[k.page]
+		row.Keywords = append(row.Keywords, *kw)
+	}
+	for _, row := range rows {
+		if row.Citations == 0 {
+			rep.NeverCited = append(rep.NeverCited, row.InventoryPage)
+			continue
+		}
+		sort.Slice(row.Keywords, func(i, j int) bool {
+			if row.Keywords[i].Citations != row.Keywords[j].Citations {
+				return row.Keywords[i].Citations > row.Keywords[j].Citations
+			}
+			return row.Keywords[i].Keyword < row.Keywords[j].Keyword
+		})
+		rep.Cited = append(rep.Cited, row)
+	}
+	rep.CitedPages = len(rep.Cited)
+	sort.SliceStable(rep.Cited, func(i, j int) bool { return rep.Cited[i].Citations > rep.Cited[j].Citations })
+	for link := range unlisted {
+		rep.Unlisted = append(rep.Unlisted, link)
+	}
+	sort.Strings(rep.Unlisted)
+	return rep
+}
+
+// currentInventory returns the stored inventory, importing it from the
+// sitemap setting the first time.
+func currentInventory() (Inventory, error) {
+	if inv := store.Inventory(); inv != nil {
+		return *inv, nil
+	}
+	source := os.Getenv("sitemap")
+	if source == "" {
+		return Inventory{}, nil
+	}
+	inv, err := LoadInventory(source)
+	if err != nil {
+		return inv, err
+	}
+	return inv, store.SetInventory(inv)
+}
+
+// importInventory reads uploaded files or, without any, the source field.
+func importInventory(r *http.Request) (Inventory, error) {
+	if err := r.ParseMultipartForm(maxSitemapSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return Inventory{}, err
+	}
+	var inv Inventory
+	var err error
+	if r.MultipartForm != nil && len(r.MultipartForm.File["file"]) > 0 {
+		files := map[string][]byte{}
+		for _, fh := range r.MultipartForm.File["file"] {
+			f, ferr := fh.Open()
+			raw, ferr := readLimited(f, ferr)
+			if ferr != nil {
+				return Inventory{}, ferr
+			}
+			files[path.Base(fh.Filename)] = raw
+		}
+		inv, err = LoadUploadedInventory(files)
+	} else if source := strings.TrimSpace(r.FormValue("source")); source != "" {
+		if !isWebURL(source) {
+			return Inventory{}, errors.New("the source must be an http or https URL")
+		}
+		inv, err = LoadInventory(source)
+	} else {
+		return Inventory{}, errors.New("upload a sitemap or URL list, or give its URL")
+	}
+	if err != nil {
+		return Inventory{}, err
+	}
+	if len(inv.Pages) == 0 {
+		return Inventory{}, errors.New("no URLs found")
+	}
+	return inv, store.SetInventory(inv)
+}
+
+// refreshInventory reads the stored source again.
+func refreshInventory() (Inventory, error) {
+	inv, err := currentInventory()
+	if err != nil {
+		return inv, err
+	}
+	source := inv.Source
+	if source == "" {
+		source = os.Getenv("sitemap")
+	}
+	if source == "" {
+		return inv, errors.New("the inventory was uploaded; upload the updated file instead")
+	}
+	if inv, err = LoadInventory(source); err != nil {
+		return inv, err
+	}
+	return inv, store.SetInventory(inv)
+}
+
+var inventoryTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Site inventory</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.25rem 0.5rem; border-bottom: 1px solid #ddd; vertical-align: top; }
+		.flag { color: #cf222e; }
+	</style>
+</head>
+<body>
+	<h1>🗺️ Site inventory</h1>
+	<p><a href="/">← Search</a></p>
+	{{with .Error}}<p class="flag">{{.}}</p>{{end}}
+	<form method="POST" action="/inventory" enctype="multipart/form-data" class="text-block">
+		<strong>Import</strong> sitemap.xml, sitemap index (with its sitemaps) or URL list<br/>
+		<input type="file" name="file" multiple />
+		or URL <input type="text" name="source" placeholder="https://example.com/sitemap.xml" size="35" />
+		<button type="submit">Import</button>
+	</form>
+	{{with .Report}}
+		{{if .Pages}}
+			<form method="POST" action="/inventory/refresh" class="text-block">
+				{{.Pages}} pages{{with .Source}} from <code>{{.}}</code>{{end}}, imported {{.ImportedAt.Format "2006-01-02 15:04"}}.
+				{{if .Source}}<button type="submit">Refresh</button>{{end}}
+			</form>
+			<p>
+				{{if .Latest}}<a href="/inventory">All overviews</a> · <b>Current overviews</b>{{else}}<b>All overviews</b> · <a href="/inventory?scope=latest">Current overviews</a>{{end}}
+				· {{.CitedPages}} of {{.Pages}} pages cited
+			</p>
+			<h2>Cited pages</h2>
+			<table>
+				<tr><th>Page</th><th>Citations</th><th>Keywords</th></tr>
+				{{range .Cited}}
+					<tr>
+						<td><a href="{{.URL}}">{{.URL}}</a></td>
+						<td>{{.Citations}}</td>
+						<td>{{range $i, $k := .Keywords}}{{if $i}}, {{end}}<a href="/keywords/{{$k.KeywordID}}/timeline">{{$k.Keyword}}</a> <small>{{$k.Locale}} ×{{$k.Citations}}</small>{{end}}</td>
+					</tr>
+				{{else}}
+					<tr><td colspan="3"><em>None of our pages are cited.</em></td></tr>
+				{{end}}
+			</table>
+			{{with .Unlisted}}
+				<h2>Cited but not in the inventory</h2>
+				<ul>{{range .}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul>
+			{{end}}
+			<h2>Never cited ({{len .NeverCited}})</h2>
+			<ul>{{range .NeverCited}}<li><a href="{{.URL}}">{{.URL}}</a>{{with .LastMod}} <small>{{.}}</small>{{end}}</li>{{end}}</ul>
+		{{end}}
+	{{end}}
+</body>
+</html>
+`
+
+var inventoryTpl = template.Must(template.New("inventory").Parse(inventoryTmpl))
+
+func renderInventory(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	inv, err := currentInventory()
+	if err != nil && msg == "" {
+		msg = err.Error()
+	}
+	rep := BuildInventoryReport(inv, r.FormValue("scope") == "latest")
+	w.WriteHeader(status)
+	if err := inventoryTpl.Execute(w, map[string]any{"Report": rep, "Error": msg}); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func inventoryPage(w http.ResponseWriter, r *http.Request) {
+	renderInventory(w, r, http.StatusOK, "")
+}
+
+func inventoryUpload(w http.ResponseWriter, r *http.Request) {
+	if _, err := importInventory(r); err != nil {
+		renderInventory(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	http.Redirect(w, r, "/inventory", http.StatusSeeOther)
+}
+
+func inventoryRefresh(w http.ResponseWriter, r *http.Request) {
+	if _, err := refreshInventory(); err != nil {
+		renderInventory(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+	http.Redirect(w, r, "/inventory", http.StatusSeeOther)
+}
+
+// apiInventory serves the citation report; ?scope=latest counts only
+// current overviews.
+func apiInventory(w http.ResponseWriter, r *http.Request) {
+	inv, err := currentInventory()
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, BuildInventoryReport(inv, r.FormValue("scope") == "latest"))
+}
+
+func apiImportInventory(w http.ResponseWriter, r *http.Request) {
+	inv, err := importInventory(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pages": len(inv.Pages), "sitemaps": inv.Sitemaps, "source": inv.Source})
+}
+
+func apiRefreshInventory(w http.ResponseWriter, r *http.Request) {
+	inv, err := refreshInventory()
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pages": len(inv.Pages), "sitemaps": inv.Sitemaps, "source": inv.Source})
+}
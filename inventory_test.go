@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(s))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func urlset(links ...string) string {
+	s := `<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+	for _, l := range links {
+		s += "<url><loc> " + l + " </loc><lastmod>2026-10-01</lastmod></url>"
+	}
+	return s + "</urlset>"
+}
+
+func sitemapIndex(locs ...string) string {
+	s := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+	for _, l := range locs {
+		s += "<sitemap><loc>" + l + "</loc></sitemap>"
+	}
+	return s + "</sitemapindex>"
+}
+
+func inventoryURLs(inv Inventory) []string {
+	var out []string
+	for _, p := range inv.Pages {
+		out = append(out, p.URL)
+	}
+	return out
+}
+
+func TestInDir(t *testing.T) {
+	tests := []struct {
+		p    string
+		want bool
+	}{
+		{"/srv/sitemaps", true},
+		{"/srv/sitemaps/sitemap-1.xml", true},
+		{"/srv/sitemaps/posts/../sitemap-2.xml", true},
+		{"/srv/sitemaps/..sitemap.xml", true},
+		{"/srv/sitemaps/../secret.xml", false},
+		{"/srv/sitemaps-old/sitemap.xml", false},
+		{"/srv", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := inDir("/srv/sitemaps", tt.p); got != tt.want {
+			t.Errorf("inDir(%q) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestLoadUploadedInventory(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string][]byte
+		want     []string
+		sitemaps int
+		err      string
+	}{
+		{
+			name:     "urlset",
+			files:    map[string][]byte{"sitemap.xml": []byte(urlset("https://example.com/emas", "https://example.com/kurs"))},
+			want:     []string{"https://example.com/emas", "https://example.com/kurs"},
+			sitemaps: 1,
+		},
+		{
+			name:     "gzip",
+			files:    map[string][]byte{"sitemap.xml.gz": gzipBytes(t, "\xef\xbb\xbf"+urlset("https://example.com/emas"))},
+			want:     []string{"https://example.com/emas"},
+			sitemaps: 1,
+		},
+		{
+			name:     "url list",
+			files:    map[string][]byte{"urls.txt": []byte("# our pages\nhttps://example.com/emas\n\n  http://example.com/kurs  \n")},
+			want:     []string{"https://example.com/emas", "http://example.com/kurs"},
+			sitemaps: 1,
+		},
+		{
+			name:  "url list with a path",
+			files: map[string][]byte{"urls.txt": []byte("https://example.com/emas\n/kurs\n")},
+			err:   "line 2 is not a URL",
+		},
+		{
+			name: "index",
+			files: map[string][]byte{
+				"sitemap_index.xml": []byte(sitemapIndex("https://example.com/sitemaps/posts.xml", "https://example.com/sitemaps/pages.xml.gz", "https://example.com/sitemaps/posts.xml")),
+				"posts.xml":         []byte(urlset("https://example.com/emas", "https://example.com/kurs")),
+				// The same pages spelled differently are listed once.
+				"pages.xml.gz": gzipBytes(t, urlset("https://www.example.com/emas/", "https://example.com/tentang")),
+			},
+			want:     []string{"https://example.com/emas", "https://example.com/kurs", "https://example.com/tentang"},
+			sitemaps: 3,
+		},
+		{
+			name: "index with a missing child",
+			files: map[string][]byte{
+				"sitemap_index.xml": []byte(sitemapIndex("posts.xml")),
+				"other.xml":         []byte(urlset("https://example.com/emas")),
+			},
+			err: "posts.xml: not uploaded and not a URL",
+		},
+		{
+			name: "index loop",
+			files: map[string][]byte{
+				"a.xml": []byte(sitemapIndex("https://example.com/b.xml")),
+				"b.xml": []byte(sitemapIndex("https://example.com/a.xml")),
+			},
+			err: "every uploaded file is referenced",
+		},
+		{
+			name:  "not a sitemap",
+			files: map[string][]byte{"feed.xml": []byte(`<rss><channel/></rss>`)},
+			err:   "expected urlset or sitemapindex, got <rss>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := LoadUploadedInventory(tt.files)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := inventoryURLs(inv); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages = %q, want %q", got, tt.want)
+			}
+			if inv.Sitemaps != tt.sitemaps {
+				t.Errorf("sitemaps = %d, want %d", inv.Sitemaps, tt.sitemaps)
+			}
+		})
+	}
+}
+
+func TestLoadInventoryFromSitemapSetting(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/remote.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(urlset("https://example.com/remote")))
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "sitemaps")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dir, "local.xml"), urlset("https://example.com/local"))
+	write(filepath.Join(root, "secret.xml"), urlset("https://example.com/secret"))
+
+	tests := []struct {
+		name  string
+		index string
+		want  []string
+		err   string
+	}{
+		{"local and remote children", sitemapIndex("local.xml", srv.URL+"/remote.xml"), []string{"https://example.com/local", "https://example.com/remote"}, ""},
+		{"relative escape", sitemapIndex("../secret.xml"), nil, "outside the sitemap directory"},
+		{"absolute escape", sitemapIndex(filepath.Join(root, "secret.xml")), nil, "outside the sitemap directory"},
+		{"remote failure", sitemapIndex(srv.URL + "/missing.xml"), nil, "404"},
+		{"other scheme", sitemapIndex("ftp://example.com/sitemap.xml"), nil, "only http and https"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := filepath.Join(dir, "index.xml")
+			write(index, tt.index)
+			t.Setenv("sitemap", index)
+			inv, err := LoadInventory(index)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := inventoryURLs(inv); !reflect.DeepEqual(got, tt.want) || inv.Source != index {
+				t.Errorf("pages = %q from %q, want %q", got, inv.Source, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("sitemap", filepath.Join(dir, "index.xml"))
+	if _, err := LoadInventory(filepath.Join(root, "secret.xml")); err == nil {
+		t.Error("loaded a local file other than the sitemap setting")
+	}
+}
+
+func TestBuildInventoryReport(t *testing.T) {
+	useTestStore(t)
+	old := ownDomains
+	ownDomains = []string{"www.example.com"}
+	t.Cleanup(func() { ownDomains = old })
+
+	day := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	for _, snap := range []Snapshot{
+		testSnapshot(day,
+			"https://www.example.com/emas#:~:text=harga%20emas",
+			"https://example.com/emas", // same page, counted once per overview
+			"https://example.com/halaman-lama",
+			"https://www.logammulia.com/id"),
+		testSnapshot(day.AddDate(0, 0, 1), "https://EXAMPLE.com/emas", "https://example.com/kurs/"),
+	} {
+		if _, err := store.Add(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inv := Inventory{Source: "https://example.com/sitemap.xml", Pages: []InventoryPage{
+		{URL: "https://example.com/emas/"}, {URL: "https://www.example.com/kurs"}, {URL: "https://example.com/tentang"},
+	}}
+
+	tests := []struct {
+		latest    bool
+		citations map[string]int
+		unlisted  []string
+	}{
+		{true, map[string]int{"https://example.com/emas/": 1, "https://www.example.com/kurs": 1}, nil},
+		{false, map[string]int{"https://example.com/emas/": 2, "https://www.example.com/kurs": 1}, []string{"https://example.com/halaman-lama"}},
+	}
+	for _, tt := range tests {
+		rep := BuildInventoryReport(inv, tt.latest)
+		got := map[string]int{}
+		for _, row := range rep.Cited {
+			got[row.URL] = row.Citations
+			if len(row.Keywords) != 1 || row.Keywords[0].Citations != row.Citations {
+				t.Errorf("latest=%v: %s keywords %+v", tt.latest, row.URL, row.Keywords)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.citations) || rep.CitedPages != len(tt.citations) {
+			t.Errorf("latest=%v: citations %v, want %v", tt.latest, got, tt.citations)
+		}
+		if len(rep.NeverCited) != 1 || rep.NeverCited[0].URL != "https://example.com/tentang" {
+			t.Errorf("latest=%v: never cited %v", tt.latest, rep.NeverCited)
+		}
+		if !reflect.DeepEqual(rep.Unlisted, tt.unlisted) {
+			t.Errorf("latest=%v: unlisted %v, want %v", tt.latest, rep.Unlisted, tt.unlisted)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+var keywordsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Keywords</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.id { color: #888; font-family: monospace; }
+	</style>
+</head>
+<body>
+	<h1>🔑 Canonical Keywords</h1>
+	<p><a href="/">← Search</a></p>
+	{{range .Keywords}}
+		<div class="text-block">
+			<strong>{{.Keyword}}</strong> <span class="id">{{.ID}}</span> · <a href="/keywords/{{.ID}}/timeline">timeline</a> · <a href="/keywords/{{.ID}}/claims">claims</a>
+			<p>{{.Requests}} requests, {{.Snapshots}} snapshots, last seen {{.LastSeen.Format "2006-01-02 15:04"}}</p>
+			<form method="POST" action="/keywords/{{.ID}}/classification">
+				<select name="intent">
+				{{$intent := .Classification.Intent}}
+				{{range $.Intents}}<option {{if eq . $intent}}selected{{end}}>{{.}}</option>{{end}}
+				</select>
+				<select name="topic">
+				{{$topic := .Classification.Topic}}
+				{{range $.Topics}}<option {{if eq . $topic}}selected{{end}}>{{.}}</option>{{end}}
+				</select>
+				<button type="submit">Override</button>
+				{{if .Overridden}}<button type="submit" name="reset" value="1">Reset</button>{{end}}
+			</form>
+			<ul>
+			{{range $raw, $count := .Variants}}
+				<li><code>{{printf "%q" $raw}}</code> × {{$count}}</li>
+			{{end}}
+			</ul>
+		</div>
+	{{else}}
+		<p><em>No keywords yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var keywordsTpl = template.Must(template.New("keywords").Parse(keywordsTmpl))
+
+func keywordsPage(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Keywords []Keyword
+		Intents  []string
+		Topics   []string
+	}{store.Keywords(), intents, topics()}
+	if err := keywordsTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+// overrideClassification handles the override form on the keywords page.
+func overrideClassification(w http.ResponseWriter, r *http.Request) {
+	c := Classification{Intent: r.FormValue("intent"), Topic: r.FormValue("topic")}
+	if r.FormValue("reset") != "" {
+		c = Classification{}
+	}
+	if err := setOverride(r.PathValue("id"), c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, "/keywords", http.StatusSeeOther)
+}
+
+func apiOverrideClassification(w http.ResponseWriter, r *http.Request) {
+	var c Classification
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if err := setOverride(r.PathValue("id"), c); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	k, _ := store.FindKeyword(r.PathValue("id"))
+	writeJSON(w, http.StatusOK, k)
+}
+
+func setOverride(id string, c Classification) error {
+	if _, ok := store.FindKeyword(id); !ok {
+		return errors.New("keyword not found")
+	}
+	if c != (Classification{}) && (!validIntent(c.Intent) || !validTopic(c.Topic)) {
+		return fmt.Errorf("invalid classification %q / %q", c.Intent, c.Topic)
+	}
+	return store.SetOverride(id, c)
+}
+
+func apiKeywords(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, store.Keywords())
+}
+
+func apiKeyword(w http.ResponseWriter, r *http.Request) {
+	k, ok := store.FindKeyword(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "keyword not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, k)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Training text for the language profiles, one file per language code
+//
+//go:embed langdata/*.txt
+var langData embed.FS
+
+// LanguageUnknown is reported for text too short to identify, or in a
+// language without a profile
+const LanguageUnknown = "und"
+
+const (
+	profileSize    = 300 // n-grams kept per profile
+	minLetters     = 12  // shorter text is reported as unknown
+	maxNgramLength = 3
+	// maxDistance is the largest out-of-place distance, as a share of the
+	// worst possible one, still taken as a match. Text further from every
+	// profile is in a language without one and is reported as unknown.
+	maxDistance = 0.56
+)
+
+// malayMarkers are words common in Malay but not in Indonesian. The two
+// share most n-grams, so Malay is only reported when one of them appears.
+var malayMarkers = []string{
+	"selepas", "iaitu", "sahaja", "kerana", "berbanding", "dalam talian", "pelaburan", "dijangka", "memandu", "lesen",
+	"kanak kanak", "kenderaan", "cukai", "syarikat",
+}
+
+// languageProfile maps an n-gram to its frequency rank
+type languageProfile map[string]int
+
+var languageProfiles = loadLanguageProfiles()
+
+func loadLanguageProfiles() map[string]languageProfile {
+	entries, err := langData.ReadDir("langdata")
+	if err != nil {
+		panic(err)
+	}
+	profiles := map[string]languageProfile{}
+	for _, e := range entries {
+		raw, err := langData.ReadFile(path.Join("langdata", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		profiles[strings.TrimSuffix(e.Name(), ".txt")] = buildProfile(string(raw))
+	}
+	return profiles
+}
+
+// buildProfile ranks the most frequent 1- to 3-grams of text, computed per
+// word with space padding (Cavnar & Trenkle).
+func buildProfile(text string) languageProfile {
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		padded := []rune(" " + word + " ")
+		for n := 1; n <= maxNgramLength; n++ {
+			for i := 0; i+n <= len(padded); i++ {
+				g := string(padded[i : i+n])
+				if g != " " {
+					counts[g]++
+				}
+			}
+		}
+	}
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+	p := make(languageProfile, len(grams))
+	for rank, g := range grams {
+		p[g] = rank
+	}
+	return p
+}
+
+// DetectLanguage identifies the language of text by the out-of-place
+// distance between its n-gram profile and each embedded profile. Text
+// close to none of them is unknown.
+func DetectLanguage(text string) string {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters {
+		return LanguageUnknown
+	}
+	doc := buildProfile(text)
+	best, bestDist := LanguageUnknown, -1
+	for lang, p := range languageProfiles {
+		dist := 0
+		for g, rank := range doc {
+			if r, ok := p[g]; ok {
+				dist += abs(rank - r)
+			} else {
+				dist += profileSize
+			}
+		}
+		if bestDist < 0 || dist < bestDist || (dist == bestDist && lang < best) {
+			best, bestDist = lang, dist
+		}
+	}
+	if float64(bestDist) > maxDistance*float64(len(doc)*profileSize) {
+		return LanguageUnknown
+	}
+	if best == "ms" || best == "id" {
+		best = "id"
+		if mentionsAny(NormalizeQuery(text, sentimentOptions), malayMarkers) {
+			best = "ms"
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// PassageLanguage is the detected language of one passage
+type PassageLanguage struct {
+	Block    int    `json:"block"`
+	Item     int    `json:"item,omitempty"`
+	Language string `json:"language"`
+}
+
+// LanguageReport describes the language mix of one overview
+type LanguageReport struct {
+	Requested string             `json:"requested"` // hl sent upstream
+	Dominant  string             `json:"dominant"`
+	Mix       map[string]float64 `json:"mix"` // language -> share of letters
+	Passages  []PassageLanguage  `json:"passages"`
+	Mixed     bool               `json:"mixed"`    // more than one language above 10%
+	Mismatch  bool               `json:"mismatch"` // dominant language differs from hl
+}
+
+// DetectOverviewLanguages detects the language of each passage of ai and
+// compares the result with the requested hl.
+func DetectOverviewLanguages(ai *AIOverview, hl string) LanguageReport {
+	r := LanguageReport{Requested: hl, Dominant: LanguageUnknown, Mix: map[string]float64{}}
+	if ai == nil {
+		return r
+	}
+	letters := map[string]int{}
+	total := 0
+	for _, p := range ai.Passages() {
+		lang := DetectLanguage(p.Text)
+		r.Passages = append(r.Passages, PassageLanguage{Block: p.Block, Item: p.Item, Language: lang})
+		if lang == LanguageUnknown {
+			continue
+		}
+		for _, c := range p.Text {
+			if unicode.IsLetter(c) {
+				letters[lang]++
+				total++
+			}
+		}
+	}
+	best, significant := 0, 0
+	for lang, n := range letters {
+		r.Mix[lang] = float64(n) / float64(total)
+		if r.Mix[lang] >= 0.1 {
+			significant++
+		}
+		if n > best || (n == best && lang < r.Dominant) {
+			best, r.Dominant = n, lang
+		}
+	}
+	r.Mixed = significant > 1
+	// Only the base language counts, so en-GB matches en; without a profile
+	// for it there is nothing to compare against.
+	base, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(hl, "_", "-")), "-")
+	_, profiled := languageProfiles[base]
+	r.Mismatch = r.Dominant != LanguageUnknown && profiled && r.Dominant != base
+	return r
+}
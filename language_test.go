@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Harga emas Antam hari ini naik Rp 5.000 per gram menjadi Rp 1.350.000.", "id"},
+		{"Gejala tipes biasanya berupa demam yang naik turun, sakit perut, dan badan terasa lemas.", "id"},
+		{"Anda bisa membayar tagihan listrik melalui aplikasi bank, minimarket, atau kantor pos terdekat.", "id"},
+		{"Kerajaan akan memberikan bantuan kepada rakyat yang memerlukan selepas banjir besar minggu lepas.", "ms"},
+		{"Anda boleh memohon lesen memandu secara dalam talian melalui laman web JPJ.", "ms"},
+		{"Aku arep lunga menyang pasar karo ibu, tuku sayuran lan iwak kanggo mangan awan.", "jv"},
+		{"How to register for health insurance online using the mobile app.", "en"},
+		{"Der Goldpreis steigt weiter, weil Anleger nach sicheren Häfen suchen.", "de"},
+		// No profile: too far from every language to guess.
+		{"Il prezzo dell'oro è salito oggi rispetto a ieri, secondo gli analisti del mercato.", LanguageUnknown},
+		{"Pogoda w Warszawie jutro będzie słoneczna, a temperatura wzrośnie do dwudziestu stopni.", LanguageUnknown},
+		{"東京の天気は明日晴れるでしょう。気温は二十度まで上がる見込みです。", LanguageUnknown},
+		{"Rp 5.000", LanguageUnknown},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
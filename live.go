@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Live event types
+const (
+	EventStarted   = "started"
+	EventFallback  = "fallback" // overview needed the page_token follow-up call
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+	EventChanged   = "changed" // overview differs from the previous snapshot
+	EventStats     = "stats"
+)
+
+// LiveEvent is pushed to dashboard clients as JSON
+type LiveEvent struct {
+	Type      string    `json:"type"`
+	At        time.Time `json:"at"`
+	KeywordID string    `json:"keyword_id,omitempty"`
+	Keyword   string    `json:"keyword,omitempty"`
+	Locale    string    `json:"locale,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Upstream  int64     `json:"upstream"` // SerpAPI lookups in flight
+	Queue     int64     `json:"queue"`    // batch items waiting
+}
+
+var (
+	upstreamInFlight atomic.Int64
+	queueDepth       atomic.Int64
+)
+
+// liveHub fans events out to connected dashboards and keeps the most
+// recent ones for clients that connect later
+type liveHub struct {
+	mu     sync.Mutex
+	subs   map[chan LiveEvent]bool
+	recent []LiveEvent
+}
+
+var hub = &liveHub{subs: map[chan LiveEvent]bool{}}
+
+func (h *liveHub) subscribe() (chan LiveEvent, []LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan LiveEvent, 64)
+	h.subs[ch] = true
+	return ch, append([]LiveEvent(nil), h.recent...)
+}
+
+func (h *liveHub) unsubscribe(ch chan LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, ch)
+}
+
+// publish never blocks: a dashboard that falls behind misses events.
+func (h *liveHub) publish(e LiveEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recent = append(h.recent, e)
+	if len(h.recent) > 50 {
+		h.recent = h.recent[len(h.recent)-50:]
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// lookupEvent publishes an event about query in loc.
+func lookupEvent(typ, query string, loc Locale, msg string) {
+	canonical, id := CanonicalKeyword(query)
+	hub.publish(LiveEvent{Type: typ, At: time.Now(), KeywordID: id, Keyword: canonical, Locale: loc.String(), Message: redactKeys(msg),
+		Upstream: upstreamInFlight.Load(), Queue: queueDepth.Load()})
+}
+
+// publishChange compares snap with the previous lookup of its keyword and
+// locale and publishes the difference, if any.
+func publishChange(snap Snapshot) {
+	series := keywordSeries(snap.KeywordID, snap.Locale)
+	if len(series) < 2 || series[len(series)-1].ID != snap.ID {
+		return
+	}
+	prev := series[len(series)-2]
+	if overviewFingerprint(prev.Overview) == overviewFingerprint(snap.Overview) {
+		return
+	}
+	d := DiffOverviews(prev.Overview, snap.Overview)
+	hub.publish(LiveEvent{Type: EventChanged, At: time.Now(), KeywordID: snap.KeywordID, Keyword: snap.Keyword, Locale: snap.Locale.String(),
+		Message:  fmt.Sprintf("+%d/−%d references, %.0f%% text change", len(d.AddedReferences), len(d.RemovedReferences), d.TextChange*100),
+		Upstream: upstreamInFlight.Load(), Queue: queueDepth.Load()})
+	go notifyOverviewChange(snap, d)
+}
+
+// liveFilter selects events by project and locale
+type liveFilter struct {
+	project *Project
+	locale  string
+}
+
+func (f liveFilter) match(e LiveEvent) bool {
+	if e.Type == EventStats {
+		return true
+	}
+	if f.locale != "" && e.Locale != f.locale {
+		return false
+	}
+	return f.project == nil || f.project.KeywordIDs == nil || f.project.KeywordIDs[e.KeywordID]
+}
+
+// liveSocket streams events over a WebSocket, filtered by ?project= and ?locale=.
+func liveSocket(w http.ResponseWriter, r *http.Request) {
+	var f liveFilter
+	if name := r.URL.Query().Get("project"); name != "" {
+		p, ok := findProject(name)
+		if !ok {
+			http.Error(w, "unknown project", http.StatusNotFound)
+			return
+		}
+		f.project = &p
+	}
+	f.locale = r.URL.Query().Get("locale")
+
+	serveWebSocket(w, r, func(ws *websocket.Conn) {
+		streamLiveEvents(ws, f)
+	})
+}
+
+// streamLiveEvents writes matching events to ws until it goes away.
+func streamLiveEvents(ws *websocket.Conn, f liveFilter) {
+	// Clients send nothing; reading notices when they close.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+	ch, recent := hub.subscribe()
+	defer hub.unsubscribe(ch)
+
+	send := func(e LiveEvent) bool {
+		if !f.match(e) {
+			return true
+		}
+		raw, _ := json.Marshal(e)
+		ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return websocket.Message.Send(ws, string(raw)) == nil
+	}
+	for _, e := range recent {
+		if !send(e) {
+			return
+		}
+	}
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-ch:
+			if !send(e) {
+				return
+			}
+		case <-ticker.C:
+			if !send(LiveEvent{Type: EventStats, At: time.Now(), Upstream: upstreamInFlight.Load(), Queue: queueDepth.Load()}) {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+var liveTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Live</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.stats span { display: inline-block; margin-right: 2rem; font-size: 1.5rem; }
+		#events div { padding: 0.25rem 0; border-bottom: 1px solid #eee; }
+		.started { color: #57606a; } .fallback { color: #9a6700; } .completed { color: #1a7f37; } .failed { color: #cf222e; } .changed { color: #8250df; font-weight: bold; }
+		#status.down { color: #cf222e; }
+	</style>
+</head>
+<body>
+	<h1>📡 Live</h1>
+	<p><a href="/">← Search</a> · <span id="status">connecting…</span></p>
+	<form class="text-block" id="filters">
+		Project <select name="project"><option value="">all</option>{{range .}}<option>{{.}}</option>{{end}}</select>
+		Locale <input type="text" name="locale" placeholder="hl-gl" size="6" />
+		<button type="submit">Apply</button>
+	</form>
+	<div class="stats text-block">
+		<span>⬆️ <b id="upstream">0</b> upstream</span>
+		<span>⏳ <b id="queue">0</b> queued</span>
+	</div>
+	<h2>Recent changes</h2>
+	<div id="changes"><p><em>None yet.</em></p></div>
+	<h2>Events</h2>
+	<div id="events"></div>
+	<script>
+		const form = document.getElementById("filters");
+		const status = document.getElementById("status");
+		let socket, retry = 0;
+
+		function line(e) {
+			const div = document.createElement("div");
+			div.className = e.type;
+			div.textContent = new Date(e.at).toLocaleTimeString() + " " + e.type + " · " + (e.keyword || "") + " " + (e.locale || "") + (e.message ? " · " + e.message : "");
+			return div;
+		}
+
+		function connect() {
+			const params = new URLSearchParams(new FormData(form));
+			const proto = location.protocol === "https:" ? "wss:" : "ws:";
+			socket = new WebSocket(proto + "//" + location.host + "/live/ws?" + params);
+			socket.onopen = () => { retry = 0; status.textContent = "connected"; status.className = ""; };
+			socket.onmessage = msg => {
+				const e = JSON.parse(msg.data);
+				document.getElementById("upstream").textContent = e.upstream;
+				document.getElementById("queue").textContent = e.queue;
+				if (e.type === "stats") return;
+				const events = document.getElementById("events");
+				events.prepend(line(e));
+				while (events.children.length > 200) events.lastChild.remove();
+				if (e.type === "changed") {
+					const changes = document.getElementById("changes");
+					if (changes.querySelector("p")) changes.innerHTML = "";
+					changes.prepend(line(e));
+					while (changes.children.length > 20) changes.lastChild.remove();
+				}
+			};
+			socket.onclose = () => {
+				// back off up to 30s between reconnection attempts
+				const delay = Math.min(30000, 1000 * 2 ** retry++);
+				status.textContent = "disconnected, retrying in " + delay / 1000 + "s";
+				status.className = "down";
+				setTimeout(connect, delay);
+			};
+		}
+
+		form.addEventListener("submit", e => {
+			e.preventDefault();
+			document.getElementById("events").innerHTML = "";
+			socket.onclose = null;
+			socket.close();
+			connect();
+		});
+		connect();
+	</script>
+</body>
+</html>
+`
+
+var liveTpl = template.Must(template.New("live").Parse(liveTmpl))
+
+func livePage(w http.ResponseWriter, r *http.Request) {
+	names := []string{"tracked"}
+	for name := range projects {
+		if name != "all" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if err := liveTpl.Execute(w, names); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestLiveSocketOrigin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(liveSocket))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/live/ws"
+
+	if _, err := websocket.Dial(wsURL, "", "https://evil.example"); err == nil {
+		t.Fatal("cross-origin handshake accepted")
+	}
+
+	ws, err := websocket.Dial(wsURL, "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	hub.publish(LiveEvent{Type: EventStarted, Keyword: "harga emas"})
+	for {
+		var raw string
+		if err := websocket.Message.Receive(ws, &raw); err != nil {
+			t.Fatal(err)
+		}
+		var e LiveEvent
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Type == EventStarted && e.Keyword == "harga emas" {
+			return
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// overviewCache holds recent snapshots keyed by canonical keyword ID and locale
+type overviewCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	snapshot Snapshot
+	expires  time.Time
+}
+
+func newOverviewCache(ttl time.Duration) *overviewCache {
+	return &overviewCache{ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+func (c *overviewCache) Get(key string) (Snapshot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return Snapshot{}, false
+	}
+	return e.snapshot, true
+}
+
+// Set caches snap for ttl, which is normally c.ttl.
+func (c *overviewCache) Set(key string, snap Snapshot, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{snapshot: snap, expires: time.Now().Add(ttl)}
+}
+
+// TTL is the lifetime of entries set by interactive lookups.
+func (c *overviewCache) TTL() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ttl
+}
+
+// SetTTL changes the lifetime of entries set from now on.
+func (c *overviewCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// upstreamLimiter caps concurrent SerpAPI lookups; a limit of 0 means none
+type upstreamLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func newUpstreamLimiter() *upstreamLimiter {
+	l := &upstreamLimiter{}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *upstreamLimiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.limit > 0 && l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+func (l *upstreamLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.cond.Broadcast()
+}
+
+// SetLimit applies a new limit; lookups already running are not interrupted.
+func (l *upstreamLimiter) SetLimit(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = n
+	l.cond.Broadcast()
+}
+
+// Locale is the set of localisation parameters sent upstream
+type Locale struct {
+	HL       string `json:"hl"`
+	GL       string `json:"gl"`
+	Location string `json:"location,omitempty"`
+}
+
+// defaultLocale is the built-in default, and the locale of history recorded
+// before locales existed. Requests use the default from the settings.
+var defaultLocale = Locale{HL: "id", GL: "id", Location: "Indonesia"}
+
+func (l Locale) String() string {
+	return l.HL + "-" + l.GL
+}
+
+// localeFromRequest reads optional hl and gl parameters on top of the
+// default locale. The default location only applies to the default gl.
+func localeFromRequest(r *http.Request) Locale {
+	return localeFrom(r.URL.Query().Get("hl"), r.URL.Query().Get("gl"))
+}
+
+func localeFrom(hl, gl string) Locale {
+	loc := currentSettings().DefaultLocale()
+	if hl != "" {
+		loc.HL = hl
+	}
+	if gl != "" && gl != loc.GL {
+		loc.GL, loc.Location = gl, ""
+	}
+	return loc
+}
+
+var (
+	store    *Store
+	cache    = newOverviewCache(time.Hour)
+	upstream = newUpstreamLimiter()
+)
+
+// lookup resolves query through the cache and falls back to SerpAPI,
+// recording the request and any upstream result in the store.
+// The raw query is always what gets sent upstream.
+func lookup(query string, loc Locale) (Snapshot, error) {
+	return lookupAs(nil, query, loc)
+}
+
+// lookupAs is lookup on behalf of an application user, whose daily budget
+// limits how many requests may go upstream. Cache hits are always served.
+func lookupAs(u *User, query string, loc Locale) (Snapshot, error) {
+	canonical, id := CanonicalKeyword(query)
+	req := RequestLog{KeywordID: id, Keyword: canonical, Query: query, Locale: loc, At: time.Now()}
+
+	key := id + "|" + loc.String()
+	snap, cached := cache.Get(key)
+	req.Cached = cached
+	var err error
+	if u != nil {
+		req.UserID = u.ID
+		err = store.LogUserRequest(req)
+		if errors.Is(err, errBudgetExceeded) || errors.Is(err, errUserNotFound) {
+			return Snapshot{}, err
+		}
+	} else {
+		err = store.LogRequest(req)
+	}
+	if err != nil {
+		log.Println("❌ failed to log request:", err)
+	}
+	if cached {
+		return snap, nil
+	}
+	return refresh(query, loc, cache.TTL())
+}
+
+// refresh fetches query from SerpAPI, stores the snapshot and caches it for
+// ttl. It does not log a request, so background work can use it without
+// skewing request statistics.
+func refresh(query string, loc Locale, ttl time.Duration) (Snapshot, error) {
+	canonical, id := CanonicalKeyword(query)
+	upstream.acquire()
+	upstreamInFlight.Add(1)
+	lookupEvent(EventStarted, query, loc, "")
+	rec := &upstreamRecorder{}
+	ai, features, err := fetchAIOverview(query, loc, rec)
+	upstreamInFlight.Add(-1)
+	upstream.release()
+	snap := Snapshot{
+		KeywordID:      id,
+		Keyword:        canonical,
+		Query:          query,
+		Locale:         loc,
+		FetchedAt:      time.Now(),
+		Features:       features,
+		Classification: Classify(canonical, features),
+		YMYL:           DetectYMYL(query, ai),
+	}
+	if err != nil {
+		snap.Error = redactKeys(err.Error())
+	} else {
+		snap.Overview = ai
+	}
+	snap, serr := store.Add(snap)
+	if serr != nil {
+		log.Println("❌ failed to store snapshot:", serr)
+	} else {
+		if ex := rec.Exchanges(); len(ex) > 0 {
+			if err := saveExchanges(snap.ID, ex); err != nil {
+				log.Println("❌ failed to store upstream exchanges:", err)
+			}
+		}
+		if gitHistory != nil {
+			gitHistory.Enqueue(snap)
+		}
+	}
+	if err != nil && !errors.Is(err, errNoOverview) {
+		lookupEvent(EventFailed, query, loc, err.Error())
+	} else {
+		lookupEvent(EventCompleted, query, loc, "")
+		publishChange(snap)
+	}
+	if err == nil {
+		cache.Set(id+"|"+loc.String(), snap, ttl)
+		checkClaimChanges(snap)
+	}
+	return snap, err
+}
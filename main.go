@@ -7,7 +7,9 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -66,34 +68,24 @@ var tmpl = `
 </head>
 <body>
 	<h1>🔍 Google AI Overview via SerpAPI</h1>
+	<p><a href="/keywords">Keywords</a> · <a href="/analytics">Analytics</a> · <a href="/brands">Brands</a> · <a href="/compliance">Compliance</a> · <a href="/volatility">Volatility</a> · <a href="/entities">Entities</a> · <a href="/import/search-console">Search Console</a> · <a href="/inventory">Inventory</a> · <a href="/features">Page features</a> · <a href="/cocitation">Co-citation</a> · <a href="/embed/tokens">Embed</a> · <a href="/users">Users</a> · <a href="/admin/credits">Credits</a> · <a href="/admin/prewarm">Prewarm</a> · <a href="/live">Live</a> · <a href="/admin/settings">Settings</a> · <a href="/evidence">Evidence</a> · <a href="/alerts">Alerts</a></p>
 	<form method="GET">
-		<input type="text" name="q" placeholder="Enter a search keyword..." style="width:80%;" value="{{.Query}}" required />
+		<input type="text" name="q" placeholder="Enter a search keyword..." style="width:65%;" value="{{.Query}}" required />
+		<input type="text" name="hl" size="2" title="hl" value="{{.Locale.HL}}" />
+		<input type="text" name="gl" size="2" title="gl" value="{{.Locale.GL}}" />
 		<button type="submit">Search</button>
 	</form>
+	{{with .Classification}}
+		<p>Intent: <strong>{{.Intent}}</strong> · Topic: <strong>{{.Topic}}</strong></p>
+	{{end}}
+	{{if .YMYL}}
+		<p>⚠️ YMYL topic: <strong>{{range $i, $c := .YMYL}}{{if $i}}, {{end}}{{$c}}{{end}}</strong> — see <a href="/compliance">compliance checklist</a></p>
+	{{end}}
+	{{with .Language}}{{if .Dominant | ne "und"}}
+		<p>Language: <strong>{{.Dominant}}</strong>{{if .Mixed}} (mixed){{end}}{{if .Mismatch}} ⚠️ requested hl={{.Requested}}{{end}}</p>
+	{{end}}{{end}}
 	{{if .AI}}
-		<h2>🧠 AI Overview Result</h2>
-		{{range .AI.TextBlocks}}
-			<div class="text-block">
-				<strong>{{.Type | title}}</strong>
-				<p>{{.Snippet}}</p>
-				{{if .List}}
-					<ul>
-					{{range .List}}
-						<li><strong>{{.Title}}</strong> — {{.Snippet}}</li>
-					{{end}}
-					</ul>
-				{{end}}
-			</div>
-		{{end}}
-		<h2>🧠 References</h2>
-		{{range .AI.References}}
-			<div class="text-block">
-			<strong>title: <a href="{{.Link}}">{{.Title}}</a></strong>
-			<p>Snippet: {{.Snippet}}</p>
-			<p>Source: {{.Source}}</p>
-			<p>Index: {{.Index}}</p>
-			</div>
-		{{end}}
+		{{template "overview" .AI}}
 	{{else if .Query}}
 		<p><em>No AI Overview found for: {{.Query}}</em></p>
 	{{end}}
@@ -101,27 +93,128 @@ var tmpl = `
 </html>
 `
 
+// Overview partial, shared by every page that renders an AIOverview
+var overviewTmpl = `
+{{define "overview"}}
+	<h2>🧠 AI Overview Result</h2>
+	{{range .TextBlocks}}
+		<div class="text-block">
+			<strong>{{.Type | title}}</strong>
+			<p>{{.Snippet}}</p>
+			{{if .List}}
+				<ul>
+				{{range .List}}
+					<li><strong>{{.Title}}</strong> — {{.Snippet}}</li>
+				{{end}}
+				</ul>
+			{{end}}
+		</div>
+	{{end}}
+	<h2>🧠 References</h2>
+	{{range .References}}
+		<div class="text-block">
+		<strong>title: <a href="{{.Link}}">{{.Title}}</a></strong>
+		<p>Snippet: {{.Snippet}}</p>
+		<p>Source: {{.Source}}</p>
+		<p>Index: {{.Index}}</p>
+		</div>
+	{{end}}
+{{end}}
+`
+
+// errNoOverview is returned when Google shows no AI Overview for the query
+var errNoOverview = errors.New("ai overview not found")
+
 // Template func map
 var funcMap = template.FuncMap{
 	"title": strings.Title,
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
 }
 
 func main() {
-	tpl := template.Must(template.New("index").Funcs(funcMap).Parse(tmpl))
+	if len(os.Args) > 1 {
+		run := runBackupCommand
+		switch os.Args[1] {
+		case "warc":
+			run = runWARCCommand
+		case "evidence":
+			run = runEvidenceCommand
+		}
+		if err := run(os.Args[1:]); err != nil {
+			log.Fatal("❌ ", err)
+		}
+		return
+	}
+
+	tpl := template.Must(template.Must(template.New("index").Funcs(funcMap).Parse(tmpl)).Parse(overviewTmpl))
+
+	var err error
+	store, err = OpenStore(filepath.Join(dataDir(), "history.json"))
+	if err != nil {
+		log.Fatal("❌ failed to open store: ", err)
+	}
+	settings.Subscribe(func(s Settings) { cache.SetTTL(s.cacheTTL()) })
+	settings.Subscribe(func(s Settings) { upstream.SetLimit(s.MaxUpstream) })
+	if err := settings.Load(filepath.Join(dataDir(), "settings.json")); err != nil {
+		log.Fatal("❌ failed to load settings: ", err)
+	}
+	go settings.Watch(5 * time.Second)
+
+	embedSecret, err = loadEmbedSecret()
+	if err != nil {
+		log.Fatal("❌ failed to load embed secret: ", err)
+	}
+	evidenceKey, err = loadEvidenceKey()
+	if err != nil {
+		log.Fatal("❌ failed to load evidence key: ", err)
+	}
+	if len(serpAPIKeys()) > 0 {
+		go syncCreditsLoop()
+	}
+	if c, err := prewarmConfig(); err == nil {
+		go prewarmLoop(c)
+	} else if os.Getenv("prewarm_hours") != "" {
+		log.Println("❌ prewarming disabled:", err)
+	}
+	if dir := os.Getenv("git_history"); dir != "" {
+		gitHistory, err = OpenGitSink(dir)
+		if err != nil {
+			log.Fatal("❌ failed to open git history: ", err)
+		}
+		go gitHistory.Run()
+	}
+	telegramBot = newTelegramBot()
+	if telegramBot != nil && os.Getenv("telegram_webhook_secret") == "" {
+		log.Println("🤖 Telegram bot polling for updates")
+		go telegramBot.Poll()
+	}
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		query := r.URL.Query().Get("q")
+		loc := localeFromRequest(r)
 		data := struct {
-			Query string
-			AI    *AIOverview
-		}{Query: query}
+			Query          string
+			Locale         Locale
+			AI             *AIOverview
+			Classification *Classification
+			YMYL           []string
+			Language       *LanguageReport
+		}{Query: query, Locale: loc}
 
 		if query != "" {
-			ai, err := fetchAIOverview(query)
+			snap, err := lookup(query, loc)
 			if err != nil {
 				log.Println("❌", err)
 			} else {
-				data.AI = ai
+				data.AI = snap.Overview
+				lr := DetectOverviewLanguages(snap.Overview, loc.HL)
+				data.Language = &lr
+			}
+			data.YMYL = snap.YMYL
+			if k, ok := store.FindKeyword(snap.KeywordID); ok {
+				data.Classification = &k.Classification
 			}
 		}
 
@@ -131,79 +224,183 @@ func main() {
 		}
 	})
 
+	http.HandleFunc("GET /keywords", keywordsPage)
+	http.HandleFunc("GET /api/v1/keywords", apiKeywords)
+	http.HandleFunc("GET /api/v1/keywords/{id}", apiKeyword)
+	http.HandleFunc("POST /keywords/{id}/classification", overrideClassification)
+	http.HandleFunc("PUT /api/v1/keywords/{id}/classification", apiOverrideClassification)
+	http.HandleFunc("GET /analytics", analyticsPage)
+	http.HandleFunc("GET /api/v1/analytics", apiAnalytics)
+	http.HandleFunc("GET /brands", brandsPage)
+	http.HandleFunc("GET /api/v1/brands/sentiment", apiBrandSentiment)
+	http.HandleFunc("GET /compliance", compliancePage)
+	http.HandleFunc("GET /api/v1/compliance", apiCompliance)
+	http.HandleFunc("GET /api/v1/snapshots/{id}", apiSnapshot)
+	http.HandleFunc("GET /export/snapshots.csv", exportSnapshotsCSV)
+	http.HandleFunc("GET /export/snapshots.warc.gz", exportWARC)
+	http.HandleFunc("POST /api/v1/warc/import", apiImportWARC)
+	http.HandleFunc("GET /evidence", evidencePage)
+	http.HandleFunc("POST /evidence", captureEvidenceForm)
+	http.HandleFunc("GET /evidence/{id}", evidenceDetail)
+	http.HandleFunc("POST /evidence/verify", verifyEvidenceForm)
+	http.HandleFunc("POST /api/v1/evidence", apiCaptureEvidence)
+	http.HandleFunc("GET /api/v1/evidence/key", apiEvidenceKey)
+	http.HandleFunc("GET /api/v1/evidence/{id}", apiEvidence)
+	http.HandleFunc("POST /api/v1/evidence/verify", apiVerifyEvidence)
+	http.HandleFunc("GET /keywords/{id}/timeline", timelinePage)
+	http.HandleFunc("GET /api/v1/keywords/{id}/timeline", apiTimeline)
+	http.HandleFunc("GET /api/v1/keywords/{id}/as-of", apiAsOf)
+	http.HandleFunc("GET /keywords/{id}/claims", claimsPage)
+	http.HandleFunc("GET /api/v1/keywords/{id}/claims", apiClaims)
+	http.HandleFunc("GET /volatility", volatilityPage)
+	http.HandleFunc("GET /api/v1/volatility", apiVolatility)
+	http.HandleFunc("GET /entities", entitiesPage)
+	http.HandleFunc("POST /entities", createEntity)
+	http.HandleFunc("GET /entities/{id}", entityPage)
+	http.HandleFunc("POST /entities/{id}/delete", deleteEntity)
+	http.HandleFunc("GET /api/v1/entities", apiEntities)
+	http.HandleFunc("POST /api/v1/entities", apiSaveEntity)
+	http.HandleFunc("GET /api/v1/entities/{id}", apiEntityReport)
+	http.HandleFunc("PUT /api/v1/entities/{id}", apiSaveEntity)
+	http.HandleFunc("DELETE /api/v1/entities/{id}", apiDeleteEntity)
+	http.HandleFunc("GET /import/search-console", searchConsolePage)
+	http.HandleFunc("POST /import/search-console", searchConsoleUpload)
+	http.HandleFunc("POST /api/v1/import/search-console", apiSearchConsoleImport)
+	http.HandleFunc("GET /api/v1/reports/search-console", apiSearchConsoleReport)
+	http.HandleFunc("GET /inventory", inventoryPage)
+	http.HandleFunc("POST /inventory", inventoryUpload)
+	http.HandleFunc("POST /inventory/refresh", inventoryRefresh)
+	http.HandleFunc("GET /api/v1/inventory", apiInventory)
+	http.HandleFunc("POST /api/v1/inventory", apiImportInventory)
+	http.HandleFunc("POST /api/v1/inventory/refresh", apiRefreshInventory)
+	http.HandleFunc("GET /features", featuresPage)
+	http.HandleFunc("POST /features/fetch", fetchFeaturesForm)
+	http.HandleFunc("GET /api/v1/features/report", apiFeatureReport)
+	http.HandleFunc("POST /api/v1/features/fetch", apiFetchFeatures)
+	http.HandleFunc("GET /cocitation", coCitationPage)
+	http.HandleFunc("GET /api/v1/cocitation", apiCoCitation)
+	http.HandleFunc("POST /api/v1/cocitation", apiBatchCoCitation)
+	http.HandleFunc("GET /export/cocitation.graphml", exportCoCitationGraphML)
+	http.HandleFunc("GET /export/cocitation.dot", exportCoCitationDOT)
+	http.HandleFunc("GET /api/v1/tracked", apiTracked)
+	http.HandleFunc("GET /embed", embedPage)
+	http.HandleFunc("GET /embed.js", embedScript)
+	http.HandleFunc("GET /embed/tokens", embedAdminPage)
+	http.HandleFunc("POST /embed/tokens", embedAdminPage)
+	http.HandleFunc("POST /api/v1/embed/tokens", apiIssueEmbedToken)
+	http.HandleFunc("GET /badge/{project}/{target...}", badge)
+	http.HandleFunc("GET /api/v1/badges/{project}/{target...}", apiBadge)
+	http.HandleFunc("GET /users", usersPage)
+	http.HandleFunc("POST /users", createUser)
+	http.HandleFunc("GET /api/v1/users", apiUsers)
+	http.HandleFunc("POST /api/v1/users", apiSaveUser)
+	http.HandleFunc("PUT /api/v1/users/{id}", apiSaveUser)
+	http.HandleFunc("POST /users/{id}/erase", eraseUserForm)
+	http.HandleFunc("GET /api/v1/users/{id}/export", apiUserExport)
+	http.HandleFunc("POST /api/v1/users/{id}/erase", apiUserErase)
+	http.HandleFunc("GET /api/v1/audit", apiAudit)
+	if os.Getenv("telegram_webhook_secret") != "" {
+		http.HandleFunc("POST /telegram/webhook", telegramWebhook)
+	}
+	http.HandleFunc("GET /admin/credits", creditsPage)
+	http.HandleFunc("POST /admin/credits", syncCreditsNow)
+	http.HandleFunc("GET /api/v1/credits", apiCredits)
+	http.HandleFunc("GET /admin/prewarm", prewarmPage)
+	http.HandleFunc("POST /admin/prewarm", prewarmNow)
+	http.HandleFunc("GET /api/v1/prewarm", apiPrewarm)
+	http.HandleFunc("GET /live", livePage)
+	http.HandleFunc("GET /live/ws", liveSocket)
+	http.HandleFunc("GET /admin/settings", settingsPage)
+	http.HandleFunc("POST /admin/settings", saveSettings)
+	http.HandleFunc("POST /admin/settings/rollback/{version}", rollbackSettings)
+	http.HandleFunc("GET /api/v1/settings", apiSettings)
+	http.HandleFunc("PUT /api/v1/settings", apiUpdateSettings)
+	http.HandleFunc("GET /api/v1/settings/history", apiSettingsHistory)
+	http.HandleFunc("POST /api/v1/settings/rollback/{version}", apiRollbackSettings)
+	http.HandleFunc("GET /admin/backup", adminBackup)
+	http.HandleFunc("POST /api/v1/backup/verify", apiVerifyBackup)
+	http.HandleFunc("GET /alerts", alertsPage)
+	http.HandleFunc("GET /api/v1/alerts", apiAlerts)
+
 	log.Println("🚀 Server running at http://localhost:8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
-func fetchAIOverview(query string) (*AIOverview, error) {
-	apiKey := os.Getenv("api_key") // 🛑 Replace with your key
+// fetchAIOverview looks the query up on SerpAPI. When rec is set, every
+// call is recorded through it.
+func fetchAIOverview(query string, loc Locale, rec *upstreamRecorder) (*AIOverview, SERPFeatures, error) {
+	apiKey := pickAPIKey() // 🛑 set api_key, comma separated for several keys
 
 	// Step 1: Try with regular Google search engine
 	param := map[string]string{
 		"engine":        "google",
 		"q":             query,
-		"location":      "Indonesia",
+		"location":      loc.Location,
 		"google_domain": "google.com",
-		"gl":            "id",
-		"hl":            "id",
+		"gl":            loc.GL,
+		"hl":            loc.HL,
+	}
+	if loc.Location == "" {
+		delete(param, "location")
 	}
 
-	fmt.Printf("params query: %+v\n", param)
-	fmt.Printf("print datenow 1: %+v\n", time.Now())
 	search := g.NewGoogleSearch(param, apiKey)
-	fmt.Printf("print datenow 2: %+v\n", time.Now())
+	search.HttpSearch.Timeout = currentSettings().upstreamTimeout()
+	if rec != nil {
+		search.HttpSearch.Transport = rec
+	}
 	results, err := search.GetJSON()
+	countSerpCall(apiKey)
 	if err != nil {
-		fmt.Printf("print datenow 3: %+v\n", time.Now())
-		fmt.Printf("error when get json search %+v", err)
-		return &AIOverview{}, err
+		err = serpAPIError(err)
+		log.Println("❌ search request failed:", err)
+		return &AIOverview{}, SERPFeatures{}, err
 	}
 
-	fmt.Printf("print datenow 4: %+v\n", time.Now())
+	features := serpFeatures(results)
 
 	// Step 2: Try direct AI Overview
 	aiOverviewRaw, ok := results["ai_overview"]
 	if !ok {
-		fmt.Printf("print datenow 5: %+v\n", time.Now())
 		log.Print("❌ AI Overview not found for this query")
-		return &AIOverview{}, errors.New("ai overview not found")
+		return &AIOverview{}, features, errNoOverview
 	}
 
-	fmt.Printf("print datenow 6: %+v %+v\n", time.Now(), aiOverviewRaw)
-
 	jsonBytes, _ := json.Marshal(aiOverviewRaw)
-	fmt.Printf("print datenow 7: %+v %+v\n", time.Now(), aiOverviewRaw)
 
 	var overview AIOverview
 	err = json.Unmarshal(jsonBytes, &overview)
-	fmt.Printf("print datenow 8: %+v %+v\n", time.Now(), aiOverviewRaw)
 	if err == nil && !overview.IsEmpty() {
-		fmt.Printf("print datenow 9: %+v %+v %+v\n", time.Now(), aiOverviewRaw, overview)
-		return &overview, nil
+		return &overview, features, nil
 	}
 
 	// fallback to use page_token
 	var meta SearchMetadata
-	fmt.Printf("print datenow 9: %+v %+v\n", time.Now(), aiOverviewRaw)
 	if err := json.Unmarshal(jsonBytes, &meta); err != nil {
-		fmt.Printf("print datenow 10: %+v %+v\n", time.Now(), aiOverviewRaw)
-		return &AIOverview{}, err
+		return &AIOverview{}, features, err
 	}
 
+	lookupEvent(EventFallback, query, loc, "")
 	fmt.Println("✅ page_token:", meta.PageToken)
 	fmt.Println("🔗 serpapi_link:", meta.SerpapiLink)
 
 	search = g.NewGoogleSearch(map[string]string{
 		"engine":     "google_ai_overview",
 		"page_token": meta.PageToken,
-		"hl":         "id",
-		"gl":         "id",
+		"hl":         loc.HL,
+		"gl":         loc.GL,
 	}, apiKey)
+	search.HttpSearch.Timeout = currentSettings().upstreamTimeout()
+	if rec != nil {
+		search.HttpSearch.Transport = rec
+	}
 
 	results, err = search.GetJSON()
+	countSerpCall(apiKey)
 	if err != nil {
+		err = serpAPIError(err)
 		fmt.Println("Failed to fetch AI Overview detail:", err)
-		return &AIOverview{}, err
+		return &AIOverview{}, features, err
 	}
 
 	aiOverviewRaw = results["ai_overview"]
@@ -213,8 +410,22 @@ func fetchAIOverview(query string) (*AIOverview, error) {
 	err = json.Unmarshal(jsonBytes, &result)
 	if err != nil {
 		fmt.Println("failed unmarshal second hit:", err)
-		return nil, err
+		return nil, features, err
 	}
 	overview = result
-	return &overview, nil
+	return &overview, features, nil
+}
+
+// serpAPIError strips the request URL, and with it the API key, from
+// transport errors of the SerpAPI client. Errors end up in snapshots,
+// exports and responses, so any key left in the text is masked too.
+func serpAPIError(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = fmt.Errorf("SerpAPI request failed: %w", uerr.Err)
+	}
+	if msg := redactKeys(err.Error()); msg != err.Error() {
+		return errors.New(msg)
+	}
+	return err
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSerpAPIErrorHidesKey(t *testing.T) {
+	t.Setenv("api_key", "secret-key-123")
+	errs := []error{
+		&url.Error{Op: "Get", URL: "https://serpapi.com/search?api_key=secret-key-123&q=emas", Err: errors.New("context deadline exceeded")},
+		errors.New("unexpected response for api_key=secret-key-123"),
+	}
+	for _, err := range errs {
+		if got := serpAPIError(err).Error(); strings.Contains(got, "secret-key-123") {
+			t.Errorf("error leaks the key: %s", got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeOptions toggles the optional steps of query normalization.
+// NFC, case folding and whitespace collapse are always applied.
+type NormalizeOptions struct {
+	StripDiacritics  bool
+	StripPunctuation bool
+}
+
+// Default options, overridable with normalize_diacritics=keep / normalize_punctuation=keep
+var normalizeOptions = NormalizeOptions{
+	StripDiacritics:  os.Getenv("normalize_diacritics") != "keep",
+	StripPunctuation: os.Getenv("normalize_punctuation") != "keep",
+}
+
+// NormalizeQuery turns a raw search query into its canonical keyword form,
+// e.g. "Harga  Émas! " -> "harga emas".
+func NormalizeQuery(q string, opts NormalizeOptions) string {
+	q = norm.NFC.String(q)
+	if opts.StripDiacritics {
+		q = stripDiacritics(q)
+	}
+	// A Caser keeps state between calls, so each call gets its own.
+	q = cases.Fold().String(q)
+	if opts.StripPunctuation {
+		q = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				return ' '
+			}
+			return r
+		}, q)
+	}
+	return strings.Join(strings.Fields(q), " ")
+}
+
+func stripDiacritics(s string) string {
+	decomposed := norm.NFD.String(s)
+	var b strings.Builder
+	b.Grow(len(decomposed))
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// KeywordID is the stable identity of a canonical keyword, used as the key
+// for caching, history grouping and analytics.
+func KeywordID(canonical string) string {
+	sum := sha1.Sum([]byte(canonical))
+	return hex.EncodeToString(sum[:6])
+}
+
+// CanonicalKeyword normalizes q with the default options and returns the
+// canonical form along with its ID.
+func CanonicalKeyword(q string) (canonical, id string) {
+	canonical = NormalizeQuery(q, normalizeOptions)
+	return canonical, KeywordID(canonical)
+}
@@ -0,0 +1,734 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// PageFeatures is what was extracted from one fetched page
+type PageFeatures struct {
+	URL             string     `json:"url"`
+	FetchedAt       time.Time  `json:"fetched_at"`
+	Status          int        `json:"status,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	Title           string     `json:"title,omitempty"`
+	WordCount       int        `json:"word_count"`
+	Headings        [6]int     `json:"headings"` // h1..h6
+	Outline         []string   `json:"outline,omitempty"`
+	SchemaTypes     []string   `json:"schema_types,omitempty"`
+	Published       *time.Time `json:"published,omitempty"`
+	Modified        *time.Time `json:"modified,omitempty"`
+	FAQSchema       bool       `json:"faq_schema"`
+	FAQQuestions    int        `json:"faq_questions"`
+	OutboundLinks   int        `json:"outbound_links"`
+	OutboundDomains int        `json:"outbound_domains"`
+	InternalLinks   int        `json:"internal_links"`
+}
+
+// Updated is the modified date, or the published date without one.
+func (f PageFeatures) Updated() *time.Time {
+	if f.Modified != nil {
+		return f.Modified
+	}
+	return f.Published
+}
+
+var pageDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05-0700", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02", time.RFC1123, time.RFC1123Z}
+
+func parsePageDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range pageDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// ExtractPageFeatures parses an HTML page fetched from link.
+func ExtractPageFeatures(link string, raw []byte) (PageFeatures, error) {
+	f := PageFeatures{URL: link, FetchedAt: time.Now()}
+	doc, err := html.Parse(bytes.NewReader(raw))
+	if err != nil {
+		return f, err
+	}
+	base, _ := url.Parse(link)
+	types := map[string]bool{}
+	domains := map[string]bool{}
+	var published, modified, timeTag *time.Time
+	var detailsQuestions, headingQuestions int
+
+	var text func(*html.Node) string
+	text = func(n *html.Node) string {
+		if n.Type == html.TextNode {
+			return n.Data
+		}
+		var b strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			b.WriteString(text(c) + " ")
+		}
+		return b.String()
+	}
+	attr := func(n *html.Node, key string) string {
+		for _, a := range n.Attr {
+			if a.Key == key {
+				return a.Val
+			}
+		}
+		return ""
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			f.WordCount += len(strings.Fields(n.Data))
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				if strings.Contains(attr(n, "type"), "ld+json") {
+					ld := parseJSONLD(text(n))
+					for _, t := range ld.types {
+						types[t] = true
+					}
+					if ld.faq > 0 {
+						f.FAQSchema = true
+						f.FAQQuestions += ld.faq
+					}
+					if published == nil {
+						published = ld.published
+					}
+					if modified == nil {
+						modified = ld.modified
+					}
+				}
+				return
+			case "style", "noscript", "template", "svg":
+				return
+			case "title":
+				if f.Title == "" {
+					f.Title = strings.Join(strings.Fields(text(n)), " ")
+				}
+				return
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				level := int(n.Data[1] - '1')
+				f.Headings[level]++
+				heading := strings.Join(strings.Fields(text(n)), " ")
+				if len(f.Outline) < 30 && heading != "" {
+					f.Outline = append(f.Outline, n.Data+" "+heading)
+				}
+				if strings.HasSuffix(heading, "?") {
+					headingQuestions++
+				}
+			case "details":
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode && c.Data == "summary" {
+						detailsQuestions++
+						break
+					}
+				}
+			case "meta":
+				switch strings.ToLower(attr(n, "property") + attr(n, "name") + attr(n, "itemprop")) {
+				case "article:published_time", "datepublished", "date":
+					if published == nil {
+						published = parsePageDate(attr(n, "content"))
+					}
+				case "article:modified_time", "og:updated_time", "datemodified", "last-modified":
+					if modified == nil {
+						modified = parsePageDate(attr(n, "content"))
+					}
+				}
+			case "time":
+				if timeTag == nil {
+					timeTag = parsePageDate(attr(n, "datetime"))
+				}
+			case "a":
+				if u, err := base.Parse(attr(n, "href")); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+					if host := referenceHost(u.String()); host == referenceHost(link) {
+						f.InternalLinks++
+					} else {
+						f.OutboundLinks++
+						domains[host] = true
+					}
+				}
+			}
+			if t := attr(n, "itemtype"); t != "" {
+				for _, it := range strings.Fields(t) {
+					types[schemaTypeName(it)] = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for t := range types {
+		f.SchemaTypes = append(f.SchemaTypes, t)
+	}
+	sort.Strings(f.SchemaTypes)
+	if types["FAQPage"] {
+		f.FAQSchema = true
+	}
+	if !f.FAQSchema {
+		f.FAQQuestions = max(detailsQuestions, headingQuestions)
+	}
+	if published == nil {
+		published = timeTag
+	}
+	f.Published, f.Modified = published, modified
+	f.OutboundDomains = len(domains)
+	return f, nil
+}
+
+// schemaTypeName turns "https://schema.org/Article" into "Article".
+func schemaTypeName(t string) string {
+	t = strings.TrimRight(t, "/")
+	if i := strings.LastIndexAny(t, "/#:"); i >= 0 {
+		t = t[i+1:]
+	}
+	return t
+}
+
+type jsonLD struct {
+	types     []string
+	faq       int
+	published *time.Time
+	modified  *time.Time
+}
+
+// parseJSONLD collects types, FAQ questions and dates from a JSON-LD
+// block, including nested objects and @graph.
+func parseJSONLD(raw string) jsonLD {
+	var ld jsonLD
+	var v any
+	if json.Unmarshal([]byte(strings.TrimSpace(raw)), &v) != nil {
+		return ld
+	}
+	var visit func(any)
+	visit = func(v any) {
+		switch x := v.(type) {
+		case []any:
+			for _, item := range x {
+				visit(item)
+			}
+		case map[string]any:
+			var isFAQ bool
+			switch t := x["@type"].(type) {
+			case string:
+				ld.types = append(ld.types, schemaTypeName(t))
+				isFAQ = schemaTypeName(t) == "FAQPage"
+			case []any:
+				for _, item := range t {
+					if s, ok := item.(string); ok {
+						ld.types = append(ld.types, schemaTypeName(s))
+						isFAQ = isFAQ || schemaTypeName(s) == "FAQPage"
+					}
+				}
+			}
+			if isFAQ {
+				switch m := x["mainEntity"].(type) {
+				case []any:
+					ld.faq += len(m)
+				case map[string]any:
+					ld.faq++
+				}
+			}
+			if s, ok := x["datePublished"].(string); ok && ld.published == nil {
+				ld.published = parsePageDate(s)
+			}
+			if s, ok := x["dateModified"].(string); ok && ld.modified == nil {
+				ld.modified = parsePageDate(s)
+			}
+			for k, child := range x {
+				if k != "@type" {
+					visit(child)
+				}
+			}
+		}
+	}
+	visit(v)
+	return ld
+}
+
+// featureCandidate is a page to compare: a reference of an overview, or an
+// organic result of the same search
+type featureCandidate struct {
+	Snapshot Snapshot
+	URL      string
+	Cited    bool
+	Position int // organic position, 0 when not ranked
+}
+
+// organicResults reads the organic results of the google search behind a
+// snapshot from its recorded exchanges.
+func organicResults(snapshotID int64) ([]string, bool) {
+	ex, err := loadExchanges(snapshotID)
+	if err != nil {
+		return nil, false
+	}
+	for _, e := range ex {
+		if e.Engine != "google" {
+			continue
+		}
+		var body struct {
+			Organic []struct {
+				Link string `json:"link"`
+			} `json:"organic_results"`
+		}
+		if json.Unmarshal(e.Body, &body) != nil {
+			return nil, false
+		}
+		var links []string
+		for _, r := range body.Organic {
+			links = append(links, r.Link)
+		}
+		return links, true
+	}
+	return nil, false
+}
+
+// featureCandidates takes the latest overview of each keyword and locale
+// whose search was recorded, so cited and uncited pages come from the same
+// queries. skipped counts series with overviews but no recorded search.
+func featureCandidates() (cands []featureCandidate, queries, skipped int) {
+	for _, series := range snapshotSeries() {
+		var snap Snapshot
+		var organic []string
+		found, cited := false, false
+		for i := len(series) - 1; i >= 0 && !found; i-- {
+			if series[i].Overview == nil || len(series[i].Overview.References) == 0 {
+				continue
+			}
+			cited = true
+			snap = series[i]
+			organic, found = organicResults(snap.ID)
+		}
+		if !found {
+			if cited {
+				skipped++
+			}
+			continue
+		}
+		queries++
+		refs := map[string]bool{}
+		for _, ref := range snap.Overview.References {
+			if key := pageKey(ref.Link); !refs[key] {
+				refs[key] = true
+				cands = append(cands, featureCandidate{Snapshot: snap, URL: ref.Link, Cited: true})
+			}
+		}
+		for i, link := range organic {
+			if refs[pageKey(link)] {
+				for j := range cands {
+					if cands[j].Snapshot.ID == snap.ID && pageKey(cands[j].URL) == pageKey(link) {
+						cands[j].Position = i + 1
+					}
+				}
+				continue
+			}
+			cands = append(cands, featureCandidate{Snapshot: snap, URL: link, Position: i + 1})
+		}
+	}
+	return cands, queries, skipped
+}
+
+// FeatureJob is the progress of fetching candidate pages
+type FeatureJob struct {
+	Running   bool      `json:"running"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	Total     int       `json:"total"`
+	Done      int       `json:"done"`
+	Failed    int       `json:"failed"`
+}
+
+var featureJob struct {
+	sync.Mutex
+	FeatureJob
+}
+
+func currentFeatureJob() FeatureJob {
+	featureJob.Lock()
+	defer featureJob.Unlock()
+	return featureJob.FeatureJob
+}
+
+// startFeatureFetch fetches every candidate page not fetched yet, or all of
+// them with refetch, in the background. It reports false if a fetch is
+// already running.
+func startFeatureFetch(refetch bool) bool {
+	cands, _, _ := featureCandidates()
+	seen := map[string]bool{}
+	var links []string
+	for _, c := range cands {
+		key := pageKey(c.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := store.PageFeatures(c.URL); ok && !refetch {
+			continue
+		}
+		links = append(links, c.URL)
+	}
+
+	featureJob.Lock()
+	if featureJob.Running {
+		featureJob.Unlock()
+		return false
+	}
+	featureJob.FeatureJob = FeatureJob{Running: true, StartedAt: time.Now(), Total: len(links)}
+	featureJob.Unlock()
+
+	go func() {
+		var wg sync.WaitGroup
+		for _, link := range links {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f := PageFeatures{URL: link, FetchedAt: time.Now()}
+				status, body, err := pageFetcher.Fetch(link)
+				if err == nil {
+					f, err = ExtractPageFeatures(link, body)
+				}
+				f.Status = status
+				if err != nil {
+					f.Error = err.Error()
+				}
+				if err := store.SetPageFeatures(f); err != nil {
+					log.Println("❌ failed to store page features:", err)
+				}
+				featureJob.Lock()
+				featureJob.Done++
+				if f.Error != "" {
+					featureJob.Failed++
+				}
+				featureJob.Unlock()
+			}()
+		}
+		wg.Wait()
+		featureJob.Lock()
+		featureJob.Running = false
+		featureJob.Unlock()
+		log.Printf("✅ fetched %d pages for feature extraction", len(links))
+	}()
+	return true
+}
+
+// FeatureGroup summarizes cited or uncited pages
+type FeatureGroup struct {
+	Pages           int            `json:"pages"`
+	MedianWords     float64        `json:"median_words"`
+	MeanHeadings    float64        `json:"mean_headings"`
+	WithSchema      float64        `json:"with_schema"` // share of pages, 0..1
+	WithFAQ         float64        `json:"with_faq"`
+	MeanOutbound    float64        `json:"mean_outbound_links"`
+	Dated           float64        `json:"dated"`
+	MedianAgeDays   float64        `json:"median_age_days"` // overview date minus last update
+	UpdatedAfter    int            `json:"updated_after_overview"`
+	SchemaTypes     map[string]int `json:"schema_types"`
+	ages, words     []float64
+	headings, links float64
+	schema, faq, dt int
+}
+
+func (g *FeatureGroup) add(f PageFeatures, age *float64) {
+	g.Pages++
+	g.words = append(g.words, float64(f.WordCount))
+	for _, n := range f.Headings {
+		g.headings += float64(n)
+	}
+	g.links += float64(f.OutboundLinks)
+	if len(f.SchemaTypes) > 0 {
+		g.schema++
+	}
+	if f.FAQQuestions > 0 {
+		g.faq++
+	}
+	for _, t := range f.SchemaTypes {
+		g.SchemaTypes[t]++
+	}
+	if age != nil {
+		g.dt++
+		g.ages = append(g.ages, *age)
+		if *age < 0 {
+			g.UpdatedAfter++
+		}
+	}
+}
+
+func (g *FeatureGroup) finish() {
+	if g.Pages == 0 {
+		return
+	}
+	n := float64(g.Pages)
+	g.MedianWords = median(g.words)
+	g.MeanHeadings = g.headings / n
+	g.MeanOutbound = g.links / n
+	g.WithSchema = float64(g.schema) / n
+	g.WithFAQ = float64(g.faq) / n
+	g.Dated = float64(g.dt) / n
+	g.MedianAgeDays = median(g.ages)
+}
+
+func median(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
+
+// correlation is Pearson's r of x against y; with y 0 or 1 it is the
+// point-biserial correlation. NaN when either side does not vary.
+func correlation(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return math.NaN()
+	}
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx, my = mx/n, my/n
+	var sxy, sxx, syy float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// FeatureCorrelation says how strongly a feature goes with being cited
+type FeatureCorrelation struct {
+	Feature string   `json:"feature"`
+	R       *float64 `json:"r"` // nil when it cannot be computed
+	Pages   int      `json:"pages"`
+}
+
+// FeatureRow is one compared page
+type FeatureRow struct {
+	KeywordID string        `json:"keyword_id"`
+	Keyword   string        `json:"keyword"`
+	Locale    string        `json:"locale"`
+	URL       string        `json:"url"`
+	Cited     bool          `json:"cited"`
+	Position  int           `json:"position,omitempty"`
+	AgeDays   *float64      `json:"age_days,omitempty"`
+	Features  *PageFeatures `json:"features,omitempty"`
+}
+
+// FeatureReport compares cited pages with uncited organic results
+type FeatureReport struct {
+	Queries      int                  `json:"queries"`
+	Skipped      int                  `json:"skipped"`   // keywords whose search was not recorded
+	Unfetched    int                  `json:"unfetched"` // pages not fetched yet
+	Failed       int                  `json:"failed"`
+	Cited        FeatureGroup         `json:"cited"`
+	NotCited     FeatureGroup         `json:"not_cited"`
+	Correlations []FeatureCorrelation `json:"correlations"`
+	Rows         []FeatureRow         `json:"rows"`
+	Job          FeatureJob           `json:"job"`
+}
+
+func BuildFeatureReport() FeatureReport {
+	cands, queries, skipped := featureCandidates()
+	rep := FeatureReport{Queries: queries, Skipped: skipped, Job: currentFeatureJob(),
+		Cited: FeatureGroup{SchemaTypes: map[string]int{}}, NotCited: FeatureGroup{SchemaTypes: map[string]int{}}}
+
+	type sample struct {
+		f     PageFeatures
+		age   *float64
+		cited float64
+	}
+	var samples []sample
+	for _, c := range cands {
+		row := FeatureRow{KeywordID: c.Snapshot.KeywordID, Keyword: c.Snapshot.Keyword, Locale: c.Snapshot.Locale.String(),
+			URL: c.URL, Cited: c.Cited, Position: c.Position}
+		f, ok := store.PageFeatures(c.URL)
+		switch {
+		case !ok:
+			rep.Unfetched++
+		case f.Error != "":
+			rep.Failed++
+		default:
+			row.Features = &f
+			if u := f.Updated(); u != nil {
+				age := c.Snapshot.FetchedAt.Sub(*u).Hours() / 24
+				row.AgeDays = &age
+			}
+			s := sample{f: f, age: row.AgeDays}
+			if c.Cited {
+				s.cited = 1
+				rep.Cited.add(f, row.AgeDays)
+			} else {
+				rep.NotCited.add(f, row.AgeDays)
+			}
+			samples = append(samples, s)
+		}
+		rep.Rows = append(rep.Rows, row)
+	}
+	rep.Cited.finish()
+	rep.NotCited.finish()
+
+	b2f := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	features := []struct {
+		name  string
+		value func(sample) (float64, bool)
+	}{
+		{"word count", func(s sample) (float64, bool) { return float64(s.f.WordCount), true }},
+		{"headings", func(s sample) (float64, bool) {
+			n := 0
+			for _, h := range s.f.Headings {
+				n += h
+			}
+			return float64(n), true
+		}},
+		{"has schema.org markup", func(s sample) (float64, bool) { return b2f(len(s.f.SchemaTypes) > 0), true }},
+		{"has FAQ", func(s sample) (float64, bool) { return b2f(s.f.FAQQuestions > 0), true }},
+		{"outbound links", func(s sample) (float64, bool) { return float64(s.f.OutboundLinks), true }},
+		{"has a date", func(s sample) (float64, bool) { return b2f(s.age != nil), true }},
+		{"age in days", func(s sample) (float64, bool) {
+			if s.age == nil {
+				return 0, false
+			}
+			return *s.age, true
+		}},
+	}
+	for _, feat := range features {
+		var x, y []float64
+		for _, s := range samples {
+			if v, ok := feat.value(s); ok {
+				x = append(x, v)
+				y = append(y, s.cited)
+			}
+		}
+		c := FeatureCorrelation{Feature: feat.name, Pages: len(x)}
+		if r := correlation(x, y); !math.IsNaN(r) {
+			c.R = &r
+		}
+		rep.Correlations = append(rep.Correlations, c)
+	}
+	return rep
+}
+
+var featuresTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Cited page features</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.25rem 0.5rem; border-bottom: 1px solid #ddd; vertical-align: top; }
+		.pos { color: #1a7f37; } .neg { color: #cf222e; }
+	</style>
+</head>
+<body>
+	<h1>🔬 Cited page features</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="POST" action="/features/fetch" class="text-block">
+		{{.Queries}} queries with a recorded search{{if .Skipped}}, {{.Skipped}} skipped because their search was not recorded{{end}}.
+		{{.Unfetched}} pages not fetched yet{{if .Failed}}, {{.Failed}} failed{{end}}.
+		{{with .Job}}{{if .Running}}<br/>Fetching: {{.Done}} of {{.Total}} done, {{.Failed}} failed.{{end}}{{end}}
+		<br/><button type="submit">Fetch missing pages</button>
+		<label><input type="checkbox" name="refetch" value="1" /> fetch all again</label>
+	</form>
+	<table>
+		<tr><th></th><th>Cited</th><th>Not cited</th></tr>
+		<tr><td>Pages</td><td>{{.Cited.Pages}}</td><td>{{.NotCited.Pages}}</td></tr>
+		<tr><td>Median words</td><td>{{printf "%.0f" .Cited.MedianWords}}</td><td>{{printf "%.0f" .NotCited.MedianWords}}</td></tr>
+		<tr><td>Mean headings</td><td>{{printf "%.1f" .Cited.MeanHeadings}}</td><td>{{printf "%.1f" .NotCited.MeanHeadings}}</td></tr>
+		<tr><td>With schema.org</td><td>{{percent .Cited.WithSchema}}</td><td>{{percent .NotCited.WithSchema}}</td></tr>
+		<tr><td>With FAQ</td><td>{{percent .Cited.WithFAQ}}</td><td>{{percent .NotCited.WithFAQ}}</td></tr>
+		<tr><td>Mean outbound links</td><td>{{printf "%.1f" .Cited.MeanOutbound}}</td><td>{{printf "%.1f" .NotCited.MeanOutbound}}</td></tr>
+		<tr><td>Dated</td><td>{{percent .Cited.Dated}}</td><td>{{percent .NotCited.Dated}}</td></tr>
+		<tr><td>Median age at overview (days)</td>{{range $g := groups .}}<td>{{if $g.Dated}}{{printf "%.0f" $g.MedianAgeDays}}{{else}}–{{end}}</td>{{end}}</tr>
+		<tr><td>Updated after the overview</td><td>{{.Cited.UpdatedAfter}}</td><td>{{.NotCited.UpdatedAfter}}</td></tr>
+	</table>
+	<h2>Correlation with being cited</h2>
+	<table>
+		<tr><th>Feature</th><th>r</th><th>Pages</th></tr>
+		{{range .Correlations}}
+			<tr><td>{{.Feature}}</td><td>{{with .R}}{{$r := deref .}}<span class="{{if gt $r 0.0}}pos{{else}}neg{{end}}">{{printf "%+.2f" $r}}</span>{{else}}–{{end}}</td><td>{{.Pages}}</td></tr>
+		{{end}}
+	</table>
+	<h2>Pages</h2>
+	<table>
+		<tr><th>Keyword</th><th>Page</th><th>Cited</th><th>Rank</th><th>Words</th><th>Schema</th><th>Age</th></tr>
+		{{range .Rows}}
+			<tr>
+				<td><a href="/keywords/{{.KeywordID}}/timeline">{{.Keyword}}</a> <small>{{.Locale}}</small></td>
+				<td><a href="{{.URL}}">{{.URL}}</a></td>
+				<td>{{if .Cited}}✔{{end}}</td>
+				<td>{{if .Position}}{{.Position}}{{end}}</td>
+				{{with .Features}}
+					<td>{{.WordCount}}</td>
+					<td>{{range $i, $t := .SchemaTypes}}{{if $i}}, {{end}}{{$t}}{{end}}</td>
+				{{else}}
+					<td colspan="2"><em>not fetched</em></td>
+				{{end}}
+				<td>{{with .AgeDays}}{{printf "%.0f" (deref .)}}d{{end}}</td>
+			</tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var featuresTpl = template.Must(template.New("features").Funcs(funcMap).Funcs(template.FuncMap{
+	"groups": func(r FeatureReport) []FeatureGroup { return []FeatureGroup{r.Cited, r.NotCited} },
+	"deref":  func(f *float64) float64 { return *f }, // only reached inside {{with}}
+}).Parse(featuresTmpl))
+
+func featuresPage(w http.ResponseWriter, r *http.Request) {
+	if err := featuresTpl.Execute(w, BuildFeatureReport()); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func fetchFeaturesForm(w http.ResponseWriter, r *http.Request) {
+	startFeatureFetch(r.FormValue("refetch") == "1")
+	http.Redirect(w, r, "/features", http.StatusSeeOther)
+}
+
+func apiFeatureReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, BuildFeatureReport())
+}
+
+// apiFetchFeatures starts fetching pages; ?refetch=1 fetches all again.
+func apiFetchFeatures(w http.ResponseWriter, r *http.Request) {
+	if !startFeatureFetch(r.FormValue("refetch") == "1") {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "a fetch is already running"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, currentFeatureJob())
+}
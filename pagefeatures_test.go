@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractPageFeatures(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+	tests := []struct {
+		name  string
+		html  string
+		check func(t *testing.T, f PageFeatures)
+	}{
+		{
+			name: "headings",
+			html: `<html><head><title> Harga  emas </title></head><body>
+				<h1>Harga emas hari ini</h1><h2>Kenapa naik?</h2><h2></h2><h3>Antam</h3>
+				<script>var x = "not counted words here";</script><p>satu dua tiga</p></body></html>`,
+			check: func(t *testing.T, f PageFeatures) {
+				if f.Title != "Harga emas" {
+					t.Errorf("Title = %q", f.Title)
+				}
+				if want := [6]int{1, 2, 1, 0, 0, 0}; f.Headings != want {
+					t.Errorf("Headings = %v, want %v", f.Headings, want)
+				}
+				if want := []string{"h1 Harga emas hari ini", "h2 Kenapa naik?", "h3 Antam"}; !reflect.DeepEqual(f.Outline, want) {
+					t.Errorf("Outline = %q, want %q", f.Outline, want)
+				}
+				// A question heading counts as an FAQ question without schema.
+				if f.FAQSchema || f.FAQQuestions != 1 {
+					t.Errorf("FAQSchema, FAQQuestions = %v, %d; want false, 1", f.FAQSchema, f.FAQQuestions)
+				}
+				// Headings and the paragraph; the title and scripts are skipped.
+				if f.WordCount != 4+2+1+3 {
+					t.Errorf("WordCount = %d, want 10", f.WordCount)
+				}
+			},
+		},
+		{
+			name: "json-ld graph and faq",
+			html: `<html><head><script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+				{"@type":"Article","datePublished":"2024-03-01T08:00:00Z","dateModified":"2024-05-02T09:30:00Z"},
+				{"@type":["WebPage","FAQPage"],"mainEntity":[{"@type":"Question"},{"@type":"Question"}]}]}</script>
+				</head><body><div itemscope itemtype="https://schema.org/Organization"></div>
+				<details><summary>Not counted once schema says FAQ</summary></details></body></html>`,
+			check: func(t *testing.T, f PageFeatures) {
+				if want := []string{"Article", "FAQPage", "Organization", "Question", "WebPage"}; !reflect.DeepEqual(f.SchemaTypes, want) {
+					t.Errorf("SchemaTypes = %q, want %q", f.SchemaTypes, want)
+				}
+				if !f.FAQSchema || f.FAQQuestions != 2 {
+					t.Errorf("FAQSchema, FAQQuestions = %v, %d; want true, 2", f.FAQSchema, f.FAQQuestions)
+				}
+				if !reflect.DeepEqual(f.Published, date("2024-03-01T08:00:00Z")) || !reflect.DeepEqual(f.Modified, date("2024-05-02T09:30:00Z")) {
+					t.Errorf("Published, Modified = %v, %v", f.Published, f.Modified)
+				}
+			},
+		},
+		{
+			name: "meta dates",
+			html: `<html><head><meta property="article:published_time" content="2024-01-10T10:00:00+07:00">
+				<meta property="og:updated_time" content="2024-02-11"></head>
+				<body><time datetime="2023-12-31">lama</time></body></html>`,
+			check: func(t *testing.T, f PageFeatures) {
+				if !reflect.DeepEqual(f.Published, date("2024-01-10T10:00:00+07:00")) {
+					t.Errorf("Published = %v", f.Published)
+				}
+				if !reflect.DeepEqual(f.Modified, date("2024-02-11T00:00:00Z")) {
+					t.Errorf("Modified = %v", f.Modified)
+				}
+			},
+		},
+		{
+			name: "time tag fallback",
+			html: `<html><body><time datetime="2023-12-31T12:00:00Z">31 Desember</time><time datetime="2020-01-01">lama</time></body></html>`,
+			check: func(t *testing.T, f PageFeatures) {
+				if !reflect.DeepEqual(f.Published, date("2023-12-31T12:00:00Z")) {
+					t.Errorf("Published = %v", f.Published)
+				}
+				if f.Modified != nil {
+					t.Errorf("Modified = %v, want nil", f.Modified)
+				}
+			},
+		},
+		{
+			name: "links",
+			html: `<html><body>
+				<a href="/emas/antam">relative</a><a href="https://www.example.com/kurs">www of the same host</a>
+				<a href="https://logammulia.com/id">outbound</a><a href="https://www.logammulia.com/en">same outbound domain</a>
+				<a href="https://bi.go.id/">another domain</a><a href="mailto:info@example.com">mail</a><a href="#top">anchor</a></body></html>`,
+			check: func(t *testing.T, f PageFeatures) {
+				// The fragment resolves against the page itself, so it is internal too.
+				if f.InternalLinks != 3 {
+					t.Errorf("InternalLinks = %d, want 3", f.InternalLinks)
+				}
+				if f.OutboundLinks != 3 || f.OutboundDomains != 2 {
+					t.Errorf("OutboundLinks, OutboundDomains = %d, %d; want 3, 2", f.OutboundLinks, f.OutboundDomains)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ExtractPageFeatures("https://example.com/emas", []byte(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.URL != "https://example.com/emas" {
+				t.Errorf("URL = %q", f.URL)
+			}
+			tt.check(t, f)
+		})
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+		want   []string
+	}{
+		{
+			name:   "star group",
+			robots: "User-agent: *\nDisallow: /admin\nDisallow:\nAllow: /admin/public\n",
+			want:   []string{"/admin"},
+		},
+		{
+			name:   "own group by product token",
+			robots: "User-agent: Googlebot\nDisallow: /google\n\nuser-agent: AIOverviewBot\nDisallow: /mine # comment\n",
+			want:   []string{"/mine"},
+		},
+		{
+			name:   "shared group",
+			robots: "User-agent: Googlebot\nUser-agent: *\nDisallow: /shared\nUser-agent: Bingbot\nDisallow: /bing\n",
+			want:   []string{"/shared"},
+		},
+		{
+			name:   "wildcards cut to a prefix",
+			robots: "User-agent: *\nDisallow: /search*q=\nDisallow: /*.pdf$\n",
+			want:   []string{"/search", "/"},
+		},
+		{
+			name:   "other agents only",
+			robots: "User-agent: Googlebot\nDisallow: /\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRobots([]byte(tt.robots), "AIOverviewBot/1.0 (+https://example.com)")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRobots = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeaturesTemplateRendersPointers(t *testing.T) {
+	r, age := -0.25, 41.6
+	rep := FeatureReport{
+		Cited:        FeatureGroup{SchemaTypes: map[string]int{}},
+		NotCited:     FeatureGroup{SchemaTypes: map[string]int{}},
+		Correlations: []FeatureCorrelation{{Feature: "Words", R: &r, Pages: 12}, {Feature: "FAQ", Pages: 3}},
+		Rows:         []FeatureRow{{KeywordID: "1", Keyword: "harga emas", URL: "https://example.com/emas", Cited: true, AgeDays: &age}},
+	}
+	var buf bytes.Buffer
+	if err := featuresTpl.Execute(&buf, rep); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`<span class="neg">-0.25</span>`, "<td>42d</td>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if strings.Contains(out, "0x") {
+		t.Error("page prints a pointer")
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prewarming refreshes the most requested keywords shortly before business
+// hours so the first users of the day hit the cache. It runs when
+// prewarm_hours is set, e.g. prewarm_hours=08:00-18:00.
+
+// PrewarmConfig is read from the environment
+type PrewarmConfig struct {
+	Start, End time.Duration // business hours as offsets from midnight
+	Zone       *time.Location
+	Lead       time.Duration // how long before Start to refresh
+	Top        int           // how many keyword/locale pairs to keep warm
+	Credits    int           // SerpAPI calls a run may spend
+	Window     time.Duration // request history to learn from
+}
+
+func prewarmConfig() (PrewarmConfig, error) {
+	c := PrewarmConfig{Lead: 30 * time.Minute, Top: 20, Credits: 50, Window: 14 * 24 * time.Hour, Zone: time.UTC}
+	start, end, ok := strings.Cut(os.Getenv("prewarm_hours"), "-")
+	if !ok {
+		return c, errors.New("prewarm_hours is not set")
+	}
+	var err error
+	if c.Start, err = clockOffset(start); err != nil {
+		return c, err
+	}
+	if c.End, err = clockOffset(end); err != nil {
+		return c, err
+	}
+	if tz := os.Getenv("prewarm_tz"); tz != "" {
+		if c.Zone, err = time.LoadLocation(tz); err != nil {
+			return c, err
+		}
+	} else if zone, err := time.LoadLocation("Asia/Jakarta"); err == nil {
+		c.Zone = zone
+	}
+	if d, err := time.ParseDuration(os.Getenv("prewarm_lead")); err == nil && d >= 0 {
+		c.Lead = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("prewarm_top")); err == nil && n > 0 {
+		c.Top = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("prewarm_credits")); err == nil && n >= 0 {
+		c.Credits = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("prewarm_window")); err == nil && d > 0 {
+		c.Window = d
+	}
+	return c, nil
+}
+
+// clockOffset parses "08:00" as 8h.
+func clockOffset(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// NextRun is the next refresh time after now: Lead before business hours start.
+func (c PrewarmConfig) NextRun(now time.Time) time.Time {
+	now = now.In(c.Zone)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.Zone)
+	run := day.Add(c.Start - c.Lead)
+	if !run.After(now) {
+		run = day.AddDate(0, 0, 1).Add(c.Start - c.Lead)
+	}
+	return run
+}
+
+// closing is when business hours end on the day of the run at t, which is
+// how long prewarmed entries stay cached.
+func (c PrewarmConfig) closing(t time.Time) time.Time {
+	t = t.In(c.Zone)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.Zone)
+	if c.Start-c.Lead < 0 {
+		day = day.AddDate(0, 0, 1) // the run happened the evening before
+	}
+	end := day.Add(c.End)
+	if c.End <= c.Start {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// PopularQuery is a keyword and locale ranked by recent requests
+type PopularQuery struct {
+	KeywordID string `json:"keyword_id"`
+	Keyword   string `json:"keyword"`
+	Query     string `json:"query"` // most requested raw variant, sent upstream
+	Locale    Locale `json:"locale"`
+	Requests  int    `json:"requests"`
+}
+
+// PopularQueries learns the top n keyword/locale pairs requested since since.
+func PopularQueries(since time.Time, n int) []PopularQuery {
+	byKey := map[string]*PopularQuery{}
+	variants := map[string]map[string]int{}
+	for _, req := range store.Requests() {
+		if req.At.Before(since) {
+			continue
+		}
+		key := req.KeywordID + "|" + req.Locale.String()
+		p, ok := byKey[key]
+		if !ok {
+			p = &PopularQuery{KeywordID: req.KeywordID, Keyword: req.Keyword, Locale: req.Locale}
+			byKey[key] = p
+			variants[key] = map[string]int{}
+		}
+		p.Requests++
+		variants[key][req.Query]++
+	}
+	out := make([]PopularQuery, 0, len(byKey))
+	for key, p := range byKey {
+		best := 0
+		for q, c := range variants[key] {
+			if c > best || (c == best && q < p.Query) {
+				p.Query, best = q, c
+			}
+		}
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].Keyword < out[j].Keyword
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// PrewarmRun records one prewarm pass
+type PrewarmRun struct {
+	At        time.Time `json:"at"`
+	Planned   int       `json:"planned"`
+	Refreshed int       `json:"refreshed"`
+	Failed    int       `json:"failed"`
+	Credits   int       `json:"credits"` // SerpAPI calls made during the run
+	Stopped   string    `json:"stopped,omitempty"`
+	Keywords  []string  `json:"keywords,omitempty"` // keyword|locale pairs refreshed
+}
+
+// Prewarm refreshes the popular queries until the credit budget is spent.
+// Calls made by interactive users during the run count towards it too.
+func Prewarm(c PrewarmConfig) PrewarmRun {
+	now := time.Now()
+	run := PrewarmRun{At: now}
+	popular := PopularQueries(now.Add(-c.Window), c.Top)
+	run.Planned = len(popular)
+	ttl := time.Until(c.closing(now))
+	if ttl <= 0 {
+		ttl = cache.TTL() // run by hand after hours
+	}
+	startCalls := serpCallCount.Load()
+	defer queueDepth.Store(0)
+	for i, p := range popular {
+		queueDepth.Store(int64(len(popular) - i))
+		if err := creditGate(); err != nil {
+			run.Stopped = err.Error()
+			break
+		}
+		// a lookup costs up to two calls
+		if int(serpCallCount.Load()-startCalls)+2 > c.Credits {
+			run.Stopped = "credit budget spent"
+			break
+		}
+		_, err := refresh(p.Query, p.Locale, ttl)
+		if err != nil && !errors.Is(err, errNoOverview) {
+			log.Println("❌ prewarm failed for", p.Keyword, err)
+			run.Failed++
+			continue
+		}
+		run.Refreshed++
+		run.Keywords = append(run.Keywords, p.KeywordID+"|"+p.Locale.String())
+	}
+	run.Credits = int(serpCallCount.Load() - startCalls)
+	log.Printf("🔥 prewarmed %d of %d popular queries using %d SerpAPI calls", run.Refreshed, run.Planned, run.Credits)
+	if err := store.AddPrewarmRun(run); err != nil {
+		log.Println("❌ failed to store prewarm run:", err)
+	}
+	return run
+}
+
+// prewarmLoop sleeps until each next run until the process exits.
+func prewarmLoop(c PrewarmConfig) {
+	for {
+		next := c.NextRun(time.Now())
+		log.Println("🔥 next prewarm at", next.Format(time.RFC3339))
+		time.Sleep(time.Until(next))
+		if !currentSettings().Prewarm {
+			log.Println("🔥 prewarming is switched off in settings, skipping")
+			continue
+		}
+		Prewarm(c)
+	}
+}
+
+// PrewarmCoverage is how many requests for prewarmed keywords since the
+// last run were served warm
+type PrewarmCoverage struct {
+	Since    time.Time `json:"since"`
+	Requests int       `json:"requests"`
+	Cached   int       `json:"cached"`
+	Rate     float64   `json:"rate"`
+}
+
+func prewarmCoverage(run PrewarmRun) PrewarmCoverage {
+	cov := PrewarmCoverage{Since: run.At}
+	warmed := map[string]bool{}
+	for _, k := range run.Keywords {
+		warmed[k] = true
+	}
+	for _, req := range store.Requests() {
+		if req.At.Before(run.At) || !warmed[req.KeywordID+"|"+req.Locale.String()] {
+			continue
+		}
+		cov.Requests++
+		if req.Cached {
+			cov.Cached++
+		}
+	}
+	if cov.Requests > 0 {
+		cov.Rate = float64(cov.Cached) / float64(cov.Requests)
+	}
+	return cov
+}
+
+var prewarmTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Prewarming</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.25rem 0.5rem; border-bottom: 1px solid #ddd; }
+	</style>
+</head>
+<body>
+	<h1>🔥 Prewarming</h1>
+	<p><a href="/">← Search</a> · <a href="/admin/credits">Credits</a></p>
+	<div class="text-block">
+		{{if .Error}}
+			<p>Prewarming is off: {{.Error}}</p>
+		{{else}}
+			<p>Business hours {{.Hours}} ({{.Config.Zone}}), refreshed {{.Config.Lead}} ahead. Next run {{.Next.Format "2006-01-02 15:04 MST"}}.</p>
+			<p>Top {{.Config.Top}} keywords of the last {{.Config.Window}}, at most {{.Config.Credits}} SerpAPI calls per run.</p>
+			<form method="POST"><button type="submit">Run now</button></form>
+		{{end}}
+	</div>
+	{{with .Coverage}}
+		<h2>Coverage since last run</h2>
+		<p>{{.Cached}} of {{.Requests}} requests for prewarmed keywords were served from cache ({{percent .Rate}}).</p>
+	{{end}}
+	<h2>Popular queries</h2>
+	<table>
+		<tr><th>Keyword</th><th>Locale</th><th>Requests</th></tr>
+		{{range .Popular}}
+			<tr><td><a href="/keywords/{{.KeywordID}}/timeline?hl={{.Locale.HL}}&gl={{.Locale.GL}}">{{.Keyword}}</a></td><td>{{.Locale}}</td><td>{{.Requests}}</td></tr>
+		{{else}}
+			<tr><td colspan="3"><em>No requests yet.</em></td></tr>
+		{{end}}
+	</table>
+	<h2>Runs</h2>
+	<table>
+		<tr><th>At</th><th>Refreshed</th><th>Failed</th><th>Spend</th><th>Note</th></tr>
+		{{range .Runs}}
+			<tr><td>{{.At.Format "2006-01-02 15:04"}}</td><td>{{.Refreshed}} / {{.Planned}}</td><td>{{.Failed}}</td><td>{{.Credits}} calls</td><td>{{.Stopped}}</td></tr>
+		{{else}}
+			<tr><td colspan="5"><em>No runs yet.</em></td></tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var prewarmTpl = template.Must(template.New("prewarm").Funcs(funcMap).Parse(prewarmTmpl))
+
+func prewarmPage(w http.ResponseWriter, r *http.Request) {
+	c, err := prewarmConfig()
+	data := struct {
+		Config   PrewarmConfig
+		Hours    string
+		Next     time.Time
+		Error    string
+		Popular  []PopularQuery
+		Runs     []PrewarmRun
+		Coverage *PrewarmCoverage
+	}{Config: c, Hours: os.Getenv("prewarm_hours"), Runs: store.PrewarmRuns()}
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		data.Next = c.NextRun(time.Now())
+	}
+	data.Popular = PopularQueries(time.Now().Add(-c.Window), c.Top)
+	if len(data.Runs) > 0 {
+		cov := prewarmCoverage(data.Runs[0])
+		data.Coverage = &cov
+	}
+	if err := prewarmTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func prewarmNow(w http.ResponseWriter, r *http.Request) {
+	c, err := prewarmConfig()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	Prewarm(c)
+	http.Redirect(w, r, "/admin/prewarm", http.StatusSeeOther)
+}
+
+func apiPrewarm(w http.ResponseWriter, r *http.Request) {
+	c, _ := prewarmConfig()
+	resp := map[string]any{
+		"popular": PopularQueries(time.Now().Add(-c.Window), c.Top),
+		"runs":    store.PrewarmRuns(),
+	}
+	if runs := store.PrewarmRuns(); len(runs) > 0 {
+		resp["coverage"] = prewarmCoverage(runs[0])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// AuditEntry records an administrative action on personal data
+type AuditEntry struct {
+	ID      int64     `json:"id"`
+	At      time.Time `json:"at"`
+	Action  string    `json:"action"`
+	Subject string    `json:"subject"` // e.g. "user 3", never names or queries
+	Actor   string    `json:"actor"`
+	Details string    `json:"details,omitempty"`
+}
+
+// EraseReport says what an erase removed and whether anything is left
+type EraseReport struct {
+	UserID        int64    `json:"user_id"`
+	Pseudonymized bool     `json:"pseudonymized"` // lookups kept without the user
+	Requests      int      `json:"requests"`      // lookups deleted or pseudonymized
+	Subscriptions int      `json:"subscriptions"`
+	AuditID       int64    `json:"audit_id"`
+	Scanned       []string `json:"scanned"`   // places searched for leftovers
+	Remaining     []string `json:"remaining"` // references still found after erasing
+	Verified      bool     `json:"verified"`  // nothing found in the scanned places
+	Notes         []string `json:"notes"`     // what the scan does not cover
+}
+
+// UserExport is everything stored about one user
+type UserExport struct {
+	ExportedAt time.Time    `json:"exported_at"`
+	User       User         `json:"user"`
+	Searches   []RequestLog `json:"searches"`
+	Notes      []string     `json:"notes"`
+}
+
+// privacyNotes explains what the export and erase cover.
+var privacyNotes = []string{
+	"Searches are the lookups made through the Telegram bot; web searches are not linked to users.",
+	"Annotations, clicks and personal tokens are not recorded. Embed tokens are issued per site, not per user.",
+	"The overview cache and live dashboard hold keywords only, never who asked.",
+	"Process logs are not part of the export; errors of Telegram lookups may be logged with their text.",
+}
+
+func BuildUserExport(u User) UserExport {
+	return UserExport{ExportedAt: time.Now(), User: u, Searches: store.UserRequests(u.ID), Notes: privacyNotes}
+}
+
+// userArchive packs an export as a zip of JSON files.
+func userArchive(e UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, v any) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	parts := []struct {
+		name string
+		v    any
+	}{
+		{"user.json", e.User},
+		{"searches.json", e.Searches},
+		{"subscriptions.json", e.User.Subscribed},
+		{"about.json", map[string]any{"exported_at": e.ExportedAt, "notes": e.Notes}},
+	}
+	for _, p := range parts {
+		if err := add(p.name, p.v); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EraseUser deletes or pseudonymizes everything tied to a user, then looks
+// for leftovers. actor says who asked, for the audit log.
+func EraseUser(u User, pseudonymize bool, actor string) (EraseReport, error) {
+	mode := "delete"
+	if pseudonymize {
+		mode = "pseudonymize"
+	}
+	rep, err := store.EraseUser(u.ID, pseudonymize, AuditEntry{
+		At: time.Now(), Action: "erase_user", Subject: fmt.Sprintf("user %d", u.ID), Actor: actor, Details: mode,
+	})
+	if err != nil {
+		return rep, err
+	}
+	if _, ok := store.FindUser(u.ID); ok {
+		rep.Remaining = append(rep.Remaining, "user record")
+	}
+	if _, ok := store.UserByTelegram(u.TelegramID); ok && u.TelegramID != 0 {
+		rep.Remaining = append(rep.Remaining, "telegram link")
+	}
+	if n := len(store.UserRequests(u.ID)); n > 0 {
+		rep.Remaining = append(rep.Remaining, fmt.Sprintf("%d searches", n))
+	}
+	// The store is only one of the files written; look through everything
+	// persisted for the user's identifiers.
+	rep.Scanned = append(rep.Scanned, "data directory "+dataDir())
+	found, err := scanForUser(dataDir(), u)
+	if err != nil {
+		return rep, err
+	}
+	rep.Remaining = append(rep.Remaining, found...)
+	if gitHistory != nil {
+		rep.Scanned = append(rep.Scanned, "git history "+gitHistory.dir)
+		found, err := scanForUser(gitHistory.dir, u)
+		if err != nil {
+			return rep, err
+		}
+		rep.Remaining = append(rep.Remaining, found...)
+	}
+	rep.Verified = len(rep.Remaining) == 0
+	rep.Notes = []string{
+		"Backups taken before now still contain this user until they are rotated.",
+		"Process logs are not scanned; Telegram errors logged before now may come from this user's chats.",
+	}
+	if pseudonymize {
+		rep.Notes = append(rep.Notes, "Pseudonymized searches keep their exact query and time, which may still point to this user.")
+	}
+	log.Printf("🧹 erased user %d (%s), %d searches, verified %v", u.ID, mode, rep.Requests, rep.Verified)
+	return rep, nil
+}
+
+// scanForUser lists the files under dir, git internals aside, that still
+// mention the user's ID, Telegram ID or name as a JSON field.
+func scanForUser(dir string, u User) ([]string, error) {
+	name, _ := json.Marshal(u.Name)
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(`"id":\s*` + strconv.FormatInt(u.ID, 10) + `,\s*"name":\s*` + regexp.QuoteMeta(string(name))),
+		regexp.MustCompile(`"user_id":\s*` + strconv.FormatInt(u.ID, 10) + `\b`),
+	}
+	if u.TelegramID != 0 {
+		patterns = append(patterns, regexp.MustCompile(`"telegram_id":\s*`+strconv.FormatInt(u.TelegramID, 10)+`\b`))
+	}
+	var found []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for _, re := range patterns {
+			if re.Match(raw) {
+				rel, _ := filepath.Rel(dir, p)
+				found = append(found, filepath.ToSlash(rel))
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return found, nil
+}
+
+func userFromPath(r *http.Request) (User, bool) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	return store.FindUser(id)
+}
+
+// apiUserExport downloads a user's data as a zip of JSON files.
+func apiUserExport(w http.ResponseWriter, r *http.Request) {
+	u, ok := userFromPath(r)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": errUserNotFound.Error()})
+		return
+	}
+	raw, err := userArchive(BuildUserExport(u))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := store.AddAudit(AuditEntry{At: time.Now(), Action: "export_user", Subject: fmt.Sprintf("user %d", u.ID), Actor: "admin"}); err != nil {
+		log.Println("❌ failed to write audit entry:", err)
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.zip"`, u.ID))
+	w.Write(raw)
+}
+
+// apiUserErase serves POST /api/v1/users/{id}/erase?mode=delete|pseudonymize.
+func apiUserErase(w http.ResponseWriter, r *http.Request) {
+	u, ok := userFromPath(r)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": errUserNotFound.Error()})
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "delete" && mode != "pseudonymize" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "mode must be delete or pseudonymize"})
+		return
+	}
+	rep, err := EraseUser(u, mode == "pseudonymize", "admin")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+var eraseReportTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Erase report</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.ok { color: #1a7f37; } .flag { color: #cf222e; }
+	</style>
+</head>
+<body>
+	<h1>🧹 Erase report</h1>
+	<p><a href="/users">← Users</a></p>
+	<div class="text-block">
+		<p>User {{.UserID}}: {{.Requests}} searches {{if .Pseudonymized}}pseudonymized{{else}}deleted{{end}}, {{.Subscriptions}} subscriptions removed. Audit entry #{{.AuditID}}.</p>
+		{{if .Verified}}
+			<p class="ok">✅ No references to this user found in {{range $i, $s := .Scanned}}{{if $i}}, {{end}}{{$s}}{{end}}.</p>
+		{{else}}
+			<p class="flag">Still found: {{range $i, $r := .Remaining}}{{if $i}}, {{end}}{{$r}}{{end}}</p>
+		{{end}}
+		{{range .Notes}}<p><em>{{.}}</em></p>{{end}}
+	</div>
+</body>
+</html>
+`
+
+var eraseReportTpl = template.Must(template.New("erase").Parse(eraseReportTmpl))
+
+// eraseUserForm handles the erase buttons on the users page.
+func eraseUserForm(w http.ResponseWriter, r *http.Request) {
+	u, ok := userFromPath(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rep, err := EraseUser(u, r.FormValue("mode") == "pseudonymize", "admin")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := eraseReportTpl.Execute(w, rep); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiAudit(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, store.Audit())
+}
+
+// telegramMyData sends a user their export as a document.
+func (b *TelegramBot) telegramMyData(chatID int64, u User) error {
+	raw, err := userArchive(BuildUserExport(u))
+	if err != nil {
+		return err
+	}
+	if err := store.AddAudit(AuditEntry{At: time.Now(), Action: "export_user", Subject: fmt.Sprintf("user %d", u.ID), Actor: "self (telegram)"}); err != nil {
+		log.Println("❌ failed to write audit entry:", err)
+	}
+	return b.SendDocument(chatID, fmt.Sprintf("user-%d-export.zip", u.ID), raw, "Everything we store about you.")
+}
+
+var errNotConfirmed = errors.New("send /forgetme confirm to delete your data")
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEraseUserScansDataDirectory(t *testing.T) {
+	useTestStore(t)
+	keep, err := store.SaveUser(User{Name: "Budi", TelegramID: 4242})
+	if err != nil {
+		t.Fatal(err)
+	}
+	erase := func(name string, telegramID int64) User {
+		u, err := store.SaveUser(User{Name: name, TelegramID: telegramID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.LogRequest(RequestLog{Keyword: "harga emas", Query: "harga emas", At: time.Now(), UserID: u.ID}); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	u := erase("Sari", 777)
+	rep, err := EraseUser(u, false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Verified || rep.Requests != 1 || len(rep.Scanned) == 0 {
+		t.Errorf("report = %+v, want verified with 1 search deleted", rep)
+	}
+	if _, ok := store.FindUser(keep.ID); !ok {
+		t.Error("erase removed another user")
+	}
+
+	// A file outside the store still naming the user fails verification.
+	u = erase("Dewi", 888)
+	stray := filepath.Join(os.Getenv("data_dir"), "captures", "stray.json")
+	os.MkdirAll(filepath.Dir(stray), 0o755)
+	if err := os.WriteFile(stray, []byte(`{"telegram_id": 888}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rep, err = EraseUser(u, false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Verified || !reflect.DeepEqual(rep.Remaining, []string{"captures/stray.json"}) {
+		t.Errorf("report = %+v, want the stray file reported", rep)
+	}
+}
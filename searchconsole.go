@@ -0,0 +1,463 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchConsoleMetrics are the performance numbers of one query from a
+// Search Console export
+type SearchConsoleMetrics struct {
+	Clicks      int     `json:"clicks"`
+	Impressions int     `json:"impressions"`
+	CTR         float64 `json:"ctr"` // 0..1
+	Position    float64 `json:"position"`
+}
+
+// SearchConsoleRow is one query line of an export
+type SearchConsoleRow struct {
+	Query string `json:"query"`
+	SearchConsoleMetrics
+}
+
+// TrackedKeyword is a keyword we intend to keep an eye on
+type TrackedKeyword struct {
+	KeywordID     string                `json:"keyword_id"`
+	Keyword       string                `json:"keyword"`
+	Query         string                `json:"query"` // raw query to send upstream
+	Locale        Locale                `json:"locale"`
+	Source        string                `json:"source"` // e.g. "search_console"
+	AddedAt       time.Time             `json:"added_at"`
+	SearchConsole *SearchConsoleMetrics `json:"search_console,omitempty"`
+}
+
+// Header names used by Search Console exports in English and Indonesian
+var searchConsoleHeaders = map[string][]string{
+	"query":       {"top queries", "queries", "query", "kueri teratas", "kueri"},
+	"clicks":      {"clicks", "klik"},
+	"impressions": {"impressions", "tayangan"},
+	"ctr":         {"ctr"},
+	"position":    {"position", "posisi"},
+}
+
+// parseSearchConsoleRows maps the header row of an export to its columns
+// and parses every query row.
+func parseSearchConsoleRows(rows [][]sheetCell) ([]SearchConsoleRow, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("export is empty")
+	}
+	cols := map[string]int{}
+	for i, c := range rows[0] {
+		h := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(c.Value, "\ufeff")))
+		for field, names := range searchConsoleHeaders {
+			for _, n := range names {
+				if h == n {
+					if _, ok := cols[field]; !ok {
+						cols[field] = i
+					}
+				}
+			}
+		}
+	}
+	if _, ok := cols["query"]; !ok {
+		return nil, errors.New("export has no query column, expected e.g. \"Top queries\"")
+	}
+	cell := func(row []sheetCell, field string) sheetCell {
+		i, ok := cols[field]
+		if !ok || i >= len(row) {
+			return sheetCell{}
+		}
+		return sheetCell{Value: strings.TrimSpace(row[i].Value), Number: row[i].Number}
+	}
+	// Numeric XLSX cells hold raw floats such as 0.125, which parseNumber
+	// would take for 125 with a thousands separator.
+	number := func(c sheetCell) (float64, bool) {
+		if c.Number {
+			v, err := strconv.ParseFloat(c.Value, 64)
+			return v, err == nil
+		}
+		return parseNumber(strings.TrimSpace(strings.TrimSuffix(c.Value, "%")))
+	}
+
+	var out []SearchConsoleRow
+	for _, row := range rows[1:] {
+		q := cell(row, "query").Value
+		if q == "" {
+			continue
+		}
+		r := SearchConsoleRow{Query: q}
+		if v, ok := number(cell(row, "clicks")); ok {
+			r.Clicks = int(v)
+		}
+		if v, ok := number(cell(row, "impressions")); ok {
+			r.Impressions = int(v)
+		}
+		ctr := cell(row, "ctr")
+		if v, ok := number(ctr); ok {
+			if strings.HasSuffix(ctr.Value, "%") || v > 1 {
+				v /= 100
+			}
+			r.CTR = v
+		}
+		if v, ok := number(cell(row, "position")); ok {
+			r.Position = v
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// ParseSearchConsoleExport reads a Search Console export as CSV, as an
+// .xlsx workbook, or as the .zip that bundles Queries.csv.
+func ParseSearchConsoleExport(name string, raw []byte) ([]SearchConsoleRow, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".xlsx"):
+		rows, err := readXLSXSheet(raw, "Queries", "Kueri")
+		if err != nil {
+			return nil, err
+		}
+		return parseSearchConsoleRows(rows)
+	case strings.HasSuffix(lower, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			base := strings.ToLower(path.Base(f.Name))
+			if base == "queries.csv" || base == "kueri.csv" {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				data, err := readZipPart(rc, f.Name)
+				rc.Close()
+				if err != nil {
+					return nil, err
+				}
+				return ParseSearchConsoleExport(f.Name, data)
+			}
+		}
+		return nil, errors.New("zip export has no Queries.csv")
+	}
+	r := csv.NewReader(bytes.NewReader(raw))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchConsoleRows(textRows(rows))
+}
+
+// SearchConsoleThresholds select which queries become tracked keywords.
+// Zero values disable a threshold.
+type SearchConsoleThresholds struct {
+	MinClicks      int     `json:"min_clicks"`
+	MinImpressions int     `json:"min_impressions"`
+	MinCTR         float64 `json:"min_ctr"`
+	MaxPosition    float64 `json:"max_position"`
+}
+
+func (t SearchConsoleThresholds) match(r SearchConsoleRow) bool {
+	return r.Clicks >= t.MinClicks && r.Impressions >= t.MinImpressions && r.CTR >= t.MinCTR &&
+		(t.MaxPosition == 0 || r.Position <= t.MaxPosition)
+}
+
+func thresholdsFromRequest(r *http.Request) SearchConsoleThresholds {
+	num := func(name string) float64 {
+		v, _ := parseNumber(strings.TrimSuffix(r.FormValue(name), "%"))
+		return v
+	}
+	t := SearchConsoleThresholds{
+		MinClicks:      int(num("min_clicks")),
+		MinImpressions: int(num("min_impressions")),
+		MinCTR:         num("min_ctr"),
+		MaxPosition:    num("max_position"),
+	}
+	if t.MinCTR > 1 {
+		t.MinCTR /= 100
+	}
+	return t
+}
+
+// ImportResult summarizes one Search Console import
+type ImportResult struct {
+	Rows     int              `json:"rows"`
+	Matched  int              `json:"matched"`
+	Created  int              `json:"created"`
+	Updated  int              `json:"updated"`
+	Keywords []TrackedKeyword `json:"keywords"`
+	DryRun   bool             `json:"dry_run"`
+}
+
+// ImportSearchConsole turns the rows passing t into tracked keywords, merging
+// rows whose queries share a canonical keyword.
+func ImportSearchConsole(rows []SearchConsoleRow, t SearchConsoleThresholds, loc Locale, dryRun bool) (ImportResult, error) {
+	res := ImportResult{Rows: len(rows), DryRun: dryRun}
+	byID := map[string]*TrackedKeyword{}
+	var order []string
+	for _, r := range rows {
+		if !t.match(r) {
+			continue
+		}
+		res.Matched++
+		canonical, id := CanonicalKeyword(r.Query)
+		tk, ok := byID[id]
+		if !ok {
+			tk = &TrackedKeyword{KeywordID: id, Keyword: canonical, Query: r.Query, Locale: loc, Source: "search_console", AddedAt: time.Now(), SearchConsole: &SearchConsoleMetrics{}}
+			byID[id] = tk
+			order = append(order, id)
+		}
+		m := tk.SearchConsole
+		// impression-weighted CTR and position across merged variants
+		total := m.Impressions + r.Impressions
+		if total > 0 {
+			m.Position = (m.Position*float64(m.Impressions) + r.Position*float64(r.Impressions)) / float64(total)
+		}
+		m.Clicks += r.Clicks
+		m.Impressions = total
+		if total > 0 {
+			m.CTR = float64(m.Clicks) / float64(total)
+		}
+	}
+
+	existing := map[string]bool{}
+	for _, tk := range store.Tracked() {
+		existing[tk.KeywordID+"|"+tk.Locale.String()] = true
+	}
+	for _, id := range order {
+		res.Keywords = append(res.Keywords, *byID[id])
+		if existing[id+"|"+loc.String()] {
+			res.Updated++
+		} else {
+			res.Created++
+		}
+	}
+	if dryRun {
+		return res, nil
+	}
+	return res, store.Track(res.Keywords...)
+}
+
+// ownDomains are the sites we care about being cited, from own_domains=a.com,b.co.id
+var ownDomains = splitAliases(os.Getenv("own_domains"))
+
+// isOwnLink reports whether link points at one of our domains or their subdomains.
+func isOwnLink(link string) bool {
+	host := referenceHost(link)
+	for _, d := range ownDomains {
+		d = strings.TrimPrefix(strings.ToLower(d), "www.")
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchConsoleReportRow joins Search Console metrics with the latest lookup
+type SearchConsoleReportRow struct {
+	TrackedKeyword
+	Checked     bool      `json:"checked"`
+	CheckedAt   time.Time `json:"checked_at,omitempty"`
+	HasOverview bool      `json:"has_overview"`
+	Cited       bool      `json:"cited"`
+	CitedLinks  []string  `json:"cited_links,omitempty"`
+	References  int       `json:"references"`
+}
+
+// SearchConsoleReportFilter narrows the report. Overview and Cited take
+// "yes", "no" or "" for either.
+type SearchConsoleReportFilter struct {
+	SearchConsoleThresholds
+	Overview string `json:"overview"`
+	Cited    string `json:"cited"`
+}
+
+func yesNo(want string, v bool) bool {
+	return want == "" || (want == "yes") == v
+}
+
+// BuildSearchConsoleReport lists tracked keywords with Search Console data,
+// highest impressions first.
+func BuildSearchConsoleReport(f SearchConsoleReportFilter) []SearchConsoleReportRow {
+	latest := map[string]Snapshot{}
+	for _, snap := range store.Snapshots(func(s Snapshot) bool { return s.Error == "" || s.Error == errNoOverview.Error() }) {
+		latest[snap.KeywordID+"|"+snap.Locale.String()] = snap
+	}
+	var out []SearchConsoleReportRow
+	for _, tk := range store.Tracked() {
+		if tk.SearchConsole == nil || !f.match(SearchConsoleRow{Query: tk.Query, SearchConsoleMetrics: *tk.SearchConsole}) {
+			continue
+		}
+		row := SearchConsoleReportRow{TrackedKeyword: tk}
+		if snap, ok := latest[tk.KeywordID+"|"+tk.Locale.String()]; ok {
+			row.Checked, row.CheckedAt = true, snap.FetchedAt
+			if snap.Overview != nil && !snap.Overview.IsEmpty() {
+				row.HasOverview = true
+				row.References = len(snap.Overview.References)
+				for _, ref := range snap.Overview.References {
+					if isOwnLink(ref.Link) {
+						row.Cited = true
+						row.CitedLinks = append(row.CitedLinks, ref.Link)
+					}
+				}
+			}
+		}
+		if (f.Overview != "" || f.Cited != "") && !row.Checked {
+			continue
+		}
+		if !yesNo(f.Overview, row.HasOverview) || !yesNo(f.Cited, row.Cited) {
+			continue
+		}
+		out = append(out, row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].SearchConsole.Impressions > out[j].SearchConsole.Impressions
+	})
+	return out
+}
+
+var searchConsoleTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Search Console</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 1000px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+		input[type=number] { width: 6rem; }
+	</style>
+</head>
+<body>
+	<h1>📥 Search Console</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="POST" action="/import/search-console" enctype="multipart/form-data" class="text-block">
+		<strong>Import export</strong> (CSV, .xlsx or .zip)<br/>
+		<input type="file" name="file" required />
+		min clicks <input type="number" name="min_clicks" />
+		min impressions <input type="number" name="min_impressions" />
+		min CTR % <input type="number" step="0.1" name="min_ctr" />
+		max position <input type="number" step="0.1" name="max_position" />
+		<label><input type="checkbox" name="dry_run" value="1" /> preview only</label>
+		<button type="submit">Import</button>
+	</form>
+	{{with .Result}}
+		<div class="text-block">
+			{{if .DryRun}}<strong>Preview:</strong>{{else}}<strong>Imported:</strong>{{end}}
+			{{.Rows}} rows, {{.Matched}} passed the thresholds, {{.Created}} new and {{.Updated}} updated tracked keywords.
+		</div>
+	{{end}}
+	<h2>Report</h2>
+	<p><a href="?min_impressions=1000&overview=yes&cited=no">High-impression queries with an AI Overview that doesn't cite us</a></p>
+	<form method="GET">
+		min impressions <input type="number" name="min_impressions" value="{{.Filter.MinImpressions}}" />
+		max position <input type="number" step="0.1" name="max_position" value="{{.Filter.MaxPosition}}" />
+		overview <select name="overview"><option value="">any</option><option {{if eq .Filter.Overview "yes"}}selected{{end}}>yes</option><option {{if eq .Filter.Overview "no"}}selected{{end}}>no</option></select>
+		cited <select name="cited"><option value="">any</option><option {{if eq .Filter.Cited "yes"}}selected{{end}}>yes</option><option {{if eq .Filter.Cited "no"}}selected{{end}}>no</option></select>
+		<button type="submit">Filter</button>
+	</form>
+	<table>
+		<tr><th>Query</th><th>Clicks</th><th>Impressions</th><th>CTR</th><th>Position</th><th>AI Overview</th><th>Cited</th></tr>
+		{{range .Rows}}
+		<tr>
+			<td><a href="/keywords/{{.KeywordID}}/timeline">{{.Query}}</a></td>
+			<td>{{.SearchConsole.Clicks}}</td><td>{{.SearchConsole.Impressions}}</td>
+			<td>{{printf "%.1f%%" (pct .SearchConsole.CTR)}}</td><td>{{printf "%.1f" .SearchConsole.Position}}</td>
+			<td>{{if not .Checked}}<em>not checked</em>{{else if .HasOverview}}yes ({{.References}} refs){{else}}no{{end}}</td>
+			<td>{{if .Cited}}✔ {{range .CitedLinks}}<a href="{{.}}">{{.}}</a> {{end}}{{else if .HasOverview}}✘{{end}}</td>
+		</tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var searchConsoleTpl = template.Must(template.New("searchconsole").Funcs(template.FuncMap{
+	"pct": func(f float64) float64 { return f * 100 },
+}).Parse(searchConsoleTmpl))
+
+func reportFilterFromRequest(r *http.Request) SearchConsoleReportFilter {
+	return SearchConsoleReportFilter{
+		SearchConsoleThresholds: thresholdsFromRequest(r),
+		Overview:                r.FormValue("overview"),
+		Cited:                   r.FormValue("cited"),
+	}
+}
+
+func renderSearchConsole(w http.ResponseWriter, r *http.Request, result *ImportResult) {
+	f := reportFilterFromRequest(r)
+	if result != nil {
+		f = SearchConsoleReportFilter{}
+	}
+	data := struct {
+		Result *ImportResult
+		Filter SearchConsoleReportFilter
+		Rows   []SearchConsoleReportRow
+	}{result, f, BuildSearchConsoleReport(f)}
+	if err := searchConsoleTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func searchConsolePage(w http.ResponseWriter, r *http.Request) {
+	renderSearchConsole(w, r, nil)
+}
+
+// importSearchConsole reads the uploaded export and applies thresholds from
+// the same form.
+func importSearchConsole(r *http.Request) (ImportResult, error) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return ImportResult{}, errors.New("missing export file")
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(io.LimitReader(file, 32<<20))
+	if err != nil {
+		return ImportResult{}, err
+	}
+	rows, err := ParseSearchConsoleExport(header.Filename, raw)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	dryRun, _ := strconv.ParseBool(r.FormValue("dry_run"))
+	return ImportSearchConsole(rows, thresholdsFromRequest(r), localeFromRequest(r), dryRun)
+}
+
+func searchConsoleUpload(w http.ResponseWriter, r *http.Request) {
+	res, err := importSearchConsole(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	renderSearchConsole(w, r, &res)
+}
+
+func apiSearchConsoleImport(w http.ResponseWriter, r *http.Request) {
+	res, err := importSearchConsole(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func apiSearchConsoleReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, BuildSearchConsoleReport(reportFilterFromRequest(r)))
+}
+
+func apiTracked(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, store.Tracked())
+}
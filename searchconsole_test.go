@@ -0,0 +1,112 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// testWorkbook builds a minimal .xlsx with one sheet named Queries.
+func testWorkbook(t *testing.T, sheetData, sharedStrings string) []byte {
+	t.Helper()
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Chart" sheetId="1" r:id="rId1"/><sheet name="Queries" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheetData + `</sheetData></worksheet>`,
+		"xl/sharedStrings.xml":     `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + sharedStrings + `</sst>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseSearchConsoleXLSX(t *testing.T) {
+	raw := testWorkbook(t,
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>4</v></c></row>`+
+			// Raw floats that look like thousands to a locale guesser.
+			`<row r="2"><c r="A2" t="s"><v>5</v></c><c r="B2"><v>1234</v></c><c r="C2"><v>9876</v></c><c r="D2"><v>0.125</v></c><c r="E2"><v>4.125</v></c></row>`+
+			// Inline string, a typed number, a skipped column and a cell without a column letter.
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>harga emas</t></is></c><c r="B3" t="n"><v>7</v></c><c r="D3"><v>0.05</v></c><c r="E3"><v>12.5</v></c><c r="7"><v>99</v></c></row>`+
+			// Numbers stored as text keep the locale-aware parsing.
+			`<row r="4"><c r="A4" t="s"><v>6</v></c><c r="B4" t="s"><v>7</v></c><c r="C4" t="s"><v>8</v></c><c r="D4" t="s"><v>9</v></c><c r="E4" t="s"><v>10</v></c></row>`,
+		`<si><t>Top queries</t></si><si><t>Clicks</t></si><si><t>Impressions</t></si><si><t>CTR</t></si><si><t>Position</t></si>`+
+			`<si><r><t>kurs </t></r><r><t>dollar</t></r></si><si><t>emas antam</t></si><si><t>1.234</t></si><si><t>10.000</t></si><si><t>12,5%</t></si><si><t>3,2</t></si>`)
+
+	rows, err := ParseSearchConsoleExport("Performance.xlsx", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchConsoleRow{
+		{Query: "kurs dollar", SearchConsoleMetrics: SearchConsoleMetrics{Clicks: 1234, Impressions: 9876, CTR: 0.125, Position: 4.125}},
+		{Query: "harga emas", SearchConsoleMetrics: SearchConsoleMetrics{Clicks: 7, CTR: 0.05, Position: 12.5}},
+		{Query: "emas antam", SearchConsoleMetrics: SearchConsoleMetrics{Clicks: 1234, Impressions: 10000, CTR: 0.125, Position: 3.2}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows =\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestParseSearchConsoleCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want SearchConsoleRow
+	}{
+		{"english", "\ufeffTop queries,Clicks,Impressions,CTR,Position\nharga emas,\"1,234\",\"10,000\",12.5%,4.1\n",
+			SearchConsoleRow{Query: "harga emas", SearchConsoleMetrics: SearchConsoleMetrics{Clicks: 1234, Impressions: 10000, CTR: 0.125, Position: 4.1}}},
+		{"indonesian", "Kueri teratas,Klik,Tayangan,CTR,Posisi\nharga emas,1.234,10.000,\"12,5%\",\"4,1\"\n",
+			SearchConsoleRow{Query: "harga emas", SearchConsoleMetrics: SearchConsoleMetrics{Clicks: 1234, Impressions: 10000, CTR: 0.125, Position: 4.1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseSearchConsoleExport("Queries.csv", []byte(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 || rows[0] != tt.want {
+				t.Errorf("rows = %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchConsoleXLSXColumnLimit(t *testing.T) {
+	raw := testWorkbook(t, `<row r="1"><c r="ZZZZZZ1" t="inlineStr"><is><t>x</t></is></c></row>`, "")
+	if _, err := ParseSearchConsoleExport("Performance.xlsx", raw); err == nil {
+		t.Fatal("expected an error for a column beyond XFD")
+	}
+}
+
+func TestParseSearchConsoleZipInFolder(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("Performance-2024/Queries.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("Top queries,Clicks,Impressions,CTR,Position\nharga emas,3,40,7.5%,2\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ParseSearchConsoleExport("Performance.zip", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Query != "harga emas" {
+		t.Errorf("rows = %+v", rows)
+	}
+}
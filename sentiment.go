@@ -0,0 +1,280 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Brand is a tracked brand with the terms that identify it in overview text
+type Brand struct {
+	Name  string   `json:"name"`
+	Terms []string `json:"terms"` // canonical form
+}
+
+// sentimentOptions tokenize sentences for the lexicon and brand matching.
+// They ignore normalize_punctuation, which only concerns keyword grouping:
+// "bagus." must still read as "bagus".
+var sentimentOptions = NormalizeOptions{StripDiacritics: true, StripPunctuation: true}
+
+// parseBrands reads "Antam|logam mulia;Pegadaian" style configuration:
+// brands separated by ";", aliases by "|", the first alias naming the brand.
+func parseBrands(spec string) []Brand {
+	var out []Brand
+	for _, entry := range strings.Split(spec, ";") {
+		var b Brand
+		for _, alias := range strings.Split(entry, "|") {
+			alias = strings.TrimSpace(alias)
+			if alias == "" {
+				continue
+			}
+			if b.Name == "" {
+				b.Name = alias
+			}
+			b.Terms = append(b.Terms, NormalizeQuery(alias, sentimentOptions))
+		}
+		if b.Name != "" {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+var brands = parseBrands(os.Getenv("brands"))
+
+// Sentiment lexicon for Indonesian and English, in canonical form
+var (
+	positiveWords = wordSet(
+		"baik", "bagus", "terbaik", "terpercaya", "aman", "resmi", "unggul", "terjangkau", "mudah", "cepat", "populer", "direkomendasikan",
+		"rekomendasi", "berkualitas", "puas", "menguntungkan", "untung", "stabil", "andal", "handal", "terkenal", "lengkap", "praktis", "nyaman",
+		"inovatif", "efektif", "terdepan", "ramah", "transparan", "legal", "terjamin", "naik",
+		"good", "great", "best", "excellent", "trusted", "reliable", "safe", "secure", "official", "affordable", "easy", "fast", "popular",
+		"recommended", "quality", "satisfied", "profitable", "stable", "leading", "convenient", "effective", "innovative", "transparent", "reputable",
+	)
+	negativeWords = wordSet(
+		"buruk", "jelek", "mahal", "lambat", "penipuan", "tipu", "palsu", "bermasalah", "masalah", "keluhan", "rugi", "kerugian", "bahaya",
+		"berbahaya", "risiko", "berisiko", "gagal", "lemah", "sulit", "rumit", "mengecewakan", "kecewa", "ilegal", "denda", "skandal", "anjlok",
+		"turun", "bangkrut", "gugatan", "lalai",
+		"bad", "poor", "worst", "expensive", "slow", "scam", "fraud", "fake", "problem", "problems", "issue", "issues", "complaint", "complaints",
+		"loss", "losses", "danger", "dangerous", "risk", "risky", "fail", "failed", "failure", "weak", "difficult", "disappointing", "illegal",
+		"scandal", "lawsuit", "decline", "bankrupt",
+	)
+	negators     = wordSet("tidak", "tak", "bukan", "belum", "tanpa", "kurang", "not", "no", "never", "without", "isn", "aren", "don", "doesn", "wasn")
+	intensifiers = wordSet("sangat", "paling", "sekali", "amat", "very", "most", "highly", "extremely")
+)
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// Sentiment labels
+const (
+	SentimentPositive = "positive"
+	SentimentNegative = "negative"
+	SentimentNeutral  = "neutral"
+)
+
+// SentenceSentiment scores one sentence with the lexicon. Negators within
+// the three preceding words flip a term, intensifiers strengthen it.
+func SentenceSentiment(sentence string) (float64, string) {
+	tokens := strings.Fields(NormalizeQuery(sentence, sentimentOptions))
+	score := 0.0
+	for i, tok := range tokens {
+		w := 0.0
+		switch {
+		case positiveWords[tok]:
+			w = 1
+		case negativeWords[tok]:
+			w = -1
+		default:
+			continue
+		}
+		for j := max(0, i-3); j < i; j++ {
+			if negators[tokens[j]] {
+				w = -w
+			}
+			if intensifiers[tokens[j]] {
+				w *= 1.5
+			}
+		}
+		score += w
+	}
+	switch {
+	case score > 0:
+		return score, SentimentPositive
+	case score < 0:
+		return score, SentimentNegative
+	}
+	return 0, SentimentNeutral
+}
+
+// BrandMention is a sentence of an overview that mentions a brand
+type BrandMention struct {
+	Brand      string    `json:"brand"`
+	KeywordID  string    `json:"keyword_id"`
+	Keyword    string    `json:"keyword"`
+	SnapshotID int64     `json:"snapshot_id"`
+	At         time.Time `json:"at"`
+	Sentence   string    `json:"sentence"`
+	Score      float64   `json:"score"`
+	Sentiment  string    `json:"sentiment"`
+}
+
+// BrandMentions finds every sentence in snap that mentions one of the brands.
+func BrandMentions(snap Snapshot, brands []Brand) []BrandMention {
+	if snap.Overview == nil {
+		return nil
+	}
+	var out []BrandMention
+	for _, p := range snap.Overview.Passages() {
+		for _, sentence := range splitSentences(p.Text) {
+			canonical := NormalizeQuery(sentence, sentimentOptions)
+			for _, b := range brands {
+				if !mentionsAny(canonical, b.Terms) {
+					continue
+				}
+				score, label := SentenceSentiment(sentence)
+				out = append(out, BrandMention{
+					Brand:      b.Name,
+					KeywordID:  snap.KeywordID,
+					Keyword:    snap.Keyword,
+					SnapshotID: snap.ID,
+					At:         snap.FetchedAt,
+					Sentence:   sentence,
+					Score:      score,
+					Sentiment:  label,
+				})
+			}
+		}
+	}
+	return out
+}
+
+func mentionsAny(canonical string, terms []string) bool {
+	for _, t := range terms {
+		if containsPhrase(canonical, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// SentimentPoint aggregates one brand's mentions on one day
+type SentimentPoint struct {
+	Day      string  `json:"day"`
+	Mentions int     `json:"mentions"`
+	Positive int     `json:"positive"`
+	Negative int     `json:"negative"`
+	Neutral  int     `json:"neutral"`
+	AvgScore float64 `json:"avg_score"`
+}
+
+type BrandSentiment struct {
+	Brand    string           `json:"brand"`
+	Trend    []SentimentPoint `json:"trend"`    // oldest day first
+	Evidence []BrandMention   `json:"evidence"` // newest first
+}
+
+// BuildBrandSentiment computes daily sentiment trends for each brand from
+// the stored snapshots.
+func BuildBrandSentiment(brands []Brand) []BrandSentiment {
+	mentions := map[string][]BrandMention{}
+	for _, snap := range store.Snapshots(nil) {
+		for _, m := range BrandMentions(snap, brands) {
+			mentions[m.Brand] = append(mentions[m.Brand], m)
+		}
+	}
+
+	out := make([]BrandSentiment, 0, len(brands))
+	for _, b := range brands {
+		bs := BrandSentiment{Brand: b.Name}
+		days := map[string]*SentimentPoint{}
+		for _, m := range mentions[b.Name] {
+			day := m.At.Format("2006-01-02")
+			p, ok := days[day]
+			if !ok {
+				p = &SentimentPoint{Day: day}
+				days[day] = p
+			}
+			p.Mentions++
+			p.AvgScore += m.Score
+			switch m.Sentiment {
+			case SentimentPositive:
+				p.Positive++
+			case SentimentNegative:
+				p.Negative++
+			default:
+				p.Neutral++
+			}
+		}
+		for _, p := range days {
+			p.AvgScore /= float64(p.Mentions)
+			bs.Trend = append(bs.Trend, *p)
+		}
+		sort.Slice(bs.Trend, func(i, j int) bool { return bs.Trend[i].Day < bs.Trend[j].Day })
+
+		bs.Evidence = mentions[b.Name]
+		sort.SliceStable(bs.Evidence, func(i, j int) bool { return bs.Evidence[i].At.After(bs.Evidence[j].At) })
+		out = append(out, bs)
+	}
+	return out
+}
+
+var brandsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Brand Sentiment</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		table { border-collapse: collapse; width: 100%; margin-bottom: 1rem; }
+		th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.positive { color: #1a7f37; } .negative { color: #cf222e; } .neutral { color: #666; }
+	</style>
+</head>
+<body>
+	<h1>💬 Brand Sentiment</h1>
+	<p><a href="/">← Search</a></p>
+	{{range .}}
+		<h2>{{.Brand}}</h2>
+		<table>
+			<tr><th>Day</th><th>Mentions</th><th>Positive</th><th>Negative</th><th>Neutral</th><th>Avg score</th></tr>
+			{{range .Trend}}
+			<tr><td>{{.Day}}</td><td>{{.Mentions}}</td><td>{{.Positive}}</td><td>{{.Negative}}</td><td>{{.Neutral}}</td><td>{{printf "%.2f" .AvgScore}}</td></tr>
+			{{end}}
+		</table>
+		{{range .Evidence}}
+			<div class="text-block">
+				<span class="{{.Sentiment}}">{{.Sentiment}} ({{printf "%.1f" .Score}})</span>
+				— <em>{{.Keyword}}</em>, {{.At.Format "2006-01-02 15:04"}}
+				<p>{{.Sentence}}</p>
+			</div>
+		{{else}}
+			<p><em>No mentions yet.</em></p>
+		{{end}}
+	{{else}}
+		<p><em>No brands configured. Set the <code>brands</code> environment variable, e.g. <code>Antam|logam mulia;Pegadaian</code>.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var brandsTpl = template.Must(template.New("brands").Parse(brandsTmpl))
+
+func brandsPage(w http.ResponseWriter, r *http.Request) {
+	if err := brandsTpl.Execute(w, BuildBrandSentiment(brands)); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiBrandSentiment(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, BuildBrandSentiment(brands))
+}
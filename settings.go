@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Settings are the runtime settings editable without a restart. The
+// environment provides the defaults until settings are first saved.
+type Settings struct {
+	DefaultHL       string `json:"default_hl"`
+	DefaultGL       string `json:"default_gl"`
+	DefaultLocation string `json:"default_location"`
+
+	UpstreamTimeout      string  `json:"upstream_timeout"` // per SerpAPI call
+	MaxUpstream          int     `json:"max_upstream"`     // concurrent SerpAPI lookups, 0 for unlimited
+	CacheTTL             string  `json:"cache_ttl"`
+	ClaimChangeThreshold float64 `json:"claim_change_threshold"`
+	CreditsMin           int     `json:"credits_min"`
+
+	Prewarm bool `json:"prewarm"`
+	Embeds  bool `json:"embeds"`
+
+	TelegramAlerts bool   `json:"telegram_alerts"`
+	AlertWebhook   string `json:"alert_webhook,omitempty"` // alerts are POSTed here as JSON
+}
+
+// envSettings builds the defaults from the environment.
+func envSettings() Settings {
+	s := Settings{
+		DefaultHL: defaultLocale.HL, DefaultGL: defaultLocale.GL, DefaultLocation: defaultLocale.Location,
+		UpstreamTimeout: "60s", CacheTTL: "1h", ClaimChangeThreshold: 0.1, CreditsMin: 100,
+		Prewarm: true, Embeds: true, TelegramAlerts: true,
+	}
+	if d, err := time.ParseDuration(os.Getenv("cache_ttl")); err == nil {
+		s.CacheTTL = d.String()
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("claim_change_threshold"), 64); err == nil && v > 0 {
+		s.ClaimChangeThreshold = v
+	}
+	if n, err := strconv.Atoi(os.Getenv("credits_min")); err == nil {
+		s.CreditsMin = n
+	}
+	return s
+}
+
+func (s Settings) validate() error {
+	if len(s.DefaultHL) < 2 || len(s.DefaultGL) != 2 {
+		return errors.New("default locale needs a language (hl) and a two letter country (gl)")
+	}
+	if d, err := time.ParseDuration(s.UpstreamTimeout); err != nil || d < time.Second {
+		return errors.New("upstream_timeout must be a duration of at least 1s")
+	}
+	if _, err := time.ParseDuration(s.CacheTTL); err != nil {
+		return errors.New("cache_ttl must be a duration such as 1h")
+	}
+	if s.MaxUpstream < 0 {
+		return errors.New("max_upstream must not be negative")
+	}
+	if s.ClaimChangeThreshold <= 0 {
+		return errors.New("claim_change_threshold must be positive")
+	}
+	if s.CreditsMin < 0 {
+		return errors.New("credits_min must not be negative")
+	}
+	if s.AlertWebhook != "" {
+		u, err := url.Parse(s.AlertWebhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("alert_webhook must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// DefaultLocale is the locale used when a request does not pick one.
+func (s Settings) DefaultLocale() Locale {
+	return Locale{HL: s.DefaultHL, GL: s.DefaultGL, Location: s.DefaultLocation}
+}
+
+func (s Settings) upstreamTimeout() time.Duration {
+	d, _ := time.ParseDuration(s.UpstreamTimeout)
+	return d
+}
+
+func (s Settings) cacheTTL() time.Duration {
+	d, _ := time.ParseDuration(s.CacheTTL)
+	return d
+}
+
+// SettingsVersion is one entry of the settings history
+type SettingsVersion struct {
+	Version  int       `json:"version"`
+	At       time.Time `json:"at"`
+	Note     string    `json:"note,omitempty"`
+	Settings Settings  `json:"settings"`
+}
+
+type settingsFile struct {
+	History []SettingsVersion `json:"history"` // oldest first, the last one is current
+}
+
+// settingsManager holds the current settings, persists every change and
+// tells subscribers about it
+type settingsManager struct {
+	// notifyMu is held from before a change commits until its subscribers
+	// return, so they see changes in the order they were made. Take it
+	// before mu, never while holding mu: subscribers read the settings.
+	notifyMu sync.Mutex
+	mu       sync.RWMutex
+	path     string
+	current  Settings
+	history  []SettingsVersion
+	modTime  time.Time
+	subs     []func(Settings)
+}
+
+var settings = &settingsManager{current: envSettings()}
+
+func currentSettings() Settings {
+	settings.mu.RLock()
+	defer settings.mu.RUnlock()
+	return settings.current
+}
+
+// Subscribe registers fn to be called with the new settings after every
+// change, and once when they are loaded.
+func (m *settingsManager) Subscribe(fn func(Settings)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs = append(m.subs, fn)
+}
+
+func (m *settingsManager) notify(s Settings) {
+	m.mu.RLock()
+	subs := slices.Clone(m.subs)
+	m.mu.RUnlock()
+	for _, fn := range subs {
+		fn(s)
+	}
+}
+
+// Load reads the settings file at path, if any, and applies it.
+func (m *settingsManager) Load(path string) error {
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+	m.mu.Lock()
+	m.path = path
+	err := m.readLocked()
+	s := m.current
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.notify(s)
+	return nil
+}
+
+func (m *settingsManager) readLocked() error {
+	info, err := os.Stat(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(m.path)
+	if err != nil {
+		return err
+	}
+	var f settingsFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+	if len(f.History) == 0 {
+		return errors.New("settings file has no history")
+	}
+	s := f.History[len(f.History)-1].Settings
+	if err := s.validate(); err != nil {
+		return fmt.Errorf("settings file: %w", err)
+	}
+	m.current, m.history, m.modTime = s, f.History, info.ModTime()
+	return nil
+}
+
+// Update validates s, records it as a new version and applies it.
+func (m *settingsManager) Update(s Settings, note string) (SettingsVersion, error) {
+	if err := s.validate(); err != nil {
+		return SettingsVersion{}, err
+	}
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+	m.mu.Lock()
+	v := SettingsVersion{Version: len(m.history) + 1, At: time.Now(), Note: note, Settings: s}
+	m.history = append(m.history, v)
+	m.current = s
+	err := m.saveLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return v, err
+	}
+	log.Printf("⚙️ settings version %d applied", v.Version)
+	m.notify(s)
+	return v, nil
+}
+
+// Rollback re-applies the settings of an earlier version as a new version.
+func (m *settingsManager) Rollback(version int) (SettingsVersion, error) {
+	for _, v := range m.History() {
+		if v.Version == version {
+			return m.Update(v.Settings, fmt.Sprintf("rollback to version %d", version))
+		}
+	}
+	return SettingsVersion{}, errors.New("no such settings version")
+}
+
+// History returns every saved version, newest first.
+func (m *settingsManager) History() []SettingsVersion {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]SettingsVersion, len(m.history))
+	for i, v := range m.history {
+		out[len(out)-1-i] = v
+	}
+	return out
+}
+
+// Export returns the settings file contents, or false when nothing has
+// been saved yet.
+func (m *settingsManager) Export() ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.history) == 0 {
+		return nil, false
+	}
+	raw, err := m.marshalLocked()
+	return raw, err == nil
+}
+
+func (m *settingsManager) marshalLocked() ([]byte, error) {
+	return json.MarshalIndent(settingsFile{History: m.history}, "", "  ")
+}
+
+func (m *settingsManager) saveLocked() error {
+	raw, err := m.marshalLocked()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(m.path); err == nil {
+		m.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// Watch reloads the settings file when it is changed outside the admin UI,
+// e.g. by a deploy tool.
+func (m *settingsManager) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		info, err := os.Stat(m.path)
+		if err != nil {
+			continue
+		}
+		m.notifyMu.Lock()
+		m.mu.Lock()
+		if info.ModTime().Equal(m.modTime) {
+			m.mu.Unlock()
+			m.notifyMu.Unlock()
+			continue
+		}
+		err = m.readLocked()
+		s := m.current
+		m.mu.Unlock()
+		if err != nil {
+			log.Println("❌ ignoring changed settings file:", err)
+		} else {
+			log.Println("⚙️ settings file changed, reloaded")
+			m.notify(s)
+		}
+		m.notifyMu.Unlock()
+	}
+}
+
+// settingsChanges lists the fields that differ between two settings.
+func settingsChanges(before, after Settings) []string {
+	var a, b map[string]any
+	ra, _ := json.Marshal(before)
+	rb, _ := json.Marshal(after)
+	json.Unmarshal(ra, &a)
+	json.Unmarshal(rb, &b)
+	var out []string
+	for k, v := range b {
+		if fmt.Sprint(a[k]) != fmt.Sprint(v) {
+			out = append(out, fmt.Sprintf("%s: %v → %v", k, a[k], v))
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			out = append(out, fmt.Sprintf("%s: %v → ", k, v))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+var settingsTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Settings</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		label { display: block; margin: 0.5rem 0; }
+		fieldset { margin-bottom: 1rem; }
+	</style>
+</head>
+<body>
+	<h1>⚙️ Settings</h1>
+	<p><a href="/">← Search</a> · <a href="/admin/backup">Download backup</a></p>
+	{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+	{{with .Current}}
+	<form method="POST" class="text-block">
+		<fieldset><legend>Defaults</legend>
+			<label>Language (hl) <input type="text" name="default_hl" value="{{.DefaultHL}}" size="5" /></label>
+			<label>Country (gl) <input type="text" name="default_gl" value="{{.DefaultGL}}" size="3" /></label>
+			<label>Location <input type="text" name="default_location" value="{{.DefaultLocation}}" /></label>
+		</fieldset>
+		<fieldset><legend>Limits</legend>
+			<label>Upstream timeout <input type="text" name="upstream_timeout" value="{{.UpstreamTimeout}}" size="6" /></label>
+			<label>Max concurrent upstream lookups (0 = unlimited) <input type="number" name="max_upstream" value="{{.MaxUpstream}}" min="0" /></label>
+			<label>Cache TTL <input type="text" name="cache_ttl" value="{{.CacheTTL}}" size="6" /></label>
+			<label>Claim change threshold <input type="number" step="0.01" name="claim_change_threshold" value="{{.ClaimChangeThreshold}}" /></label>
+			<label>Pause scheduled work below credits <input type="number" name="credits_min" value="{{.CreditsMin}}" min="0" /></label>
+		</fieldset>
+		<fieldset><legend>Features</legend>
+			<label><input type="checkbox" name="prewarm" {{if .Prewarm}}checked{{end}} /> Prewarming</label>
+			<label><input type="checkbox" name="embeds" {{if .Embeds}}checked{{end}} /> Embeddable widget</label>
+		</fieldset>
+		<fieldset><legend>Notifiers</legend>
+			<label><input type="checkbox" name="telegram_alerts" {{if .TelegramAlerts}}checked{{end}} /> Telegram alerts to subscribers</label>
+			<label>Alert webhook <input type="url" name="alert_webhook" value="{{.AlertWebhook}}" size="40" /></label>
+		</fieldset>
+		<label>Note <input type="text" name="note" size="40" /></label>
+		<button type="submit">Save and apply</button>
+	</form>
+	{{end}}
+	<h2>History</h2>
+	{{range .History}}
+		<div class="text-block">
+			<strong>Version {{.Version}}</strong> · {{.At.Format "2006-01-02 15:04"}}{{if .Note}} · {{.Note}}{{end}}
+			{{if .Changes}}<ul>{{range .Changes}}<li><code>{{.}}</code></li>{{end}}</ul>{{end}}
+			{{if ne .Version $.Latest}}
+				<form method="POST" action="/admin/settings/rollback/{{.Version}}"><button type="submit">Roll back to this version</button></form>
+			{{end}}
+		</div>
+	{{else}}
+		<p><em>Using defaults from the environment, nothing saved yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var settingsTpl = template.Must(template.New("settings").Parse(settingsTmpl))
+
+func renderSettings(w http.ResponseWriter, current Settings, errMsg string) {
+	type entry struct {
+		SettingsVersion
+		Changes []string
+	}
+	history := settings.History()
+	data := struct {
+		Current Settings
+		History []entry
+		Latest  int
+		Error   string
+	}{Current: current, Error: errMsg}
+	for i, v := range history {
+		prev := envSettings()
+		if i+1 < len(history) {
+			prev = history[i+1].Settings
+		}
+		data.History = append(data.History, entry{v, settingsChanges(prev, v.Settings)})
+	}
+	if len(history) > 0 {
+		data.Latest = history[0].Version
+	}
+	if err := settingsTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func settingsPage(w http.ResponseWriter, r *http.Request) {
+	renderSettings(w, currentSettings(), "")
+}
+
+// saveSettings handles the settings form.
+func saveSettings(w http.ResponseWriter, r *http.Request) {
+	s := Settings{
+		DefaultHL:       strings.TrimSpace(r.FormValue("default_hl")),
+		DefaultGL:       strings.TrimSpace(r.FormValue("default_gl")),
+		DefaultLocation: strings.TrimSpace(r.FormValue("default_location")),
+		UpstreamTimeout: strings.TrimSpace(r.FormValue("upstream_timeout")),
+		CacheTTL:        strings.TrimSpace(r.FormValue("cache_ttl")),
+		Prewarm:         r.FormValue("prewarm") != "",
+		Embeds:          r.FormValue("embeds") != "",
+		TelegramAlerts:  r.FormValue("telegram_alerts") != "",
+		AlertWebhook:    strings.TrimSpace(r.FormValue("alert_webhook")),
+	}
+	s.MaxUpstream, _ = strconv.Atoi(r.FormValue("max_upstream"))
+	s.ClaimChangeThreshold, _ = strconv.ParseFloat(r.FormValue("claim_change_threshold"), 64)
+	s.CreditsMin, _ = strconv.Atoi(r.FormValue("credits_min"))
+	if _, err := settings.Update(s, r.FormValue("note")); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderSettings(w, s, err.Error())
+		return
+	}
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
+
+func rollbackSettings(w http.ResponseWriter, r *http.Request) {
+	version, _ := strconv.Atoi(r.PathValue("version"))
+	if _, err := settings.Rollback(version); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
+
+func apiSettings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentSettings())
+}
+
+// apiUpdateSettings replaces the settings. Fields left out keep their
+// current values.
+func apiUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	s := currentSettings()
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	v, err := settings.Update(s, r.URL.Query().Get("note"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func apiSettingsHistory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, settings.History())
+}
+
+func apiRollbackSettings(w http.ResponseWriter, r *http.Request) {
+	version, _ := strconv.Atoi(r.PathValue("version"))
+	v, err := settings.Rollback(version)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
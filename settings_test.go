@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSettingsNotifyInCommitOrder(t *testing.T) {
+	m := &settingsManager{current: envSettings()}
+	if err := m.Load(filepath.Join(t.TempDir(), "settings.json")); err != nil {
+		t.Fatal(err)
+	}
+	var seen []int
+	m.Subscribe(func(s Settings) {
+		time.Sleep(time.Millisecond) // widen the window between commit and notify
+		seen = append(seen, s.MaxUpstream)
+	})
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := envSettings()
+			s.MaxUpstream = i
+			if _, err := m.Update(s, ""); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	history := m.History()
+	if len(seen) != len(history) {
+		t.Fatalf("%d notifications for %d versions", len(seen), len(history))
+	}
+	for i, v := range history {
+		if got := seen[len(seen)-1-i]; got != v.Settings.MaxUpstream {
+			t.Fatalf("notified %v, committed in a different order", seen)
+		}
+	}
+	if last := seen[len(seen)-1]; last != m.current.MaxUpstream {
+		t.Errorf("last notification %d, current %d", last, m.current.MaxUpstream)
+	}
+}
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot is one recorded lookup of a query
+type Snapshot struct {
+	ID        int64       `json:"id"`
+	KeywordID string      `json:"keyword_id"`
+	Keyword   string      `json:"keyword"` // canonical form
+	Query     string      `json:"query"`   // exact string sent upstream
+	Locale    Locale      `json:"locale"`
+	FetchedAt time.Time   `json:"fetched_at"`
+	Overview  *AIOverview `json:"overview,omitempty"`
+	Error     string      `json:"error,omitempty"`
+
+	Features       SERPFeatures   `json:"features"`
+	Classification Classification `json:"classification"` // automatic, before overrides
+	YMYL           []string       `json:"ymyl,omitempty"`
+}
+
+// RequestLog is one incoming lookup request, whether served from cache or upstream
+type RequestLog struct {
+	KeywordID string    `json:"keyword_id"`
+	Keyword   string    `json:"keyword"`
+	Query     string    `json:"query"`
+	Locale    Locale    `json:"locale"`
+	At        time.Time `json:"at"`
+	Cached    bool      `json:"cached"`
+	UserID    int64     `json:"user_id,omitempty"` // 0 for anonymous web requests
+}
+
+// Store keeps lookup history in memory and persists it as a JSON file.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	nextID    int64
+	snapshots []Snapshot
+	requests  []RequestLog
+	overrides map[string]Classification // keyword ID -> user override
+	alerts    []Alert
+	entities  []Entity
+	tracked   []TrackedKeyword
+	users     []User
+	serpCalls map[string]int // month|key ID -> local count of SerpAPI calls
+	accounts  []AccountStatus
+	prewarms  []PrewarmRun
+	audit     []AuditEntry
+	inventory *Inventory
+	pages     map[string]PageFeatures // by pageKey
+}
+
+type storeFile struct {
+	Snapshots []Snapshot                `json:"snapshots"`
+	Requests  []RequestLog              `json:"requests"`
+	Overrides map[string]Classification `json:"overrides,omitempty"`
+	Alerts    []Alert                   `json:"alerts,omitempty"`
+	Entities  []Entity                  `json:"entities,omitempty"`
+	Tracked   []TrackedKeyword          `json:"tracked,omitempty"`
+	Users     []User                    `json:"users,omitempty"`
+	SerpCalls map[string]int            `json:"serp_calls,omitempty"`
+	Accounts  []AccountStatus           `json:"accounts,omitempty"`
+	Prewarms  []PrewarmRun              `json:"prewarms,omitempty"`
+	Audit     []AuditEntry              `json:"audit,omitempty"`
+	Inventory *Inventory                `json:"inventory,omitempty"`
+	Pages     map[string]PageFeatures   `json:"pages,omitempty"`
+}
+
+func dataDir() string {
+	if dir := os.Getenv("data_dir"); dir != "" {
+		return dir
+	}
+	return "data"
+}
+
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, nextID: 1, overrides: map[string]Classification{}, serpCalls: map[string]int{}, pages: map[string]PageFeatures{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f storeFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+	s.snapshots = f.Snapshots
+	s.requests = f.Requests
+	s.alerts = f.Alerts
+	s.entities = f.Entities
+	s.tracked = f.Tracked
+	s.users = f.Users
+	s.accounts = f.Accounts
+	s.prewarms = f.Prewarms
+	s.audit = f.Audit
+	s.inventory = f.Inventory
+	if f.Pages != nil {
+		s.pages = f.Pages
+	}
+	if f.SerpCalls != nil {
+		s.serpCalls = f.SerpCalls
+	}
+	if f.Overrides != nil {
+		s.overrides = f.Overrides
+	}
+	sort.SliceStable(s.snapshots, func(i, j int) bool { return s.snapshots[i].FetchedAt.Before(s.snapshots[j].FetchedAt) })
+	for i, snap := range s.snapshots {
+		if snap.ID >= s.nextID {
+			s.nextID = snap.ID + 1
+		}
+		if snap.Locale == (Locale{}) {
+			s.snapshots[i].Locale = defaultLocale
+		}
+	}
+	for i := range s.requests {
+		if s.requests[i].Locale == (Locale{}) {
+			s.requests[i].Locale = defaultLocale
+		}
+	}
+	return s, nil
+}
+
+// Add records a snapshot, assigning its ID, and persists the store.
+func (s *Store) Add(snap Snapshot) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap.ID = s.nextID
+	s.nextID++
+	// Imported snapshots can be older than the latest, so insert by fetch
+	// time: readers take the last snapshot of a series as the latest.
+	i := sort.Search(len(s.snapshots), func(i int) bool { return s.snapshots[i].FetchedAt.After(snap.FetchedAt) })
+	s.snapshots = slices.Insert(s.snapshots, i, snap)
+	return snap, s.saveLocked()
+}
+
+// LogRequest records an incoming lookup request and persists the store.
+func (s *Store) LogRequest(req RequestLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	return s.saveLocked()
+}
+
+// LogUserRequest records a lookup made by req.UserID. Lookups going
+// upstream are refused with errBudgetExceeded once the user's daily budget
+// is spent; checking and recording under one lock keeps concurrent lookups
+// from overrunning it.
+func (s *Store) LogUserRequest(req RequestLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.users, func(u User) bool { return u.ID == req.UserID })
+	if i < 0 {
+		return errUserNotFound
+	}
+	if budget := s.users[i].DailyBudget; !req.Cached && budget > 0 && upstreamToday(s.requests, req.UserID) >= budget {
+		return errBudgetExceeded
+	}
+	s.requests = append(s.requests, req)
+	return s.saveLocked()
+}
+
+// Snapshots returns all snapshots matching keep (all when keep is nil),
+// oldest first.
+func (s *Store) Snapshots(keep func(Snapshot) bool) []Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Snapshot
+	for _, snap := range s.snapshots {
+		if keep == nil || keep(snap) {
+			out = append(out, snap)
+		}
+	}
+	return out
+}
+
+// Requests returns the request log, oldest first.
+func (s *Store) Requests() []RequestLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]RequestLog(nil), s.requests...)
+}
+
+// KeywordSnapshots returns the history of one canonical keyword, oldest first.
+func (s *Store) KeywordSnapshots(keywordID string) []Snapshot {
+	return s.Snapshots(func(snap Snapshot) bool { return snap.KeywordID == keywordID })
+}
+
+// AddAlert records an alert, assigning its ID, and persists the store.
+func (s *Store) AddAlert(a Alert) (Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.ID = int64(len(s.alerts)) + 1
+	s.alerts = append(s.alerts, a)
+	return a, s.saveLocked()
+}
+
+// Alerts returns all alerts, newest first.
+func (s *Store) Alerts() []Alert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Alert, len(s.alerts))
+	for i, a := range s.alerts {
+		out[len(out)-1-i] = a
+	}
+	return out
+}
+
+// Entities returns the entity registry ordered by name.
+func (s *Store) Entities() []Entity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := append([]Entity(nil), s.entities...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SaveEntity adds e to the registry, or replaces the entity with the same
+// ID when e.ID is set.
+func (s *Store) SaveEntity(e Entity) (Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.ID == 0 {
+		for _, existing := range s.entities {
+			e.ID = max(e.ID, existing.ID)
+		}
+		e.ID++
+		s.entities = append(s.entities, e)
+		return e, s.saveLocked()
+	}
+	for i, existing := range s.entities {
+		if existing.ID == e.ID {
+			s.entities[i] = e
+			return e, s.saveLocked()
+		}
+	}
+	return Entity{}, errEntityNotFound
+}
+
+// DeleteEntity removes an entity from the registry.
+func (s *Store) DeleteEntity(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.entities {
+		if e.ID == id {
+			s.entities = append(s.entities[:i], s.entities[i+1:]...)
+			return s.saveLocked()
+		}
+	}
+	return errEntityNotFound
+}
+
+// Tracked returns the tracked keywords in the order they were added.
+func (s *Store) Tracked() []TrackedKeyword {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]TrackedKeyword(nil), s.tracked...)
+}
+
+// Track adds keywords to the tracked list. A keyword already tracked in the
+// same locale keeps its AddedAt but takes the new query and metrics.
+func (s *Store) Track(keywords ...TrackedKeyword) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tk := range keywords {
+		found := false
+		for i, existing := range s.tracked {
+			if existing.KeywordID == tk.KeywordID && existing.Locale == tk.Locale {
+				tk.AddedAt = existing.AddedAt
+				s.tracked[i] = tk
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.tracked = append(s.tracked, tk)
+		}
+	}
+	return s.saveLocked()
+}
+
+// Inventory returns our site's pages, or nil before the first import.
+func (s *Store) Inventory() *Inventory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inventory
+}
+
+// SetInventory replaces the site inventory.
+func (s *Store) SetInventory(inv Inventory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inventory = &inv
+	return s.saveLocked()
+}
+
+// PageFeatures returns what was extracted from a fetched page.
+func (s *Store) PageFeatures(link string) (PageFeatures, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.pages[pageKey(link)]
+	return f, ok
+}
+
+// SetPageFeatures records the features of a fetched page.
+func (s *Store) SetPageFeatures(f PageFeatures) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[pageKey(f.URL)] = f
+	return s.saveLocked()
+}
+
+// Users returns all application users ordered by ID.
+func (s *Store) Users() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]User(nil), s.users...)
+}
+
+// FindUser returns the user with the given ID.
+func (s *Store) FindUser(id int64) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// UserByTelegram returns the user linked to a Telegram account.
+func (s *Store) UserByTelegram(telegramID int64) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if telegramID != 0 && u.TelegramID == telegramID {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// SaveUser adds u, or replaces the user with the same ID when u.ID is set.
+func (s *Store) SaveUser(u User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.ID == 0 {
+		for _, existing := range s.users {
+			u.ID = max(u.ID, existing.ID)
+		}
+		u.ID++
+		s.users = append(s.users, u)
+		return u, s.saveLocked()
+	}
+	for i, existing := range s.users {
+		if existing.ID == u.ID {
+			s.users[i] = u
+			return u, s.saveLocked()
+		}
+	}
+	return User{}, errUserNotFound
+}
+
+// UpdateUser applies update to the stored user with the given ID under the
+// store lock and saves when update reports a change, so concurrent edits
+// of the same user are not lost.
+func (s *Store) UpdateUser(id int64, update func(*User) bool) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].ID != id {
+			continue
+		}
+		u := s.users[i]
+		u.Subscribed = append([]Subscription(nil), u.Subscribed...)
+		if !update(&u) {
+			return u, nil
+		}
+		s.users[i] = u
+		return u, s.saveLocked()
+	}
+	return User{}, errUserNotFound
+}
+
+// CountSerpCall adds one SerpAPI call made with the given key this month.
+func (s *Store) CountSerpCall(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serpCalls[time.Now().UTC().Format("2006-01")+"|"+keyID]++
+	return s.saveLocked()
+}
+
+// SerpCalls returns the local count of calls made with a key in a month
+// formatted as 2006-01.
+func (s *Store) SerpCalls(keyID, month string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.serpCalls[month+"|"+keyID]
+}
+
+// Accounts returns the latest known status of every SerpAPI key.
+func (s *Store) Accounts() []AccountStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]AccountStatus(nil), s.accounts...)
+}
+
+// SetAccount records the latest status of a key, replacing the previous one.
+func (s *Store) SetAccount(a AccountStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.accounts {
+		if existing.KeyID == a.KeyID {
+			s.accounts[i] = a
+			return s.saveLocked()
+		}
+	}
+	s.accounts = append(s.accounts, a)
+	return s.saveLocked()
+}
+
+// AddPrewarmRun records a prewarm run, keeping the last 100.
+func (s *Store) AddPrewarmRun(run PrewarmRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prewarms = append(s.prewarms, run)
+	if len(s.prewarms) > 100 {
+		s.prewarms = s.prewarms[len(s.prewarms)-100:]
+	}
+	return s.saveLocked()
+}
+
+// PrewarmRuns returns the recorded prewarm runs, newest first.
+func (s *Store) PrewarmRuns() []PrewarmRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]PrewarmRun, len(s.prewarms))
+	for i, run := range s.prewarms {
+		out[len(out)-1-i] = run
+	}
+	return out
+}
+
+// UserRequests returns the lookups made by a user, oldest first.
+func (s *Store) UserRequests(userID int64) []RequestLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []RequestLog
+	for _, req := range s.requests {
+		if req.UserID == userID {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+// EraseUser removes a user and either deletes their lookups or, with
+// pseudonymize, keeps them as anonymous requests. The audit entry is
+// written in the same save.
+func (s *Store) EraseUser(userID int64, pseudonymize bool, audit AuditEntry) (EraseReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rep := EraseReport{UserID: userID, Pseudonymized: pseudonymize}
+	found := false
+	for i, u := range s.users {
+		if u.ID == userID {
+			rep.Subscriptions = len(u.Subscribed)
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return rep, errUserNotFound
+	}
+	kept := s.requests[:0]
+	for _, req := range s.requests {
+		if req.UserID != userID {
+			kept = append(kept, req)
+			continue
+		}
+		rep.Requests++
+		if pseudonymize {
+			req.UserID = 0
+			kept = append(kept, req)
+		}
+	}
+	s.requests = kept
+	audit.ID = int64(len(s.audit)) + 1
+	s.audit = append(s.audit, audit)
+	rep.AuditID = audit.ID
+	return rep, s.saveLocked()
+}
+
+// AddAudit records an audit entry.
+func (s *Store) AddAudit(a AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.ID = int64(len(s.audit)) + 1
+	s.audit = append(s.audit, a)
+	return s.saveLocked()
+}
+
+// Audit returns the audit log, newest first.
+func (s *Store) Audit() []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]AuditEntry, len(s.audit))
+	for i, a := range s.audit {
+		out[len(out)-1-i] = a
+	}
+	return out
+}
+
+// SetOverride pins the classification of a keyword. An empty
+// classification removes the override.
+func (s *Store) SetOverride(keywordID string, c Classification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c == (Classification{}) {
+		delete(s.overrides, keywordID)
+	} else {
+		s.overrides[keywordID] = c
+	}
+	return s.saveLocked()
+}
+
+// Export returns the store file contents as of now, for backups.
+func (s *Store) Export() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.marshalLocked()
+}
+
+func (s *Store) marshalLocked() ([]byte, error) {
+	return json.Marshal(storeFile{Snapshots: s.snapshots, Requests: s.requests, Overrides: s.overrides, Alerts: s.alerts, Entities: s.entities, Tracked: s.tracked, Users: s.users, SerpCalls: s.serpCalls, Accounts: s.accounts, Prewarms: s.prewarms, Audit: s.audit, Inventory: s.inventory, Pages: s.pages})
+}
+
+func (s *Store) saveLocked() error {
+	raw, err := s.marshalLocked()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Keyword groups every raw variant seen for one canonical keyword
+type Keyword struct {
+	ID        string         `json:"id"`
+	Keyword   string         `json:"keyword"`
+	Variants  map[string]int `json:"variants"` // raw query -> request count
+	Requests  int            `json:"requests"`
+	Snapshots int            `json:"snapshots"`
+	LastSeen  time.Time      `json:"last_seen"`
+
+	Classification Classification `json:"classification"`
+	Overridden     bool           `json:"overridden"`
+}
+
+// Keywords lists all canonical keywords, most recently seen first.
+func (s *Store) Keywords() []Keyword {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byID := map[string]*Keyword{}
+	get := func(id, canonical string) *Keyword {
+		k, ok := byID[id]
+		if !ok {
+			k = &Keyword{ID: id, Keyword: canonical, Variants: map[string]int{}}
+			byID[id] = k
+		}
+		return k
+	}
+	for _, req := range s.requests {
+		k := get(req.KeywordID, req.Keyword)
+		k.Variants[req.Query]++
+		k.Requests++
+		if req.At.After(k.LastSeen) {
+			k.LastSeen = req.At
+		}
+	}
+	for _, snap := range s.snapshots {
+		k := get(snap.KeywordID, snap.Keyword)
+		k.Snapshots++
+		if snap.FetchedAt.After(k.LastSeen) {
+			k.LastSeen = snap.FetchedAt
+		}
+	}
+	latest := map[string]Snapshot{}
+	for _, snap := range s.snapshots {
+		latest[snap.KeywordID] = snap
+	}
+	out := make([]Keyword, 0, len(byID))
+	for _, k := range byID {
+		if c, ok := s.overrides[k.ID]; ok {
+			k.Classification, k.Overridden = c, true
+		} else if snap, ok := latest[k.ID]; ok {
+			k.Classification = snap.Classification
+		} else {
+			k.Classification = Classify(k.Keyword, SERPFeatures{})
+		}
+		out = append(out, *k)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen.After(out[j].LastSeen) })
+	return out
+}
+
+// FindKeyword returns the grouped keyword with the given ID.
+func (s *Store) FindKeyword(id string) (Keyword, bool) {
+	for _, k := range s.Keywords() {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Keyword{}, false
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Telegram bot. Set telegram_token to enable it. Updates arrive by long
+// polling, or through POST /telegram/webhook when telegram_webhook_secret is
+// set. telegram_api points the client at a local stand-in of the Bot API.
+
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	MessageID int64 `json:"message_id"`
+	From      *struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"from"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// TelegramBot talks to the Telegram Bot API
+type TelegramBot struct {
+	api    string
+	token  string
+	client *http.Client
+}
+
+// telegramBot is nil unless the bot is configured.
+var telegramBot *TelegramBot
+
+func newTelegramBot() *TelegramBot {
+	token := os.Getenv("telegram_token")
+	if token == "" {
+		return nil
+	}
+	api := os.Getenv("telegram_api")
+	if api == "" {
+		api = "https://api.telegram.org"
+	}
+	return &TelegramBot{api: strings.TrimRight(api, "/"), token: token, client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// call invokes a Bot API method and decodes its result into out.
+func (b *TelegramBot) call(method string, params, out any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	resp, err := b.post(method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return err
+	}
+	if !res.OK {
+		return errors.New("telegram " + method + ": " + res.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(res.Result, out)
+	}
+	return nil
+}
+
+// post sends a request to a Bot API method. The token is part of the URL,
+// so transport errors are returned without it.
+func (b *TelegramBot) post(method, contentType string, body io.Reader) (*http.Response, error) {
+	resp, err := b.client.Post(b.api+"/bot"+b.token+"/"+method, contentType, body)
+	if err != nil {
+		// A *url.Error quotes the URL, and with it the token.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = fmt.Errorf("telegram %s: %w", method, uerr.Err)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Send delivers an HTML formatted message.
+func (b *TelegramBot) Send(chatID int64, text string) error {
+	return b.call("sendMessage", map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+// SendDocument uploads raw as a file named name.
+func (b *TelegramBot) SendDocument(chatID int64, name string, raw []byte, caption string) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	mw.WriteField("caption", caption)
+	fw, err := mw.CreateFormFile("document", name)
+	if err != nil {
+		return err
+	}
+	fw.Write(raw)
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	resp, err := b.post("sendDocument", mw.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return err
+	}
+	if !res.OK {
+		return errors.New("telegram sendDocument: " + res.Description)
+	}
+	return nil
+}
+
+// Poll fetches updates with long polling until the process exits.
+func (b *TelegramBot) Poll() {
+	var offset int64
+	for {
+		var updates []telegramUpdate
+		err := b.call("getUpdates", map[string]any{"offset": offset, "timeout": 30, "allowed_updates": []string{"message"}}, &updates)
+		if err != nil {
+			log.Println("❌ telegram polling failed:", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			b.Handle(u)
+		}
+	}
+}
+
+// telegramWebhook receives updates pushed by Telegram.
+func telegramWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := os.Getenv("telegram_webhook_secret")
+	if telegramBot == nil || secret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")), []byte(secret)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var u telegramUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+	go telegramBot.Handle(u)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handle answers one update. Only messages from Telegram accounts linked to
+// an application user are served.
+func (b *TelegramBot) Handle(upd telegramUpdate) {
+	m := upd.Message
+	if m == nil || m.From == nil || strings.TrimSpace(m.Text) == "" {
+		return
+	}
+	reply := func(text string) {
+		if err := b.Send(m.Chat.ID, text); err != nil {
+			log.Println("❌ telegram send failed:", err)
+		}
+	}
+	u, ok := store.UserByTelegram(m.From.ID)
+	if !ok {
+		reply(fmt.Sprintf("You are not linked to a user yet. Ask an admin to add Telegram ID <code>%d</code> on /users.", m.From.ID))
+		return
+	}
+
+	cmd, arg := telegramCommand(m.Text)
+	switch cmd {
+	case "/start", "/help":
+		reply(telegramHelp)
+	case "/locale":
+		if arg == "" {
+			reply("Current locale: <b>" + userLocale(u).String() + "</b>")
+			return
+		}
+		loc, err := parseLocale(arg)
+		if err != nil {
+			reply(html.EscapeString(err.Error()))
+			return
+		}
+		if _, err := store.UpdateUser(u.ID, func(u *User) bool { u.Locale = &loc; return true }); err != nil {
+			reply("Could not save locale.")
+			return
+		}
+		reply("Locale set to <b>" + loc.String() + "</b>")
+	case "/budget":
+		if left := u.BudgetLeft(); left < 0 {
+			reply("No daily budget limit.")
+		} else {
+			reply(fmt.Sprintf("%d of %d upstream lookups left today.", left, u.DailyBudget))
+		}
+	case "/subscribe", "/unsubscribe":
+		if arg == "" {
+			reply("Usage: " + cmd + " &lt;keyword&gt;")
+			return
+		}
+		canonical, id := CanonicalKeyword(arg)
+		changed := false
+		_, err := store.UpdateUser(u.ID, func(u *User) bool {
+			if cmd == "/subscribe" {
+				changed = u.Subscribe(canonical, id)
+			} else {
+				changed = u.Unsubscribe(id)
+			}
+			return changed
+		})
+		if err != nil {
+			reply("Could not save subscription.")
+			return
+		}
+		switch {
+		case cmd == "/subscribe" && changed:
+			reply("🔔 Subscribed to change alerts for <b>" + html.EscapeString(canonical) + "</b>")
+		case cmd == "/subscribe":
+			reply("Already subscribed to <b>" + html.EscapeString(canonical) + "</b>")
+		case changed:
+			reply("🔕 Unsubscribed from <b>" + html.EscapeString(canonical) + "</b>")
+		default:
+			reply("Not subscribed to <b>" + html.EscapeString(canonical) + "</b>")
+		}
+	case "/subscriptions":
+		if len(u.Subscribed) == 0 {
+			reply("No subscriptions.")
+			return
+		}
+		var sb strings.Builder
+		sb.WriteString("🔔 Subscriptions:\n")
+		for _, s := range u.Subscribed {
+			sb.WriteString("• " + html.EscapeString(s.Keyword) + "\n")
+		}
+		reply(sb.String())
+	case "/mydata":
+		if err := b.telegramMyData(m.Chat.ID, u); err != nil {
+			log.Println("❌ telegram export failed:", err)
+			reply("Could not export your data, try again later.")
+		}
+	case "/forgetme":
+		if arg != "confirm" {
+			reply(html.EscapeString(errNotConfirmed.Error()) + ". This removes your account, searches and subscriptions and cannot be undone.")
+			return
+		}
+		rep, err := EraseUser(u, false, "self (telegram)")
+		if err != nil {
+			log.Println("❌ telegram erase failed:", err)
+			reply("Could not delete your data, try again later.")
+			return
+		}
+		if !rep.Verified {
+			reply(fmt.Sprintf("Your data was deleted, but some references remain. Ask an admin to check audit entry #%d.", rep.AuditID))
+			return
+		}
+		reply(fmt.Sprintf("🧹 Deleted your account, %d searches and %d subscriptions.", rep.Requests, rep.Subscriptions))
+	case "/q", "":
+		loc := userLocale(u)
+		query := arg
+		// "/q en-us query" looks up once in another locale
+		if cmd == "/q" {
+			first, rest, _ := strings.Cut(arg, " ")
+			if l, err := parseLocale(first); err == nil {
+				loc, query = l, strings.TrimSpace(rest)
+			}
+		}
+		if strings.TrimSpace(query) == "" {
+			reply("Usage: /q [hl-gl] &lt;query&gt;")
+			return
+		}
+		snap, err := lookupAs(&u, query, loc)
+		switch {
+		case errors.Is(err, errBudgetExceeded):
+			reply("⛔ Your daily lookup budget is used up.")
+		case errors.Is(err, errNoOverview):
+			reply(formatTelegramOverview(snap))
+		case err != nil:
+			log.Println("❌", err)
+			reply("Lookup failed, try again later.")
+		default:
+			reply(formatTelegramOverview(snap))
+		}
+	default:
+		reply("Unknown command. " + telegramHelp)
+	}
+}
+
+const telegramHelp = `Send a query to get its AI Overview.
+/q &lt;hl-gl&gt; &lt;query&gt; – look up once in another locale
+/locale [hl-gl] – show or set your locale
+/subscribe &lt;keyword&gt; – get change alerts
+/unsubscribe &lt;keyword&gt;
+/subscriptions
+/budget – lookups left today
+/mydata – download everything stored about you
+/forgetme confirm – delete your account and searches`
+
+// telegramCommand splits "/cmd@bot arg" into "/cmd" and "arg". Plain text
+// has an empty command.
+func telegramCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", text
+	}
+	cmd, arg, _ := strings.Cut(text, " ")
+	cmd, _, _ = strings.Cut(cmd, "@")
+	return strings.ToLower(cmd), strings.TrimSpace(arg)
+}
+
+func userLocale(u User) Locale {
+	if u.Locale != nil {
+		return *u.Locale
+	}
+	return currentSettings().DefaultLocale()
+}
+
+// parseLocale reads "hl-gl", e.g. "en-us". As with localeFromRequest, the
+// default location only applies to the default gl.
+func parseLocale(s string) (Locale, error) {
+	hl, gl, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "-")
+	if !ok || len(hl) < 2 || len(hl) > 5 || len(gl) != 2 || !isLetters(hl) || !isLetters(gl) {
+		return Locale{}, errors.New("locale must look like hl-gl, e.g. en-us")
+	}
+	loc := currentSettings().DefaultLocale()
+	loc.HL = hl
+	if gl != loc.GL {
+		loc.GL, loc.Location = gl, ""
+	}
+	return loc, nil
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatTelegramOverview renders an overview as Telegram HTML with numbered
+// references, within the 4096 character message limit.
+func formatTelegramOverview(snap Snapshot) string {
+	ai := snap.Overview
+	if ai == nil {
+		return "No AI Overview for <b>" + html.EscapeString(snap.Keyword) + "</b> (" + snap.Locale.String() + ")."
+	}
+	number := map[int]int{}
+	for i, ref := range ai.References {
+		number[ref.Index] = i + 1
+	}
+	cite := func(indexes []int) string {
+		var sb strings.Builder
+		for _, idx := range indexes {
+			if n, ok := number[idx]; ok {
+				sb.WriteString("[" + strconv.Itoa(n) + "]")
+			}
+		}
+		return sb.String()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🤖 <b>AI Overview</b> · " + html.EscapeString(snap.Keyword) + " (" + snap.Locale.String() + ")\n\n")
+	for _, b := range ai.TextBlocks {
+		if b.Snippet != "" {
+			sb.WriteString(html.EscapeString(b.Snippet) + " " + cite(b.ReferenceIndexes) + "\n")
+		}
+		for _, item := range b.List {
+			sb.WriteString("• <b>" + html.EscapeString(item.Title) + "</b> " + html.EscapeString(item.Snippet) + " " + cite(item.ReferenceIndexes) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	// References stop short of the limit, leaving room for the start of the
+	// text, which then fills what is left.
+	const limit = 4096
+	text := sb.String()
+	budget := limit - min(len(text), limit/2) - 40
+	var refs strings.Builder
+	for i, ref := range ai.References {
+		line := fmt.Sprintf("%d. <a href=\"%s\">%s</a>\n", i+1, html.EscapeString(ref.Link), html.EscapeString(ref.Title))
+		if refs.Len()+len(line) > budget {
+			fmt.Fprintf(&refs, "… and %d more\n", len(ai.References)-i)
+			break
+		}
+		refs.WriteString(line)
+	}
+	if len(text)+refs.Len() > limit {
+		cut := max(limit-refs.Len()-2, 0)
+		// back off to a line break so no HTML tag is cut in half
+		if i := strings.LastIndex(text[:min(cut, len(text))], "\n"); i >= 0 {
+			cut = i
+		}
+		text = text[:min(cut, len(text))] + "\n…\n"
+	}
+	return text + refs.String()
+}
+
+// notifySubscribers forwards an alert to subscribed Telegram users.
+func notifySubscribers(a Alert) {
+	sendToSubscribers(a.KeywordID, "🚨 <b>"+html.EscapeString(a.Keyword)+"</b>\n"+html.EscapeString(a.Message))
+}
+
+// notifyOverviewChange tells subscribed Telegram users what changed in a
+// keyword's overview.
+func notifyOverviewChange(snap Snapshot, d OverviewDiff) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔄 <b>%s</b> (%s): AI Overview changed\n+%d/−%d references, %.0f%% text change\n",
+		html.EscapeString(snap.Keyword), snap.Locale, len(d.AddedReferences), len(d.RemovedReferences), d.TextChange*100)
+	lines := 0
+	list := func(sign string, refs []Reference) {
+		for i, ref := range refs {
+			line := sign + " <a href=\"" + html.EscapeString(ref.Link) + "\">" + html.EscapeString(ref.Title) + "</a>\n"
+			if lines == 10 || sb.Len()+len(line) > 4000 {
+				fmt.Fprintf(&sb, "%s and %d more\n", sign, len(refs)-i)
+				return
+			}
+			sb.WriteString(line)
+			lines++
+		}
+	}
+	list("+", d.AddedReferences)
+	list("−", d.RemovedReferences)
+	sendToSubscribers(snap.KeywordID, sb.String())
+}
+
+func sendToSubscribers(keywordID, text string) {
+	if telegramBot == nil || !currentSettings().TelegramAlerts {
+		return
+	}
+	for _, u := range subscribers(keywordID) {
+		if u.TelegramID == 0 {
+			continue
+		}
+		// private chats share the user's ID
+		if err := telegramBot.Send(u.TelegramID, text); err != nil {
+			log.Println("❌ telegram alert failed:", err)
+		}
+	}
+}
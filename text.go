@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Passage is one piece of overview text along with the references it cites
+type Passage struct {
+	Block            int    `json:"block"`          // index into TextBlocks
+	Item             int    `json:"item,omitempty"` // 1-based index into the block's List, 0 for the block snippet
+	Text             string `json:"text"`
+	ReferenceIndexes []int  `json:"reference_indexes,omitempty"`
+}
+
+// Passages flattens the text of an overview: each block snippet followed
+// by its list items ("title: snippet").
+func (a AIOverview) Passages() []Passage {
+	var out []Passage
+	for i, tb := range a.TextBlocks {
+		if strings.TrimSpace(tb.Snippet) != "" {
+			out = append(out, Passage{Block: i, Text: tb.Snippet, ReferenceIndexes: tb.ReferenceIndexes})
+		}
+		for j, item := range tb.List {
+			text := item.Snippet
+			if item.Title != "" {
+				text = strings.TrimSuffix(item.Title, ":") + ": " + item.Snippet
+			}
+			out = append(out, Passage{Block: i, Item: j + 1, Text: text, ReferenceIndexes: item.ReferenceIndexes})
+		}
+	}
+	return out
+}
+
+// splitSentences breaks text on sentence-ending punctuation. Dots between
+// digits ("Rp 1.200.000") do not end a sentence.
+func splitSentences(text string) []string {
+	var out []string
+	runes := []rune(text)
+	start := 0
+	for i, r := range runes {
+		if r != '.' && r != '!' && r != '?' && r != '\n' {
+			continue
+		}
+		if r == '.' && i > 0 && i+1 < len(runes) && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1]) {
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+			out = append(out, s)
+		}
+		start = i + 1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Text joins all passages of the overview, one per line.
+func (a AIOverview) Text() string {
+	var lines []string
+	for _, p := range a.Passages() {
+		lines = append(lines, p.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wordEditDistance is the word-level Levenshtein distance between a and b
+// divided by the longer length, so 0 is identical and 1 is fully rewritten.
+func wordEditDistance(a, b string) float64 {
+	x, y := strings.Fields(a), strings.Fields(b)
+	if len(x) == 0 && len(y) == 0 {
+		return 0
+	}
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return float64(prev[len(y)]) / float64(max(len(x), len(y)))
+}
+
+// ReferencesFor resolves reference indexes to the cited references.
+func (a AIOverview) ReferencesFor(indexes []int) []Reference {
+	var out []Reference
+	for _, i := range indexes {
+		for _, ref := range a.References {
+			if ref.Index == i {
+				out = append(out, ref)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TimelinePoint is one snapshot on a keyword's timeline
+type TimelinePoint struct {
+	SnapshotID  int64     `json:"snapshot_id"`
+	At          time.Time `json:"at"`
+	HasOverview bool      `json:"has_overview"`
+	References  int       `json:"references"`
+	Changed     bool      `json:"changed"` // content differs from the previous point
+	Error       string    `json:"error,omitempty"`
+}
+
+// keywordSeries returns the stored lookups of a keyword in one locale, oldest
+// first, skipping upstream failures that say nothing about the overview.
+func keywordSeries(keywordID string, loc Locale) []Snapshot {
+	var out []Snapshot
+	for _, snap := range store.KeywordSnapshots(keywordID) {
+		if snap.Locale == loc && (snap.Error == "" || snap.Error == errNoOverview.Error()) {
+			out = append(out, snap)
+		}
+	}
+	return out
+}
+
+// BuildTimeline marks the change points of a series.
+func BuildTimeline(snaps []Snapshot) []TimelinePoint {
+	out := make([]TimelinePoint, 0, len(snaps))
+	prev := ""
+	for i, snap := range snaps {
+		fp := overviewFingerprint(snap.Overview)
+		p := TimelinePoint{
+			SnapshotID:  snap.ID,
+			At:          snap.FetchedAt,
+			HasOverview: fp != "",
+			Changed:     i == 0 || fp != prev,
+			Error:       snap.Error,
+		}
+		if snap.Overview != nil {
+			p.References = len(snap.Overview.References)
+		}
+		out = append(out, p)
+		prev = fp
+	}
+	return out
+}
+
+// snapshotAsOf returns the latest snapshot taken at or before t.
+func snapshotAsOf(snaps []Snapshot, t time.Time) (Snapshot, bool) {
+	var found Snapshot
+	ok := false
+	for _, snap := range snaps {
+		if snap.FetchedAt.After(t) {
+			break
+		}
+		found, ok = snap, true
+	}
+	return found, ok
+}
+
+// parseAsOf accepts RFC 3339 timestamps, datetime-local values and plain
+// dates (meaning the end of that second, minute or day, UTC) and unix
+// seconds.
+func parseAsOf(s string) (time.Time, error) {
+	if s == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if t.Nanosecond() == 0 {
+			t = t.Add(time.Second - time.Nanosecond)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05", s); err == nil {
+		return t.Add(time.Second - time.Nanosecond), nil
+	}
+	if t, err := time.Parse("2006-01-02T15:04", s); err == nil {
+		return t.Add(time.Minute - time.Nanosecond), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Time{}, errors.New("invalid time, use RFC 3339, YYYY-MM-DD or unix seconds")
+}
+
+func findSnapshot(snaps []Snapshot, id int64) (Snapshot, bool) {
+	for _, snap := range snaps {
+		if snap.ID == id {
+			return snap, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// apiAsOf serves GET /api/v1/keywords/{id}/as-of?t=
+func apiAsOf(w http.ResponseWriter, r *http.Request) {
+	k, ok := store.FindKeyword(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "keyword not found"})
+		return
+	}
+	t, err := parseAsOf(r.URL.Query().Get("t"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	snap, ok := snapshotAsOf(keywordSeries(k.ID, localeFromRequest(r)), t)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no snapshot at or before " + t.Format(time.RFC3339)})
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		AsOf time.Time `json:"as_of"`
+		Snapshot
+	}{t, snap})
+}
+
+// apiTimeline lists the timeline points of a keyword.
+func apiTimeline(w http.ResponseWriter, r *http.Request) {
+	k, ok := store.FindKeyword(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "keyword not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, BuildTimeline(keywordSeries(k.ID, localeFromRequest(r))))
+}
+
+var timelineTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Timeline · {{.Keyword.Keyword}}</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.timeline { display: flex; gap: 2px; margin: 1rem 0; }
+		.timeline a { flex: 1; height: 24px; background: #ddd; border-radius: 2px; }
+		.timeline a.has { background: #9ecbff; }
+		.timeline a.changed { border-top: 4px solid #cf222e; }
+		.timeline a.selected { outline: 2px solid #000; }
+		input[type=range] { width: 100%; }
+		.added { color: #1a7f37; } .removed { color: #cf222e; text-decoration: line-through; }
+	</style>
+</head>
+<body>
+	<h1>🕰️ Timeline for "{{.Keyword.Keyword}}"</h1>
+	<p><a href="/keywords">← Keywords</a> · locale {{.Locale}} · {{len .Points}} snapshots, change points marked in red</p>
+	{{if .Points}}
+		<div class="timeline">
+		{{range $i, $p := .Points}}
+			<a href="?hl={{$.Locale.HL}}&gl={{$.Locale.GL}}&t={{$p.At.Format "2006-01-02T15:04:05Z07:00"}}" title="{{$p.At.Format "2006-01-02 15:04"}}{{if $p.Changed}} (changed){{end}}"
+				class="{{if $p.HasOverview}}has{{end}} {{if $p.Changed}}changed{{end}} {{if eq $p.SnapshotID $.Selected.ID}}selected{{end}}"></a>
+		{{end}}
+		</div>
+		<form method="GET" id="scrub">
+			<input type="hidden" name="hl" value="{{.Locale.HL}}" />
+			<input type="hidden" name="gl" value="{{.Locale.GL}}" />
+			<input type="hidden" name="t" value="{{.T}}" />
+			<input type="range" min="0" max="{{len .Points | dec}}" value="{{.SelectedIndex}}" />
+		</form>
+		<form method="GET">
+			<input type="hidden" name="hl" value="{{.Locale.HL}}" />
+			<input type="hidden" name="gl" value="{{.Locale.GL}}" />
+			As of <input type="datetime-local" name="t" value="{{.T}}" step="1" />
+			<button type="submit">Go</button>
+		</form>
+		<form method="GET">
+			<input type="hidden" name="hl" value="{{.Locale.HL}}" />
+			<input type="hidden" name="gl" value="{{.Locale.GL}}" />
+			<input type="hidden" name="t" value="{{.T}}" />
+			Diff
+			<select name="a">{{range .Points}}<option value="{{.SnapshotID}}" {{if eq .SnapshotID $.A}}selected{{end}}>{{.At.Format "2006-01-02 15:04"}}</option>{{end}}</select>
+			→
+			<select name="b">{{range .Points}}<option value="{{.SnapshotID}}" {{if eq .SnapshotID $.B}}selected{{end}}>{{.At.Format "2006-01-02 15:04"}}</option>{{end}}</select>
+			<button type="submit">Compare</button>
+		</form>
+	{{end}}
+
+	{{with .Diff}}
+		<h2>🔀 Diff</h2>
+		<div class="text-block">
+			<p>Text change: {{percent .TextChange}}</p>
+			{{range .AddedReferences}}<p class="added">+ <a href="{{.Link}}">{{.Title}}</a></p>{{end}}
+			{{range .RemovedReferences}}<p class="removed">− <a href="{{.Link}}">{{.Title}}</a></p>{{end}}
+			{{range .AddedPassages}}<p class="added">+ {{.}}</p>{{end}}
+			{{range .RemovedPassages}}<p class="removed">− {{.}}</p>{{end}}
+			{{if .Empty}}<p><em>No differences.</em></p>{{end}}
+		</div>
+	{{end}}
+
+	{{if .Found}}
+		<p>Showing snapshot #{{.Selected.ID}} from {{.Selected.FetchedAt.Format "2006-01-02 15:04"}}</p>
+		{{if .Selected.Overview}}{{if not .Selected.Overview.IsEmpty}}{{template "overview" .Selected.Overview}}{{else}}<p><em>No AI Overview at this time.</em></p>{{end}}{{else}}<p><em>No AI Overview at this time.</em></p>{{end}}
+	{{else}}
+		<p><em>No snapshot at or before the chosen time.</em></p>
+	{{end}}
+	<script>
+		const points = [{{range .Points}}{{.At.Format "2006-01-02T15:04:05Z07:00"}},{{end}}];
+		const scrub = document.getElementById("scrub");
+		if (scrub) {
+			scrub.querySelector("input[type=range]").addEventListener("change", e => {
+				scrub.elements.t.value = points[e.target.value];
+				scrub.submit();
+			});
+		}
+	</script>
+</body>
+</html>
+`
+
+var timelineTpl = template.Must(template.Must(template.New("timeline").Funcs(funcMap).Funcs(template.FuncMap{
+	"dec": func(i int) int { return i - 1 },
+}).Parse(timelineTmpl)).Parse(overviewTmpl))
+
+func timelinePage(w http.ResponseWriter, r *http.Request) {
+	k, ok := store.FindKeyword(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	loc := localeFromRequest(r)
+	t, err := parseAsOf(r.URL.Query().Get("t"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snaps := keywordSeries(k.ID, loc)
+	data := struct {
+		Keyword       Keyword
+		Locale        Locale
+		Points        []TimelinePoint
+		T             string
+		Selected      Snapshot
+		SelectedIndex int
+		Found         bool
+		A, B          int64
+		Diff          *OverviewDiff
+	}{Keyword: k, Locale: loc, Points: BuildTimeline(snaps), T: t.UTC().Format("2006-01-02T15:04:05")}
+	data.Selected, data.Found = snapshotAsOf(snaps, t)
+	for i, snap := range snaps {
+		if snap.ID == data.Selected.ID {
+			data.SelectedIndex = i
+		}
+	}
+
+	data.A, _ = strconv.ParseInt(r.URL.Query().Get("a"), 10, 64)
+	data.B, _ = strconv.ParseInt(r.URL.Query().Get("b"), 10, 64)
+	if a, ok := findSnapshot(snaps, data.A); ok {
+		if b, ok := findSnapshot(snaps, data.B); ok {
+			d := DiffOverviews(a.Overview, b.Overview)
+			data.Diff = &d
+		}
+	}
+	if err := timelineTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// User is an application user. Bots and other front ends map their own
+// accounts onto users for authorization and budgets.
+type User struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	TelegramID  int64          `json:"telegram_id,omitempty"`
+	DailyBudget int            `json:"daily_budget"` // upstream lookups per UTC day, 0 for unlimited
+	Locale      *Locale        `json:"locale,omitempty"`
+	Subscribed  []Subscription `json:"subscriptions,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Subscription asks for the change alerts of one keyword
+type Subscription struct {
+	KeywordID string `json:"keyword_id"`
+	Keyword   string `json:"keyword"`
+}
+
+var (
+	errUserNotFound   = errors.New("user not found")
+	errBudgetExceeded = errors.New("daily lookup budget exhausted")
+)
+
+// UpstreamToday counts the user's lookups that went to SerpAPI today (UTC).
+func (u User) UpstreamToday() int {
+	return upstreamToday(store.Requests(), u.ID)
+}
+
+func upstreamToday(requests []RequestLog, userID int64) int {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	n := 0
+	for _, req := range requests {
+		if req.UserID == userID && !req.Cached && !req.At.Before(day) {
+			n++
+		}
+	}
+	return n
+}
+
+// BudgetLeft is the number of upstream lookups left today, or -1 when the
+// user has no budget.
+func (u User) BudgetLeft() int {
+	if u.DailyBudget <= 0 {
+		return -1
+	}
+	return max(u.DailyBudget-u.UpstreamToday(), 0)
+}
+
+// Subscribe adds a subscription, reporting false when it already existed.
+func (u *User) Subscribe(canonical, id string) bool {
+	for _, s := range u.Subscribed {
+		if s.KeywordID == id {
+			return false
+		}
+	}
+	u.Subscribed = append(u.Subscribed, Subscription{KeywordID: id, Keyword: canonical})
+	return true
+}
+
+// Unsubscribe removes a subscription, reporting whether it existed.
+func (u *User) Unsubscribe(id string) bool {
+	for i, s := range u.Subscribed {
+		if s.KeywordID == id {
+			u.Subscribed = append(u.Subscribed[:i], u.Subscribed[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// subscribers returns the users subscribed to a keyword.
+func subscribers(keywordID string) []User {
+	var out []User
+	for _, u := range store.Users() {
+		for _, s := range u.Subscribed {
+			if s.KeywordID == keywordID {
+				out = append(out, u)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (u User) validate() error {
+	if strings.TrimSpace(u.Name) == "" {
+		return errors.New("name is required")
+	}
+	if u.DailyBudget < 0 {
+		return errors.New("daily_budget must not be negative")
+	}
+	if u.TelegramID != 0 {
+		if other, ok := store.UserByTelegram(u.TelegramID); ok && other.ID != u.ID {
+			return errors.New("telegram account already linked to " + other.Name)
+		}
+	}
+	return nil
+}
+
+var usersTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Users</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.25rem 0.5rem; border-bottom: 1px solid #ddd; }
+	</style>
+</head>
+<body>
+	<h1>👤 Users</h1>
+	<p><a href="/">← Search</a></p>
+	<form method="POST" class="text-block">
+		<input type="text" name="name" placeholder="Name" required />
+		<input type="number" name="telegram_id" placeholder="Telegram user ID" />
+		<input type="number" name="daily_budget" placeholder="Daily budget" min="0" />
+		<button type="submit">Add user</button>
+	</form>
+	<table>
+		<tr><th>Name</th><th>Telegram</th><th>Budget today</th><th>Subscriptions</th><th>Data</th></tr>
+		{{range .Users}}
+			<tr>
+				<td>{{.Name}}</td>
+				<td>{{if .TelegramID}}{{.TelegramID}}{{else}}–{{end}}</td>
+				<td>{{if .DailyBudget}}{{.UpstreamToday}} / {{.DailyBudget}}{{else}}unlimited{{end}}</td>
+				<td>{{range $i, $s := .Subscribed}}{{if $i}}, {{end}}<a href="/keywords/{{$s.KeywordID}}/claims">{{$s.Keyword}}</a>{{end}}</td>
+				<td>
+					<a href="/api/v1/users/{{.ID}}/export">Export</a>
+					<form method="POST" action="/users/{{.ID}}/erase" style="display:inline" onsubmit="return confirm('Erase {{.Name}}? This cannot be undone.')">
+						<select name="mode"><option value="delete">delete</option><option value="pseudonymize">pseudonymize</option></select>
+						<button type="submit">Erase</button>
+					</form>
+				</td>
+			</tr>
+		{{else}}
+			<tr><td colspan="5"><em>No users yet.</em></td></tr>
+		{{end}}
+	</table>
+	<h2>Audit log</h2>
+	<table>
+		<tr><th>#</th><th>When</th><th>Action</th><th>Subject</th><th>Actor</th><th>Details</th></tr>
+		{{range .Audit}}
+			<tr><td>{{.ID}}</td><td>{{.At.Format "2006-01-02 15:04"}}</td><td>{{.Action}}</td><td>{{.Subject}}</td><td>{{.Actor}}</td><td>{{.Details}}</td></tr>
+		{{else}}
+			<tr><td colspan="6"><em>No entries.</em></td></tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var usersTpl = template.Must(template.New("users").Parse(usersTmpl))
+
+func usersPage(w http.ResponseWriter, r *http.Request) {
+	if err := usersTpl.Execute(w, map[string]any{"Users": store.Users(), "Audit": store.Audit()}); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+// createUser handles the add form on the users page.
+func createUser(w http.ResponseWriter, r *http.Request) {
+	u := User{Name: strings.TrimSpace(r.FormValue("name")), CreatedAt: time.Now()}
+	u.TelegramID, _ = strconv.ParseInt(r.FormValue("telegram_id"), 10, 64)
+	u.DailyBudget, _ = strconv.Atoi(r.FormValue("daily_budget"))
+	if err := u.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := store.SaveUser(u); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+func apiUsers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, store.Users())
+}
+
+// apiSaveUser creates (POST) or replaces (PUT /{id}) a user.
+func apiSaveUser(w http.ResponseWriter, r *http.Request) {
+	var u User
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	u.ID, u.CreatedAt = 0, time.Now()
+	if r.Method == http.MethodPut {
+		// A PUT never creates: an id that does not parse or is not stored
+		// is not found.
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		existing, ok := store.FindUser(id)
+		if err != nil || !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": errUserNotFound.Error()})
+			return
+		}
+		u.ID, u.CreatedAt = id, existing.CreatedAt
+	}
+	if err := u.validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	saved, err := store.SaveUser(u)
+	if errors.Is(err, errUserNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
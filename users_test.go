@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogUserRequestBudgetIsAtomic(t *testing.T) {
+	useTestStore(t)
+	u, err := store.SaveUser(User{Name: "Budi", DailyBudget: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed, refused := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.LogUserRequest(RequestLog{Query: "harga emas", At: time.Now(), UserID: u.ID})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				allowed++
+			case errors.Is(err, errBudgetExceeded):
+				refused++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 3 || refused != 17 {
+		t.Errorf("allowed %d, refused %d, want 3 and 17", allowed, refused)
+	}
+	if err := store.LogUserRequest(RequestLog{Query: "harga emas", At: time.Now(), UserID: u.ID, Cached: true}); err != nil {
+		t.Errorf("cache hit refused: %v", err)
+	}
+}
+
+func TestUpdateUserKeepsConcurrentSubscriptions(t *testing.T) {
+	useTestStore(t)
+	u, err := store.SaveUser(User{Name: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			canonical, id := CanonicalKeyword(fmt.Sprintf("keyword %d", i))
+			if _, err := store.UpdateUser(u.ID, func(u *User) bool { return u.Subscribe(canonical, id) }); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got, _ := store.FindUser(u.ID); len(got.Subscribed) != 20 {
+		t.Errorf("%d subscriptions, want 20", len(got.Subscribed))
+	}
+}
+
+func TestAPISaveUserPutNeverCreates(t *testing.T) {
+	useTestStore(t)
+	u, err := store.SaveUser(User{Name: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"abc", http.StatusNotFound},
+		{"0", http.StatusNotFound},
+		{"99", http.StatusNotFound},
+		{fmt.Sprint(u.ID), http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+tt.id, strings.NewReader(`{"name":"Siti"}`))
+		req.SetPathValue("id", tt.id)
+		rec := httptest.NewRecorder()
+		apiSaveUser(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("PUT %s: status %d, want %d (%s)", tt.id, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if users := store.Users(); len(users) != 1 || users[0].Name != "Siti" || !users[0].CreatedAt.Equal(u.CreatedAt) {
+		t.Errorf("users = %+v", users)
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	for in, ok := range map[string]bool{"en-us": true, "id-ID": true, "covid-19": false, "harga": false, "e-us": false} {
+		if _, err := parseLocale(in); (err == nil) != ok {
+			t.Errorf("parseLocale(%q) error = %v", in, err)
+		}
+	}
+}
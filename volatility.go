@@ -0,0 +1,205 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Weights of the volatility components
+const (
+	referenceTurnoverWeight = 0.4
+	textChangeWeight        = 0.4
+	presenceFlapWeight      = 0.2
+)
+
+// Volatility summarizes how much a keyword's overview churns between
+// consecutive snapshots in one locale. All rates are 0..1.
+type Volatility struct {
+	KeywordID         string        `json:"keyword_id"`
+	Keyword           string        `json:"keyword"`
+	Locale            Locale        `json:"locale"`
+	Snapshots         int           `json:"snapshots"`
+	ReferenceTurnover float64       `json:"reference_turnover"` // mean Jaccard distance of cited links
+	TextChange        float64       `json:"text_change"`        // mean word edit distance
+	PresenceFlapping  float64       `json:"presence_flapping"`  // share of transitions where the overview appeared or vanished
+	Score             float64       `json:"score"`
+	SuggestedInterval time.Duration `json:"suggested_interval"`
+	Suggested         string        `json:"suggested"`
+	MonthlyCredits    int           `json:"monthly_credits"` // at the suggested interval, two calls per check worst case
+}
+
+// minVolatilitySnapshots is the history needed before a score means anything
+const minVolatilitySnapshots = 3
+
+// suggestInterval maps a volatility score to a monitoring interval:
+// churning keywords are checked several times a day, stable ones weekly.
+func suggestInterval(score float64, snapshots int) time.Duration {
+	switch {
+	case snapshots < minVolatilitySnapshots:
+		return 24 * time.Hour // not enough history, check daily
+	case score >= 0.5:
+		return 6 * time.Hour
+	case score >= 0.3:
+		return 12 * time.Hour
+	case score >= 0.15:
+		return 24 * time.Hour
+	case score >= 0.05:
+		return 3 * 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+func formatInterval(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		days := int(d / (24 * time.Hour))
+		if days == 1 {
+			return "daily"
+		}
+		if days == 7 {
+			return "weekly"
+		}
+		return "every " + strconv.Itoa(days) + " days"
+	}
+	return "every " + strconv.Itoa(int(d/time.Hour)) + "h"
+}
+
+// ComputeVolatility scores one series of snapshots, oldest first.
+func ComputeVolatility(snaps []Snapshot) Volatility {
+	v := Volatility{Snapshots: len(snaps)}
+	if len(snaps) > 0 {
+		v.KeywordID, v.Keyword, v.Locale = snaps[0].KeywordID, snaps[0].Keyword, snaps[0].Locale
+	}
+	var refPairs, textPairs, flaps int
+	for i := 1; i < len(snaps); i++ {
+		prev, cur := snaps[i-1], snaps[i]
+		prevHas := prev.Overview != nil && !prev.Overview.IsEmpty()
+		curHas := cur.Overview != nil && !cur.Overview.IsEmpty()
+		if prevHas != curHas {
+			flaps++
+		}
+		if prevHas && curHas {
+			v.ReferenceTurnover += jaccardDistance(referenceLinks(prev.Overview), referenceLinks(cur.Overview))
+			v.TextChange += wordEditDistance(NormalizeQuery(prev.Overview.Text(), normalizeOptions), NormalizeQuery(cur.Overview.Text(), normalizeOptions))
+			refPairs++
+			textPairs++
+		}
+	}
+	if refPairs > 0 {
+		v.ReferenceTurnover /= float64(refPairs)
+	}
+	if textPairs > 0 {
+		v.TextChange /= float64(textPairs)
+	}
+	if len(snaps) > 1 {
+		v.PresenceFlapping = float64(flaps) / float64(len(snaps)-1)
+	}
+	v.Score = referenceTurnoverWeight*v.ReferenceTurnover + textChangeWeight*v.TextChange + presenceFlapWeight*v.PresenceFlapping
+	v.SuggestedInterval = suggestInterval(v.Score, v.Snapshots)
+	v.Suggested = formatInterval(v.SuggestedInterval)
+	v.MonthlyCredits = 2 * int(30*24*time.Hour/v.SuggestedInterval)
+	return v
+}
+
+// referenceLinks returns the set of links cited by ai.
+func referenceLinks(ai *AIOverview) map[string]bool {
+	links := map[string]bool{}
+	for _, ref := range ai.References {
+		links[ref.Link] = true
+	}
+	return links
+}
+
+func jaccardDistance(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return 1 - float64(inter)/float64(len(a)+len(b)-inter)
+}
+
+// snapshotSeries groups successful upstream lookups by keyword and locale,
+// each series oldest first.
+func snapshotSeries() map[string][]Snapshot {
+	series := map[string][]Snapshot{}
+	for _, snap := range store.Snapshots(func(s Snapshot) bool {
+		return s.Error == "" || s.Error == errNoOverview.Error()
+	}) {
+		key := snap.KeywordID + "|" + snap.Locale.String()
+		series[key] = append(series[key], snap)
+	}
+	return series
+}
+
+// VolatilityLeaderboard scores every keyword and locale, most volatile first.
+func VolatilityLeaderboard() []Volatility {
+	var out []Volatility
+	for _, snaps := range snapshotSeries() {
+		out = append(out, ComputeVolatility(snaps))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Keyword < out[j].Keyword
+	})
+	return out
+}
+
+var volatilityTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>Volatility</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 900px; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #ddd; }
+	</style>
+</head>
+<body>
+	<h1>🌪️ Volatility Leaderboard</h1>
+	<p><a href="/">← Search</a></p>
+	<p>Suggested monitoring: {{.Credits}} SerpAPI credits/month, versus {{.DailyCredits}} when checking everything daily.</p>
+	<table>
+		<tr><th>#</th><th>Keyword</th><th>Locale</th><th>Snapshots</th><th>Refs turnover</th><th>Text change</th><th>Flapping</th><th>Score</th><th>Suggested</th></tr>
+		{{range $i, $v := .Board}}
+		<tr><td>{{inc $i}}</td><td><a href="/keywords/{{.KeywordID}}/claims">{{.Keyword}}</a></td><td>{{.Locale}}</td><td>{{.Snapshots}}</td>
+			<td>{{percent .ReferenceTurnover}}</td><td>{{percent .TextChange}}</td><td>{{percent .PresenceFlapping}}</td>
+			<td><strong>{{printf "%.2f" .Score}}</strong></td><td>{{.Suggested}}</td></tr>
+		{{end}}
+	</table>
+</body>
+</html>
+`
+
+var volatilityTpl = template.Must(template.New("volatility").Funcs(funcMap).Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(volatilityTmpl))
+
+func volatilityPage(w http.ResponseWriter, r *http.Request) {
+	board := VolatilityLeaderboard()
+	credits := 0
+	for _, v := range board {
+		credits += v.MonthlyCredits
+	}
+	data := struct {
+		Board        []Volatility
+		Credits      int
+		DailyCredits int
+	}{board, credits, 2 * 30 * len(board)}
+	if err := volatilityTpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiVolatility(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, VolatilityLeaderboard())
+}
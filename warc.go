@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WARC export of captured material. Every snapshot becomes a metadata
+// record holding the snapshot as JSON, preceded by request and response
+// records for the SerpAPI calls behind it. Each record is its own gzip
+// member, as WARC readers expect of .warc.gz files.
+//
+// Reference pages are not archived by this application, so none are
+// exported.
+
+const (
+	warcVersion   = "WARC/1.0"
+	maxWARCRecord = 64 << 20 // largest block read back; SerpAPI responses are far smaller
+	maxWARCSize   = 1 << 30  // largest file read back, decompressed
+)
+
+// warcRecord is one record before serialization
+type warcRecord struct {
+	Type        string
+	ID          string
+	Date        time.Time
+	TargetURI   string
+	ContentType string
+	Fields      [][2]string // further WARC headers, in order
+	Block       []byte
+}
+
+// warcSnapshot is the block of a metadata record
+type warcSnapshot struct {
+	Snapshot Snapshot   `json:"snapshot"`
+	Records  []warcLink `json:"records,omitempty"`
+}
+
+// warcLink names the records of one SerpAPI call
+type warcLink struct {
+	Engine   string `json:"engine"`
+	Request  string `json:"request"`
+	Response string `json:"response"`
+}
+
+// warcID derives a stable record ID, so exporting the same history twice
+// gives the same records.
+func warcID(parts ...any) string {
+	sum := sha1.Sum([]byte(fmt.Sprint(parts...)))
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func warcDigest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func writeWARCRecord(w io.Writer, rec warcRecord) error {
+	var buf bytes.Buffer
+	buf.WriteString(warcVersion + "\r\n")
+	buf.WriteString("WARC-Type: " + rec.Type + "\r\n")
+	buf.WriteString("WARC-Record-ID: " + rec.ID + "\r\n")
+	buf.WriteString("WARC-Date: " + rec.Date.UTC().Format(time.RFC3339) + "\r\n")
+	if rec.TargetURI != "" {
+		buf.WriteString("WARC-Target-URI: " + rec.TargetURI + "\r\n")
+	}
+	for _, f := range rec.Fields {
+		buf.WriteString(f[0] + ": " + f[1] + "\r\n")
+	}
+	buf.WriteString("WARC-Block-Digest: " + warcDigest(rec.Block) + "\r\n")
+	buf.WriteString("Content-Type: " + rec.ContentType + "\r\n")
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(rec.Block)) + "\r\n\r\n")
+	buf.Write(rec.Block)
+	buf.WriteString("\r\n\r\n")
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// httpRequestBlock and httpResponseBlock rebuild the HTTP messages of an
+// exchange. Bodies are stored decoded, so the framing headers are rewritten
+// to match.
+func httpRequestBlock(ex Exchange) []byte {
+	u, _ := url.Parse(ex.URL)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", ex.Method, u.RequestURI())
+	h := ex.RequestHeader.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	if h.Get("Host") == "" {
+		h.Set("Host", u.Host)
+	}
+	h.Write(&b)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func httpResponseBlock(ex Exchange) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP/1.1 %s\r\n", ex.Status)
+	h := ex.ResponseHeader.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Del("Content-Encoding")
+	h.Del("Transfer-Encoding")
+	h.Set("Content-Length", strconv.Itoa(len(ex.Body)))
+	h.Write(&b)
+	b.WriteString("\r\n")
+	b.Write(ex.Body)
+	return b.Bytes()
+}
+
+// WriteWARC writes the snapshots and their recorded calls as a WARC file.
+func WriteWARC(w io.Writer, snaps []Snapshot) error {
+	info := fmt.Sprintf("software: ai-overview-google-scrapping\r\nformat: WARC File Format 1.0\r\ndescription: SerpAPI calls and AI Overview snapshots\r\nsnapshots: %d\r\n", len(snaps))
+	if err := writeWARCRecord(w, warcRecord{Type: "warcinfo", ID: warcID("warcinfo", time.Now().UnixNano()), Date: time.Now(),
+		ContentType: "application/warc-fields", Block: []byte(info)}); err != nil {
+		return err
+	}
+	for _, snap := range snaps {
+		ex, err := loadExchanges(snap.ID)
+		if err != nil {
+			return fmt.Errorf("snapshot %d: %w", snap.ID, err)
+		}
+		meta := warcSnapshot{Snapshot: snap}
+		for i, e := range ex {
+			link := warcLink{Engine: e.Engine, Request: warcID(snap.ID, i, "request"), Response: warcID(snap.ID, i, "response")}
+			if err := writeWARCRecord(w, warcRecord{Type: "response", ID: link.Response, Date: e.RequestedAt, TargetURI: e.URL,
+				ContentType: "application/http;msgtype=response", Fields: [][2]string{{"WARC-Payload-Digest", warcDigest(e.Body)}},
+				Block: httpResponseBlock(e)}); err != nil {
+				return err
+			}
+			if err := writeWARCRecord(w, warcRecord{Type: "request", ID: link.Request, Date: e.RequestedAt, TargetURI: e.URL,
+				ContentType: "application/http;msgtype=request", Fields: [][2]string{{"WARC-Concurrent-To", link.Response}},
+				Block: httpRequestBlock(e)}); err != nil {
+				return err
+			}
+			meta.Records = append(meta.Records, link)
+		}
+		block, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		rec := warcRecord{Type: "metadata", ID: warcID(snap.ID, "metadata"), Date: snap.FetchedAt,
+			TargetURI: fmt.Sprintf("urn:aio:snapshot:%d", snap.ID), ContentType: "application/json", Block: block}
+		if len(meta.Records) > 0 {
+			rec.TargetURI = ex[0].URL
+			rec.Fields = [][2]string{{"WARC-Refers-To", meta.Records[0].Response}}
+		}
+		if err := writeWARCRecord(w, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readWARCRecord reads the next record, or returns io.EOF.
+func readWARCRecord(br *bufio.Reader) (textproto.MIMEHeader, []byte, error) {
+	tp := textproto.NewReader(br)
+	var version string
+	for version == "" {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, nil, err
+		}
+		version = strings.TrimSpace(line)
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, nil, fmt.Errorf("expected a WARC record, got %q", version)
+	}
+	h, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, nil, fmt.Errorf("record %s has no valid Content-Length", h.Get("WARC-Record-ID"))
+	}
+	if n > maxWARCRecord {
+		return nil, nil, fmt.Errorf("record %s is larger than 64 MB", h.Get("WARC-Record-ID"))
+	}
+	block, err := io.ReadAll(io.LimitReader(br, int64(n)))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(block) < n {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	return h, block, nil
+}
+
+// warcSizeLimit fails reads past a limit. Unlike io.LimitReader it does not
+// end the file there, which would pass for a truncated record.
+type warcSizeLimit struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *warcSizeLimit) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if l.left -= int64(n); l.left < 0 {
+		return n, fmt.Errorf("WARC file is larger than %d MB decompressed", maxWARCSize>>20)
+	}
+	return n, err
+}
+
+// ReadWARC reads a WARC file, compressed or not, into snapshots with their
+// calls. Metadata records written by WriteWARC are taken as they are;
+// responses of the google engine without one, such as captures made by other
+// tools, are parsed like a fresh lookup.
+func ReadWARC(r io.Reader) ([]Snapshot, [][]Exchange, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		src = gz
+	}
+	// Records are kept until the end, so the whole file must fit.
+	br = bufio.NewReader(&warcSizeLimit{r: src, left: maxWARCSize})
+
+	exchanges := map[string]Exchange{} // response record ID -> call
+	var order []string
+	var metas []warcSnapshot
+	for {
+		h, block, err := readWARCRecord(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		id := h.Get("WARC-Record-ID")
+		date, _ := time.Parse(time.RFC3339, h.Get("WARC-Date"))
+		switch h.Get("WARC-Type") {
+		case "response":
+			resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+			if err != nil {
+				return nil, nil, fmt.Errorf("record %s: %w", id, err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, nil, fmt.Errorf("record %s: %w", id, err)
+			}
+			target := h.Get("WARC-Target-URI")
+			u, _ := url.Parse(target)
+			q := u.Query()
+			if q.Has("api_key") {
+				q.Del("api_key")
+				u.RawQuery = q.Encode()
+			}
+			exchanges[id] = Exchange{Engine: q.Get("engine"), Method: http.MethodGet, URL: u.String(), Status: resp.Status,
+				ResponseHeader: resp.Header, Body: []byte(redactKeys(string(body))), RequestedAt: date, RespondedAt: date}
+			order = append(order, id)
+		case "metadata":
+			if !strings.HasPrefix(h.Get("Content-Type"), "application/json") {
+				continue
+			}
+			var m warcSnapshot
+			if err := json.Unmarshal(block, &m); err != nil || m.Snapshot.KeywordID == "" {
+				continue
+			}
+			metas = append(metas, m)
+		}
+	}
+
+	var snaps []Snapshot
+	var calls [][]Exchange
+	linked := map[string]bool{}
+	for _, m := range metas {
+		var ex []Exchange
+		for _, l := range m.Records {
+			linked[l.Response] = true
+			if e, ok := exchanges[l.Response]; ok {
+				ex = append(ex, e)
+			}
+		}
+		snaps = append(snaps, m.Snapshot)
+		calls = append(calls, ex)
+	}
+	for _, id := range order {
+		e := exchanges[id]
+		if linked[id] || e.Engine != "google" {
+			continue
+		}
+		snap, err := snapshotFromExchange(e)
+		if err != nil {
+			return nil, nil, fmt.Errorf("record %s: %w", id, err)
+		}
+		snaps = append(snaps, snap)
+		calls = append(calls, []Exchange{e})
+	}
+	return snaps, calls, nil
+}
+
+// snapshotFromExchange parses a google engine response the way a lookup
+// would, without the page_token follow-up.
+func snapshotFromExchange(e Exchange) (Snapshot, error) {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	q := u.Query()
+	query := q.Get("q")
+	if query == "" {
+		return Snapshot{}, errors.New("response has no query")
+	}
+	var results map[string]interface{}
+	if err := json.Unmarshal(e.Body, &results); err != nil {
+		return Snapshot{}, err
+	}
+	canonical, id := CanonicalKeyword(query)
+	features := serpFeatures(results)
+	snap := Snapshot{KeywordID: id, Keyword: canonical, Query: query,
+		Locale:    Locale{HL: q.Get("hl"), GL: q.Get("gl"), Location: q.Get("location")},
+		FetchedAt: e.RequestedAt, Features: features, Classification: Classify(canonical, features)}
+	var overview AIOverview
+	raw, _ := json.Marshal(results["ai_overview"])
+	if err := json.Unmarshal(raw, &overview); err == nil && !overview.IsEmpty() {
+		snap.Overview = &overview
+	} else {
+		snap.Error = errNoOverview.Error()
+	}
+	snap.YMYL = DetectYMYL(query, snap.Overview)
+	return snap, nil
+}
+
+// WARCImport is the outcome of importing a WARC file
+type WARCImport struct {
+	Read     int     `json:"read"` // snapshots in the file
+	Imported int     `json:"imported"`
+	Skipped  int     `json:"skipped"` // already in history
+	Stored   []int64 `json:"stored"`  // IDs of the imported snapshots
+}
+
+// ImportWARC adds the snapshots of a WARC file to the history. The whole
+// file is read before anything is stored, so a malformed file imports
+// nothing. Should storing fail part way, the result lists the snapshots
+// already stored. Snapshots with the same keyword, locale and fetch time
+// as an existing one are skipped, so importing a file again is harmless
+// and completes a partial import.
+func ImportWARC(r io.Reader) (WARCImport, error) {
+	res := WARCImport{Stored: []int64{}}
+	snaps, calls, err := ReadWARC(r)
+	if err != nil {
+		return res, err
+	}
+	res.Read = len(snaps)
+	seen := map[string]bool{}
+	key := func(s Snapshot) string {
+		return s.KeywordID + "|" + s.Locale.String() + "|" + strconv.FormatInt(s.FetchedAt.UnixNano(), 10)
+	}
+	for _, s := range store.Snapshots(nil) {
+		seen[key(s)] = true
+	}
+	for i, snap := range snaps {
+		if seen[key(snap)] {
+			res.Skipped++
+			continue
+		}
+		seen[key(snap)] = true
+		saved, err := store.Add(snap)
+		if err != nil {
+			return res, err
+		}
+		res.Imported++
+		res.Stored = append(res.Stored, saved.ID)
+		if len(calls[i]) > 0 {
+			if err := saveExchanges(saved.ID, calls[i]); err != nil {
+				return res, fmt.Errorf("snapshot %d stored without its calls: %w", saved.ID, err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// warcSnapshots selects the snapshots to export by keyword ID and a time range.
+func warcSnapshots(keywordID string, from, to time.Time) []Snapshot {
+	return store.Snapshots(func(s Snapshot) bool {
+		return (keywordID == "" || s.KeywordID == keywordID) &&
+			(from.IsZero() || !s.FetchedAt.Before(from)) && (to.IsZero() || s.FetchedAt.Before(to))
+	})
+}
+
+func warcFileName() string {
+	return "aio-" + time.Now().UTC().Format("20060102-150405") + ".warc.gz"
+}
+
+// runWARCCommand implements
+//
+//	aio warc export [-o file] [-keyword id] [-from date] [-to date]
+//	aio warc import <file>
+//
+// Import writes the history file directly; stop the server first, or use
+// POST /api/v1/warc/import on the running server.
+func runWARCCommand(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: warc export|import")
+	}
+	var err error
+	store, err = OpenStore(filepath.Join(dataDir(), "history.json"))
+	if err != nil {
+		return err
+	}
+	switch args[1] {
+	case "export":
+		fl := flag.NewFlagSet("warc export", flag.ContinueOnError)
+		out := fl.String("o", warcFileName(), "file to write")
+		keyword := fl.String("keyword", "", "only this keyword ID")
+		from := fl.String("from", "", "only snapshots from this date (YYYY-MM-DD)")
+		to := fl.String("to", "", "only snapshots before this date (YYYY-MM-DD)")
+		if err := fl.Parse(args[2:]); err != nil {
+			return err
+		}
+		var fromT, toT time.Time
+		if *from != "" {
+			if fromT, err = time.Parse(time.DateOnly, *from); err != nil {
+				return err
+			}
+		}
+		if *to != "" {
+			if toT, err = time.Parse(time.DateOnly, *to); err != nil {
+				return err
+			}
+		}
+		snaps := warcSnapshots(*keyword, fromT, toT)
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := WriteWARC(f, snaps); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		log.Printf("💾 wrote %s with %d snapshots", *out, len(snaps))
+		return nil
+	case "import":
+		if len(args) != 3 {
+			return errors.New("usage: warc import <file>")
+		}
+		f, err := os.Open(args[2])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		res, err := ImportWARC(f)
+		if err != nil {
+			if res.Imported > 0 {
+				log.Printf("❌ only %d of %d snapshots were stored before the import failed, run it again to add the rest", res.Imported, res.Read)
+			}
+			return err
+		}
+		log.Printf("✅ imported %d snapshots from %s, %d already in history", res.Imported, args[2], res.Skipped)
+		return nil
+	}
+	return fmt.Errorf("unknown warc command %q", args[1])
+}
+
+// exportWARC serves GET /export/snapshots.warc.gz?keyword=&from=&to=.
+func exportWARC(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.DateOnly, v); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.DateOnly, v); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteWARC(&buf, warcSnapshots(r.URL.Query().Get("keyword"), from, to)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/warc")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+warcFileName()+`"`)
+	w.Write(buf.Bytes())
+}
+
+// apiImportWARC adds the snapshots of an uploaded WARC file to the history.
+func apiImportWARC(w http.ResponseWriter, r *http.Request) {
+	res, err := ImportWARC(http.MaxBytesReader(w, r.Body, 1<<30))
+	if err != nil && res.Read == 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		// The file was fine but storing failed; say what made it in.
+		writeJSON(w, http.StatusInternalServerError, struct {
+			Error string `json:"error"`
+			WARCImport
+		}{err.Error(), res})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestStore points the data directory and the global store at a fresh
+// temporary directory for the duration of the test.
+func useTestStore(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("data_dir", dir)
+	s, err := OpenStore(filepath.Join(dir, "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := store
+	store = s
+	t.Cleanup(func() { store = old })
+}
+
+func testSnapshot(at time.Time, links ...string) Snapshot {
+	ai := &AIOverview{TextBlocks: []TextBlock{{Type: "paragraph", Snippet: "Harga emas hari ini."}}}
+	for i, link := range links {
+		ai.References = append(ai.References, Reference{Title: link, Link: link, Index: i})
+	}
+	return Snapshot{KeywordID: "7bef3a2d338c", Keyword: "harga emas", Query: "harga emas", Locale: Locale{HL: "id", GL: "id"}, FetchedAt: at, Overview: ai}
+}
+
+func TestWARCRoundTrip(t *testing.T) {
+	useTestStore(t)
+	day := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	old := []Snapshot{
+		testSnapshot(day, "https://www.logammulia.com/id"),
+		testSnapshot(day.AddDate(0, 0, 1), "https://www.logammulia.com/id", "https://www.pegadaian.co.id/harga"),
+	}
+	ex := Exchange{Engine: "google", Method: http.MethodGet, URL: "https://serpapi.com/search.json?engine=google&q=harga+emas",
+		Status: "200 OK", ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+		Body: []byte(`{"organic_results":[{"link":"https://example.com/emas"}]}`), RequestedAt: day, RespondedAt: day.Add(time.Second)}
+	for i := range old {
+		saved, err := store.Add(old[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		old[i] = saved
+		if err := saveExchanges(saved.ID, []Exchange{ex}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteWARC(&buf, store.Snapshots(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, calls, err := ReadWARC(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != len(old) {
+		t.Fatalf("read %d snapshots, want %d", len(snaps), len(old))
+	}
+	for i := range snaps {
+		if !reflect.DeepEqual(snaps[i].Overview, old[i].Overview) || !snaps[i].FetchedAt.Equal(old[i].FetchedAt) {
+			t.Errorf("snapshot %d changed in the round trip: %+v", i, snaps[i])
+		}
+		if len(calls[i]) != 1 || !bytes.Equal(calls[i][0].Body, ex.Body) || calls[i][0].URL != ex.URL {
+			t.Errorf("snapshot %d exchanges changed in the round trip: %+v", i, calls[i])
+		}
+	}
+
+	// Import into a history that already has a newer lookup.
+	useTestStore(t)
+	newest, err := store.Add(testSnapshot(day.AddDate(0, 0, 10), "https://www.antam.com/harga"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ImportWARC(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imported != 2 || res.Skipped != 0 {
+		t.Fatalf("import = %+v, want 2 imported", res)
+	}
+	all := store.Snapshots(nil)
+	for i := 1; i < len(all); i++ {
+		if all[i].FetchedAt.Before(all[i-1].FetchedAt) {
+			t.Fatalf("snapshots out of order after import: %v before %v", all[i-1].FetchedAt, all[i].FetchedAt)
+		}
+	}
+	series := snapshotSeries()["7bef3a2d338c|id-id"]
+	if len(series) != 3 || series[len(series)-1].ID != newest.ID {
+		t.Fatalf("latest of series = %+v, want the newer lookup %d", series[len(series)-1], newest.ID)
+	}
+	if got, ok := snapshotAsOf(series, day.AddDate(0, 0, 1).Add(time.Hour)); !ok || !got.FetchedAt.Equal(old[1].FetchedAt) {
+		t.Errorf("as of day 2 = %v, want the imported day 2 snapshot", got.FetchedAt)
+	}
+	if got, _ := snapshotAsOf(series, day.AddDate(0, 0, 20)); got.ID != newest.ID {
+		t.Errorf("as of day 20 = snapshot %d, want %d", got.ID, newest.ID)
+	}
+
+	res, err = ImportWARC(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imported != 0 || res.Skipped != 2 {
+		t.Errorf("second import = %+v, want everything skipped", res)
+	}
+}
+
+func TestReadWARCHugeContentLength(t *testing.T) {
+	for _, n := range []string{"9000000000000000000", "50000000000", "10"} {
+		raw := "WARC/1.0\r\nWARC-Type: metadata\r\nWARC-Record-ID: <urn:uuid:x>\r\nContent-Length: " + n + "\r\n\r\n{}"
+		if _, _, err := ReadWARC(strings.NewReader(raw)); err == nil {
+			t.Errorf("Content-Length %s: expected an error", n)
+		}
+	}
+}
+
+func TestImportWARCReportsPartialImport(t *testing.T) {
+	useTestStore(t)
+	day := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	var snaps []Snapshot
+	for i := 0; i < 3; i++ {
+		saved, err := store.Add(testSnapshot(day.AddDate(0, 0, i), "https://www.logammulia.com/id"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, saved)
+		ex := Exchange{Engine: "google", Method: http.MethodGet, URL: "https://serpapi.com/search.json?engine=google&q=harga+emas",
+			Status: "200 OK", Body: []byte(`{}`), RequestedAt: saved.FetchedAt, RespondedAt: saved.FetchedAt}
+		if err := saveExchanges(saved.ID, []Exchange{ex}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteWARC(&buf, snaps); err != nil {
+		t.Fatal(err)
+	}
+
+	// The calls of the second imported snapshot cannot be written.
+	useTestStore(t)
+	if err := os.MkdirAll(capturePath(2)+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ImportWARC(bytes.NewReader(buf.Bytes()))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if res.Read != 3 || res.Imported != 2 || !reflect.DeepEqual(res.Stored, []int64{1, 2}) {
+		t.Errorf("import = %+v, want 2 of 3 stored", res)
+	}
+	if n := len(store.Snapshots(nil)); n != 2 {
+		t.Errorf("%d snapshots in history, want 2", n)
+	}
+
+	// A malformed file stores nothing.
+	raw := buf.Bytes()
+	res, err = ImportWARC(bytes.NewReader(raw[:len(raw)-10]))
+	if err == nil || res.Read != 0 || len(res.Stored) != 0 {
+		t.Errorf("truncated file: import = %+v, err = %v", res, err)
+	}
+}
+
+func TestWARCSizeLimit(t *testing.T) {
+	for _, tt := range []struct {
+		size, limit int64
+		ok          bool
+	}{{10, 10, true}, {10, 9, false}, {0, 0, true}} {
+		l := &warcSizeLimit{r: strings.NewReader(strings.Repeat("x", int(tt.size))), left: tt.limit}
+		_, err := io.ReadAll(l)
+		if (err == nil) != tt.ok {
+			t.Errorf("%d bytes with a limit of %d: err = %v", tt.size, tt.limit, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSockets use golang.org/x/net/websocket, which answers pings and
+// closes on its own. Browsers send Origin on every WebSocket handshake, so
+// requests from other sites are turned away here.
+
+// maxWebSocketMessage bounds what a client may send; dashboards send nothing.
+const maxWebSocketMessage = 64 << 10
+
+// sameOriginHandshake rejects handshakes whose Origin is not this host.
+func sameOriginHandshake(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil || origin == nil {
+		return errors.New("missing origin")
+	}
+	if !strings.EqualFold(origin.Host, r.Host) {
+		return errors.New("cross-origin websocket from " + origin.Host)
+	}
+	config.Origin = origin
+	return nil
+}
+
+// serveWebSocket upgrades same-origin requests and runs handler on the
+// connection. Handshake failures get a 403.
+func serveWebSocket(w http.ResponseWriter, r *http.Request, handler func(*websocket.Conn)) {
+	websocket.Server{
+		Handshake: sameOriginHandshake,
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxWebSocketMessage
+			handler(ws)
+		},
+	}.ServeHTTP(w, r)
+}
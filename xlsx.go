@@ -0,0 +1,202 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Minimal reader for the .xlsx workbooks Search Console exports: shared
+// strings, inline strings and numbers, nothing else.
+
+const (
+	maxXLSXPart    = 64 << 20 // uncompressed size of one zip entry
+	maxXLSXColumns = 16384    // column XFD, the last one Excel allows
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSST struct {
+	Items []struct {
+		T    string `xml:"t"`
+		Runs []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// sheetCell is one cell of a CSV or XLSX sheet. Number marks XLSX numeric
+// cells, whose value is written as a plain float whatever the locale.
+type sheetCell struct {
+	Value  string
+	Number bool
+}
+
+// textRows wraps rows read from a CSV file.
+func textRows(rows [][]string) [][]sheetCell {
+	out := make([][]sheetCell, len(rows))
+	for i, row := range rows {
+		for _, v := range row {
+			out[i] = append(out[i], sheetCell{Value: v})
+		}
+	}
+	return out
+}
+
+// readXLSXSheet returns the rows of the sheet whose name matches one of
+// names (case-insensitive), or of the first sheet.
+func readXLSXSheet(raw []byte, names ...string) ([][]sheetCell, error) {
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+	readXML := func(name string, v any) error {
+		f, err := zr.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data, err := readZipPart(f, name)
+		if err != nil {
+			return err
+		}
+		return xml.Unmarshal(data, v)
+	}
+
+	var wb xlsxWorkbook
+	if err := readXML("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	var rels xlsxRels
+	if err := readXML("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheet := wb.Sheets[0]
+	for _, s := range wb.Sheets {
+		for _, n := range names {
+			if strings.EqualFold(s.Name, n) {
+				sheet = s
+			}
+		}
+	}
+	target := ""
+	for _, r := range rels.Rels {
+		if r.ID == sheet.RID {
+			target = strings.TrimPrefix(r.Target, "/xl/")
+		}
+	}
+	if target == "" {
+		return nil, errors.New("sheet " + sheet.Name + " not found in workbook")
+	}
+
+	var sst xlsxSST
+	if err := readXML("xl/sharedStrings.xml", &sst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.T
+		for _, r := range si.Runs {
+			shared[i] += r.T
+		}
+	}
+
+	var ws xlsxSheet
+	if err := readXML(path.Join("xl", target), &ws); err != nil {
+		return nil, err
+	}
+	var rows [][]sheetCell
+	for _, row := range ws.Rows {
+		var out []sheetCell
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = xlsxColumn(c.Ref)
+			}
+			if col < 0 {
+				continue
+			}
+			if col >= maxXLSXColumns {
+				return nil, errors.New("cell " + c.Ref + " is beyond column XFD")
+			}
+			for len(out) <= col {
+				out = append(out, sheetCell{})
+			}
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n < len(shared) {
+					out[col].Value = shared[n]
+				}
+			case "inlineStr":
+				out[col].Value = c.Inline
+			case "", "n":
+				out[col] = sheetCell{Value: c.Value, Number: true}
+			default:
+				out[col].Value = c.Value
+			}
+		}
+		rows = append(rows, out)
+	}
+	return rows, nil
+}
+
+// readZipPart reads one zip entry, refusing entries that inflate past
+// maxXLSXPart so a small upload cannot expand into a decompression bomb.
+func readZipPart(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxXLSXPart+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxXLSXPart {
+		return nil, errors.New(name + " is larger than 64 MB uncompressed")
+	}
+	return data, nil
+}
+
+// xlsxColumn converts a cell reference such as "C12" to a 0-based column,
+// or -1 when the reference has no column letters.
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > maxXLSXColumns {
+			break
+		}
+	}
+	return col - 1
+}
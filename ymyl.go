@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/csv"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// YMYL ("your money or your life") categories
+const (
+	YMYLHealth  = "health"
+	YMYLFinance = "finance"
+	YMYLLegal   = "legal"
+	YMYLSafety  = "safety"
+)
+
+var ymylCues = map[string][]string{
+	YMYLHealth: {
+		"obat", "dosis", "gejala", "penyakit", "dokter", "sakit", "hamil", "kehamilan", "vaksin", "diabetes", "hipertensi", "kolesterol", "kanker", "diet",
+		"suplemen", "vitamin", "efek samping", "pengobatan", "terapi", "rumah sakit", "bpjs kesehatan",
+		"medicine", "dosage", "dose", "symptom", "symptoms", "disease", "doctor", "pregnancy", "vaccine", "cancer", "side effects", "treatment", "supplement",
+	},
+	YMYLFinance: {
+		"pinjaman", "pinjol", "kredit", "kpr", "investasi", "saham", "reksadana", "kripto", "asuransi", "pajak", "suku bunga", "bunga pinjaman", "cicilan", "deposito",
+		"investasi emas", "dana pensiun", "trading", "forex",
+		"loan", "credit", "mortgage", "investment", "stock", "stocks", "crypto", "insurance", "tax", "interest rate", "retirement",
+	},
+	YMYLLegal: {
+		"hukum", "pasal", "undang undang", "gugatan", "cerai", "perceraian", "warisan", "pidana", "perdata", "pengacara", "notaris", "pengajuan visa", "visa kerja",
+		"imigrasi", "law", "lawsuit", "divorce", "inheritance", "criminal", "lawyer", "immigration", "visa application", "breach of contract",
+	},
+	YMYLSafety: {
+		"darurat", "bencana", "gempa", "banjir", "kebakaran", "keracunan", "pertolongan pertama", "keamanan", "senjata", "bunuh diri",
+		"emergency", "disaster", "earthquake", "flood", "house fire", "poisoning", "first aid", "weapon", "suicide", "overdose",
+	},
+}
+
+// DetectYMYL returns the sorted YMYL categories signalled by the query and
+// the overview text.
+func DetectYMYL(query string, ai *AIOverview) []string {
+	texts := []string{NormalizeQuery(query, normalizeOptions)}
+	if ai != nil {
+		for _, p := range ai.Passages() {
+			texts = append(texts, NormalizeQuery(p.Text, normalizeOptions))
+		}
+	}
+	var out []string
+	for category, cues := range ymylCues {
+		hits := 0
+		for i, text := range texts {
+			for _, cue := range cues {
+				if containsPhrase(text, cue) {
+					if i == 0 {
+						hits += 2 // query matches weigh more than overview mentions
+					} else {
+						hits++
+					}
+				}
+			}
+		}
+		if hits >= 2 {
+			out = append(out, category)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Domains treated as authoritative in addition to government and academic TLDs
+var authoritativeDomains = []string{
+	"who.int", "cdc.gov", "nih.gov", "alodokter.com", "halodoc.com", "ojk.go.id", "bi.go.id", "kemkes.go.id", "pajak.go.id",
+	"idx.co.id", "lps.go.id", "bpjs-kesehatan.go.id", "mayoclinic.org", "nhs.uk", "wikipedia.org",
+}
+
+var authoritativeSuffixes = []string{".go.id", ".gov", ".ac.id", ".edu", ".mil.id", ".gov.uk", ".int"}
+
+// referenceHost returns the lowercased host of link without a leading "www.".
+func referenceHost(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func isAuthoritative(host string) bool {
+	for _, suffix := range authoritativeSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	for _, d := range authoritativeDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+var disclaimerCues = []string{
+	"konsultasikan", "berkonsultasi", "konsultasi dengan dokter", "hubungi dokter", "bukan merupakan nasihat", "bukan nasihat", "tenaga medis",
+	"tenaga profesional", "penasihat keuangan", "disclaimer", "consult", "not financial advice", "not medical advice", "not legal advice",
+	"for informational purposes", "seek professional", "speak to a",
+}
+
+// numericClaimRe matches quantities that need a source when they appear in
+// YMYL answers: dosages, rates, percentages and money amounts.
+var numericClaimRe = regexp.MustCompile(`(?i)((?:\b(?:rp|idr|usd)|\$)\s?\d[\d.,]*|\d[\d.,]*\s?(?:%|(?:persen|percent|mg|mcg|ml|iu|gram|g|kg|tablet|kapsul|capsules?|kali sehari|x sehari|times a day)\b))`)
+
+// ComplianceReport is the compliance checklist for one YMYL snapshot
+type ComplianceReport struct {
+	SnapshotID           int64    `json:"snapshot_id"`
+	KeywordID            string   `json:"keyword_id"`
+	Keyword              string   `json:"keyword"`
+	FetchedAt            string   `json:"fetched_at"`
+	YMYL                 []string `json:"ymyl"`
+	AuthoritativeSources []string `json:"authoritative_sources"`
+	HasDisclaimer        bool     `json:"has_disclaimer"`
+	UncitedNumericClaims []string `json:"uncited_numeric_claims"`
+	Flags                []string `json:"flags"`
+}
+
+// Compliance flags
+const (
+	FlagNoAuthoritativeSource = "no_authoritative_source"
+	FlagNoDisclaimer          = "no_disclaimer"
+	FlagUncitedNumericClaim   = "uncited_numeric_claim"
+)
+
+// CheckCompliance builds the checklist for snap. Snapshots that are not
+// YMYL get an empty flag list.
+func CheckCompliance(snap Snapshot) ComplianceReport {
+	r := ComplianceReport{
+		SnapshotID: snap.ID,
+		KeywordID:  snap.KeywordID,
+		Keyword:    snap.Keyword,
+		FetchedAt:  snap.FetchedAt.Format("2006-01-02 15:04"),
+		YMYL:       snap.YMYL,
+		Flags:      []string{},
+	}
+	if snap.Overview == nil || len(snap.YMYL) == 0 {
+		return r
+	}
+
+	seen := map[string]bool{}
+	for _, ref := range snap.Overview.References {
+		if host := referenceHost(ref.Link); isAuthoritative(host) && !seen[host] {
+			seen[host] = true
+			r.AuthoritativeSources = append(r.AuthoritativeSources, host)
+		}
+	}
+	for _, p := range snap.Overview.Passages() {
+		canonical := NormalizeQuery(p.Text, NormalizeOptions{StripDiacritics: true})
+		for _, cue := range disclaimerCues {
+			if strings.Contains(canonical, cue) {
+				r.HasDisclaimer = true
+			}
+		}
+		if len(p.ReferenceIndexes) == 0 {
+			r.UncitedNumericClaims = append(r.UncitedNumericClaims, numericClaimRe.FindAllString(p.Text, -1)...)
+		}
+	}
+
+	if len(r.AuthoritativeSources) == 0 {
+		r.Flags = append(r.Flags, FlagNoAuthoritativeSource)
+	}
+	if !r.HasDisclaimer {
+		r.Flags = append(r.Flags, FlagNoDisclaimer)
+	}
+	if len(r.UncitedNumericClaims) > 0 {
+		r.Flags = append(r.Flags, FlagUncitedNumericClaim)
+	}
+	return r
+}
+
+// latestComplianceReports checks the latest successful snapshot of every
+// YMYL keyword.
+func latestComplianceReports() []ComplianceReport {
+	latest := map[string]Snapshot{}
+	for _, snap := range store.Snapshots(func(s Snapshot) bool { return s.Overview != nil && len(s.YMYL) > 0 }) {
+		latest[snap.KeywordID] = snap
+	}
+	out := make([]ComplianceReport, 0, len(latest))
+	for _, snap := range latest {
+		out = append(out, CheckCompliance(snap))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].Flags) != len(out[j].Flags) {
+			return len(out[i].Flags) > len(out[j].Flags)
+		}
+		return out[i].Keyword < out[j].Keyword
+	})
+	return out
+}
+
+var complianceTmpl = `
+<!DOCTYPE html>
+<html>
+<head>
+	<title>YMYL Compliance</title>
+	<style>
+		body { font-family: sans-serif; margin: 2rem auto; max-width: 800px; }
+		.text-block { margin-bottom: 1rem; padding: 1rem; background: #f9f9f9; border-radius: 8px; }
+		.ok { color: #1a7f37; } .flag { color: #cf222e; }
+	</style>
+</head>
+<body>
+	<h1>⚖️ YMYL Compliance</h1>
+	<p><a href="/">← Search</a> · <a href="/export/snapshots.csv">Export CSV</a> · <a href="/export/snapshots.warc.gz">Export WARC</a></p>
+	{{range .}}
+		<div class="text-block">
+			<strong>{{.Keyword}}</strong> — {{join .YMYL ", "}} · {{.FetchedAt}}
+			<ul>
+				<li>{{if .AuthoritativeSources}}<span class="ok">✔ Authoritative sources cited:</span> {{join .AuthoritativeSources ", "}}{{else}}<span class="flag">✘ No government or authoritative source cited</span>{{end}}</li>
+				<li>{{if .HasDisclaimer}}<span class="ok">✔ Disclaimer present</span>{{else}}<span class="flag">✘ No disclaimer</span>{{end}}</li>
+				<li>{{if .UncitedNumericClaims}}<span class="flag">✘ Numeric claims without citation:</span> {{join .UncitedNumericClaims ", "}}{{else}}<span class="ok">✔ All numeric claims cited</span>{{end}}</li>
+			</ul>
+		</div>
+	{{else}}
+		<p><em>No YMYL lookups yet.</em></p>
+	{{end}}
+</body>
+</html>
+`
+
+var complianceTpl = template.Must(template.New("compliance").Funcs(template.FuncMap{"join": strings.Join}).Parse(complianceTmpl))
+
+func compliancePage(w http.ResponseWriter, r *http.Request) {
+	if err := complianceTpl.Execute(w, latestComplianceReports()); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func apiCompliance(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, latestComplianceReports())
+}
+
+// apiSnapshot returns one stored snapshot along with its compliance
+// checklist and language report.
+func apiSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	snaps := store.Snapshots(func(s Snapshot) bool { return s.ID == id })
+	if len(snaps) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "snapshot not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Snapshot
+		Compliance ComplianceReport `json:"compliance"`
+		Language   LanguageReport   `json:"language"`
+	}{snaps[0], CheckCompliance(snaps[0]), DetectOverviewLanguages(snaps[0].Overview, snaps[0].Locale.HL)})
+}
+
+// exportSnapshotsCSV exports every stored snapshot with its classification
+// and YMYL compliance flags.
+func exportSnapshotsCSV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="snapshots.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"snapshot_id", "keyword_id", "keyword", "query", "fetched_at", "has_overview", "references", "intent", "topic", "ymyl", "compliance_flags", "error"})
+	for _, snap := range store.Snapshots(nil) {
+		refs := 0
+		if snap.Overview != nil {
+			refs = len(snap.Overview.References)
+		}
+		report := CheckCompliance(snap)
+		cw.Write([]string{
+			strconv.FormatInt(snap.ID, 10),
+			snap.KeywordID,
+			csvSafe(snap.Keyword),
+			csvSafe(snap.Query),
+			snap.FetchedAt.Format("2006-01-02T15:04:05Z07:00"),
+			strconv.FormatBool(snap.Overview != nil && !snap.Overview.IsEmpty()),
+			strconv.Itoa(refs),
+			snap.Classification.Intent,
+			snap.Classification.Topic,
+			strings.Join(snap.YMYL, "|"),
+			strings.Join(report.Flags, "|"),
+			csvSafe(snap.Error),
+		})
+	}
+	cw.Flush()
+}
+
+// csvSafe keeps spreadsheets from reading user supplied text as a formula.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
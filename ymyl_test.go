@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNumericClaimRe(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"biaya sekitar $500 per bulan", []string{"$500"}},
+		{"harga Rp 1.200.000 atau USD 80", []string{"Rp 1.200.000", "USD 80"}},
+		{"bunga 5% per tahun, dosis 500 mg", []string{"5%", "500 mg"}},
+		{"tidak ada angka di sini", nil},
+	}
+	for _, tt := range tests {
+		if got := numericClaimRe.FindAllString(tt.text, -1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: claims = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectYMYLIgnoresBroadWords(t *testing.T) {
+	for _, q := range []string{"cara membuat bunga kertas", "fire emblem walkthrough", "visa debit card design", "kalung emas model terbaru"} {
+		if got := DetectYMYL(q, nil); len(got) != 0 {
+			t.Errorf("%q: YMYL = %v, want none", q, got)
+		}
+	}
+	if got := DetectYMYL("suku bunga kpr", nil); !reflect.DeepEqual(got, []string{YMYLFinance}) {
+		t.Errorf("suku bunga kpr: YMYL = %v", got)
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	for in, want := range map[string]string{"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")", "+1": "'+1", "-2": "'-2", "@SUM(A1)": "'@SUM(A1)", "harga emas": "harga emas", "": ""} {
+		if got := csvSafe(in); got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}